@@ -0,0 +1,356 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kevin-cantwell/mysqlite/internal/server"
+	"github.com/kevin-cantwell/mysqlite/internal/sqlite"
+	"github.com/liquidata-inc/go-mysql-server/auth"
+	"github.com/liquidata-inc/vitess/go/vt/vttls"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+// defaultMaxConnections is MySQL's default max_connections.
+const defaultMaxConnections = 151
+
+// defaultShutdownTimeout is how long running queries get to finish when the
+// server is stopped.
+const defaultShutdownTimeout = 30 * time.Second
+
+// config describes a server deployment. It's read from the YAML file given by
+// -config, or assembled from the other flags:
+//
+//	listeners:
+//	  tcp: localhost:3306
+//	  socket: /run/mysqlite/mysqlite.sock
+//	tls:
+//	  cert: /etc/mysqlite/server-cert.pem
+//	  key: /etc/mysqlite/server-key.pem
+//	  client_ca: /etc/mysqlite/ca.pem
+//	  require_secure_transport: true
+//	max_connections: 151
+//	timeouts:
+//	  read: 30s
+//	  write: 30s
+//	  shutdown: 30s
+//	secure_file_priv: /var/lib/mysqlite-files
+//	databases:
+//	  default:
+//	    dsn: /var/lib/mysqlite/default.db
+//	    journal_mode: WAL
+//	    busy_timeout: 5s
+//	users:
+//	  ingest:
+//	    password: secret
+//	  dashboard:
+//	    password_hash: "*6ED7F401C4BAAD59A5148DCED0B7157002FA9D2F"
+//	    grants: [read]
+//	users_database: default
+//	logging:
+//	  level: info
+//	  format: json
+//	  file: /var/log/mysqlite.log
+//
+// Database options are those of sqlite.Options. Users get all grants on every
+// database unless they're listed. Accounts created with CREATE USER are kept
+// in users_database, which defaults to the database named default, or else the
+// first by name. With a client_ca, TLS clients must present a certificate it
+// signed.
+type config struct {
+	Listeners      listenersConfig           `yaml:"listeners"`
+	TLS            tlsConfig                 `yaml:"tls"`
+	MaxConnections uint64                    `yaml:"max_connections"`
+	Timeouts       timeoutsConfig            `yaml:"timeouts"`
+	SecureFilePriv string                    `yaml:"secure_file_priv"`
+	Databases      map[string]databaseConfig `yaml:"databases"`
+	Users          map[string]userConfig     `yaml:"users"`
+	UsersDatabase  string                    `yaml:"users_database"`
+	Logging        loggingConfig             `yaml:"logging"`
+}
+
+type listenersConfig struct {
+	TCP    string `yaml:"tcp"`
+	Socket string `yaml:"socket"`
+}
+
+type tlsConfig struct {
+	Cert                   string `yaml:"cert"`
+	Key                    string `yaml:"key"`
+	ClientCA               string `yaml:"client_ca"`
+	RequireSecureTransport bool   `yaml:"require_secure_transport"`
+}
+
+// serverConfig loads the certificates, returning nil if TLS isn't configured.
+func (t tlsConfig) serverConfig() (*tls.Config, error) {
+	if t.Cert == "" {
+		return nil, nil
+	}
+	return vttls.ServerConfig(t.Cert, t.Key, t.ClientCA)
+}
+
+type timeoutsConfig struct {
+	Read     time.Duration `yaml:"read"`
+	Write    time.Duration `yaml:"write"`
+	Shutdown time.Duration `yaml:"shutdown"`
+}
+
+type databaseConfig struct {
+	DSN            string `yaml:"dsn"`
+	sqlite.Options `yaml:",inline"`
+}
+
+// UnmarshalYAML starts databases from sqlite.DefaultOptions.
+func (d *databaseConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain databaseConfig
+	p := plain{Options: sqlite.DefaultOptions()}
+	if err := unmarshal(&p); err != nil {
+		return err
+	}
+	*d = databaseConfig(p)
+	return nil
+}
+
+type userConfig struct {
+	Password     string   `yaml:"password"`
+	PasswordHash string   `yaml:"password_hash"`
+	Grants       []string `yaml:"grants"`
+}
+
+type loggingConfig struct {
+	Level  string `yaml:"level"`
+	Format string `yaml:"format"`
+	File   string `yaml:"file"`
+}
+
+// loadConfig reads and validates the config file at path.
+func loadConfig(path string) (*config, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &config{
+		MaxConnections: defaultMaxConnections,
+		Timeouts:       timeoutsConfig{Shutdown: defaultShutdownTimeout},
+	}
+	if err := yaml.UnmarshalStrict(raw, c); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	if err := c.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return c, nil
+}
+
+var nativePasswordRegex = regexp.MustCompile(`^\*[0-9A-F]{40}$`)
+
+// validate reports the first setting in c that the server can't run with.
+func (c *config) validate() error {
+	if c.Listeners.TCP == "" && c.Listeners.Socket == "" {
+		return errors.New("listeners: no tcp address or socket")
+	}
+	if (c.TLS.Cert == "") != (c.TLS.Key == "") {
+		return errors.New("tls: cert and key must be given together")
+	}
+	if c.TLS.Cert == "" && c.TLS.ClientCA != "" {
+		return errors.New("tls: client_ca needs a cert and key")
+	}
+	if c.TLS.Cert == "" && c.TLS.RequireSecureTransport && c.Listeners.TCP != "" {
+		return errors.New("tls: require_secure_transport needs a cert and key")
+	}
+	if _, err := c.TLS.serverConfig(); err != nil {
+		return fmt.Errorf("tls: %s", err)
+	}
+	if c.MaxConnections < 1 {
+		return errors.New("max_connections: must be at least 1")
+	}
+	if c.Timeouts.Read < 0 || c.Timeouts.Write < 0 || c.Timeouts.Shutdown < 0 {
+		return errors.New("timeouts: negative timeout")
+	}
+	if c.SecureFilePriv != "" {
+		if fi, err := os.Stat(c.SecureFilePriv); err != nil || !fi.IsDir() {
+			return fmt.Errorf("secure_file_priv: %s is not a directory", c.SecureFilePriv)
+		}
+	}
+
+	if len(c.Databases) == 0 {
+		return errors.New("databases: none given")
+	}
+	for _, name := range sortedKeys(c.Databases) {
+		if strings.EqualFold(name, "information_schema") {
+			return fmt.Errorf("databases.%s: name is reserved", name)
+		}
+		if c.Databases[name].DSN == "" {
+			return fmt.Errorf("databases.%s: no dsn", name)
+		}
+		if err := c.Databases[name].Validate(); err != nil {
+			return fmt.Errorf("databases.%s: %s", name, err)
+		}
+	}
+
+	if _, ok := c.Databases[c.UsersDatabase]; c.UsersDatabase != "" && !ok {
+		return fmt.Errorf("users_database: no database %s", c.UsersDatabase)
+	}
+
+	if len(c.Users) == 0 {
+		return errors.New("users: none given")
+	}
+	for _, name := range sortedKeys(c.Users) {
+		u := c.Users[name]
+		if u.Password != "" && u.PasswordHash != "" {
+			return fmt.Errorf("users.%s: both password and password_hash given", name)
+		}
+		if u.PasswordHash != "" && !nativePasswordRegex.MatchString(u.PasswordHash) {
+			return fmt.Errorf("users.%s: password_hash is not a mysql_native_password hash", name)
+		}
+		for _, g := range u.Grants {
+			if _, ok := grantPrivileges[strings.ToLower(g)]; !ok {
+				return fmt.Errorf("users.%s: unknown grant %q", name, g)
+			}
+		}
+	}
+
+	if c.Logging.Level != "" {
+		if _, err := logrus.ParseLevel(c.Logging.Level); err != nil {
+			return fmt.Errorf("logging.level: %s", err)
+		}
+	}
+	switch c.Logging.Format {
+	case "", "text", "json":
+	default:
+		return fmt.Errorf("logging.format: unknown format %q", c.Logging.Format)
+	}
+	return nil
+}
+
+// grantPrivileges are the privileges on every database each of a user's grants
+// gives: read for dashboards, write for ingest, and admin to manage other
+// accounts and files.
+var grantPrivileges = map[string]server.Privilege{
+	"read": server.SelectPriv,
+	"write": server.InsertPriv | server.UpdatePriv | server.DeletePriv |
+		server.CreatePriv | server.DropPriv | server.IndexPriv | server.AlterPriv,
+	"admin": server.FilePriv | server.CreateUserPriv | server.GrantOptionPriv,
+}
+
+// accounts returns the accounts of c's users.
+func (c *config) accounts() map[string]server.Account {
+	accounts := make(map[string]server.Account, len(c.Users))
+	for name, u := range c.Users {
+		priv := server.AllPrivileges | server.GrantOptionPriv
+		if len(u.Grants) > 0 {
+			priv = 0
+			for _, g := range u.Grants {
+				priv |= grantPrivileges[strings.ToLower(g)]
+			}
+		}
+		acc := server.Account{
+			PasswordHash: u.PasswordHash,
+			Privileges:   map[server.Scope]server.Privilege{{}: priv},
+		}
+		if u.PasswordHash == "" {
+			acc.PasswordHash = auth.NativePassword(u.Password)
+		}
+		accounts[name] = acc
+	}
+	return accounts
+}
+
+// usersDatabase returns the name of the database accounts created with SQL
+// are kept in.
+func (c *config) usersDatabase() string {
+	if c.UsersDatabase != "" {
+		return c.UsersDatabase
+	}
+	if _, ok := c.Databases["default"]; ok {
+		return "default"
+	}
+	return sortedKeys(c.Databases)[0]
+}
+
+// logFile is the file logs are written to, if not stderr.
+var logFile *os.File
+
+// apply configures logrus according to l, reopening the log file so that it
+// can be rotated.
+func (l loggingConfig) apply() error {
+	level := logrus.InfoLevel
+	if l.Level != "" {
+		level, _ = logrus.ParseLevel(l.Level)
+	}
+	var formatter logrus.Formatter = &logrus.TextFormatter{}
+	if l.Format == "json" {
+		formatter = &logrus.JSONFormatter{}
+	}
+
+	var f *os.File
+	if l.File != "" {
+		var err error
+		f, err = os.OpenFile(l.File, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return err
+		}
+		logrus.SetOutput(f)
+	} else {
+		logrus.SetOutput(os.Stderr)
+	}
+	if logFile != nil {
+		logFile.Close()
+	}
+	logFile = f
+
+	logrus.SetLevel(level)
+	logrus.SetFormatter(formatter)
+	return nil
+}
+
+// restartSettings returns the settings that differ between c and next and
+// only take effect on restart.
+func (c *config) restartSettings(next *config) []string {
+	var changed []string
+	if c.Listeners != next.Listeners {
+		changed = append(changed, "listeners")
+	}
+	if c.TLS != next.TLS {
+		changed = append(changed, "tls")
+	}
+	if c.MaxConnections != next.MaxConnections {
+		changed = append(changed, "max_connections")
+	}
+	if c.Timeouts != next.Timeouts {
+		changed = append(changed, "timeouts")
+	}
+	if c.SecureFilePriv != next.SecureFilePriv {
+		changed = append(changed, "secure_file_priv")
+	}
+	if c.usersDatabase() != next.usersDatabase() {
+		changed = append(changed, "users_database")
+	}
+	for _, name := range sortedKeys(c.Databases) {
+		db, ok := next.Databases[name]
+		if !ok {
+			changed = append(changed, "databases."+name+" (removed)")
+		} else if !reflect.DeepEqual(db, c.Databases[name]) {
+			changed = append(changed, "databases."+name)
+		}
+	}
+	return changed
+}
+
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	for _, k := range reflect.ValueOf(m).MapKeys() {
+		keys = append(keys, k.String())
+	}
+	sort.Strings(keys)
+	return keys
+}
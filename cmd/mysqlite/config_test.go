@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kevin-cantwell/mysqlite/internal/server"
+	"github.com/kevin-cantwell/mysqlite/internal/sqlite"
+)
+
+// writeConfig writes a config file with the given YAML to path.
+func writeConfig(t *testing.T, path, yaml string) {
+	t.Helper()
+	if err := ioutil.WriteFile(path, []byte(yaml), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mysqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "mysqlite.yaml")
+
+	writeConfig(t, path, `
+listeners:
+  tcp: localhost:3306
+databases:
+  default:
+    dsn: default.db
+users:
+  dashboard:
+    password: secret
+    grants: [read]
+`)
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MaxConnections != defaultMaxConnections || cfg.Timeouts.Shutdown != defaultShutdownTimeout {
+		t.Errorf("defaults are %d connections and %v, want %d and %v", cfg.MaxConnections, cfg.Timeouts.Shutdown, defaultMaxConnections, defaultShutdownTimeout)
+	}
+	if cfg.Databases["default"].Options != sqlite.DefaultOptions() {
+		t.Errorf("database options are %+v, want the defaults", cfg.Databases["default"].Options)
+	}
+	if acc := cfg.accounts()["dashboard"]; !reflect.DeepEqual(acc.Privileges, map[server.Scope]server.Privilege{{}: server.SelectPriv}) {
+		t.Errorf("dashboard has privileges %v, want SELECT", acc.Privileges)
+	}
+	if cfg.usersDatabase() != "default" {
+		t.Errorf("users database is %q, want default", cfg.usersDatabase())
+	}
+
+	for _, tt := range []struct {
+		yaml, err string
+	}{
+		{"databases: {default: {dsn: a.db}}\nusers: {root: {password: x}}", "listeners: no tcp address or socket"},
+		{"listeners: {tcp: ':3306'}\nusers: {root: {password: x}}", "databases: none given"},
+		{"listeners: {tcp: ':3306'}\ndatabases: {default: {dsn: a.db}}\nusers: {root: {grants: [everything]}}", `users.root: unknown grant "everything"`},
+		{"listeners: {tcp: ':3306'}\ndatabases: {default: {dsn: a.db}}\nusers: {root: {password_hash: nope}}", "users.root: password_hash is not a mysql_native_password hash"},
+		{"listeners: {tcp: ':3306'}\ndatabases: {default: {dsn: a.db}}\nusers: {root: {}}\ntls: {require_secure_transport: true}", "tls: require_secure_transport needs a cert and key"},
+		{"listeners: {tcp: ':3306'}\nport: 3306", "field port not found"},
+	} {
+		writeConfig(t, path, tt.yaml)
+		if _, err := loadConfig(path); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%q: got %v, want %q", tt.yaml, err, tt.err)
+		}
+	}
+}
+
+func TestReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mysqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "mysqlite.yaml")
+
+	writeConfig(t, path, `
+listeners:
+  tcp: localhost:3306
+databases:
+  default:
+    dsn: `+filepath.Join(dir, "default.db")+`
+users:
+  alice:
+    password: secret
+`)
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sqlite.NewDatabase("default", cfg.Databases["default"].DSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	e := newEngine()
+	e.AddDatabase(db)
+	accounts, err := server.NewAccounts(context.Background(), db, cfg.accounts())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Users and new databases change at once, listeners on restart
+	writeConfig(t, path, `
+listeners:
+  tcp: localhost:3307
+databases:
+  default:
+    dsn: `+filepath.Join(dir, "default.db")+`
+  logs:
+    dsn: `+filepath.Join(dir, "logs.db")+`
+users:
+  bob:
+    password: hunter2
+    grants: [read]
+`)
+	cfg = reload(cfg, path, accounts, e)
+	if _, err := accounts.Grants("alice"); err == nil {
+		t.Error("alice still has an account")
+	}
+	if grants, err := accounts.Grants("bob"); err != nil || !reflect.DeepEqual(grants, []string{"GRANT SELECT ON *.* TO `bob`@`%`"}) {
+		t.Errorf("bob has grants %v, %v", grants, err)
+	}
+	logs, err := e.Catalog.Database("logs")
+	if err != nil {
+		t.Fatalf("logs database not added: %v", err)
+	}
+	defer logs.(*sqlite.Database).Close()
+	if cfg.Listeners.TCP != "localhost:3306" {
+		t.Errorf("listening on %s after reloading, want localhost:3306 until a restart", cfg.Listeners.TCP)
+	}
+	if _, ok := cfg.Databases["logs"]; !ok {
+		t.Error("reloaded config has no logs database")
+	}
+
+	// An invalid file leaves the config in effect
+	writeConfig(t, path, "listeners: [")
+	if reloaded := reload(cfg, path, accounts, e); reloaded != cfg {
+		t.Error("an invalid config file was applied")
+	}
+	if _, err := accounts.Grants("bob"); err != nil {
+		t.Errorf("bob lost the account to an invalid config: %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/kevin-cantwell/mysqlite/internal/sqlite"
+	"github.com/liquidata-inc/go-mysql-server/sql"
+)
+
+// dump writes a mysqlite database file as MySQL statements that load into a
+// MySQL server:
+//
+//	mysqlite dump [-o file] database.db
+func dump(args []string) error {
+	fs := flag.NewFlagSet("dump", flag.ExitOnError)
+	out := fs.String("o", "", "Write the dump to `file` rather than stdout.")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: mysqlite dump [-o file] database.db\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	// Opening a database that doesn't exist would create it
+	dsn := fs.Arg(0)
+	if _, err := os.Stat(dsn); err != nil {
+		return err
+	}
+	db, err := sqlite.NewDatabase("default", dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	return db.Dump(sql.NewEmptyContext(), w)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// userFlag is a repeatable -user flag adding a "name:password" account.
+type userFlag map[string]string
+
+func (f userFlag) String() string {
+	names := make([]string, 0, len(f))
+	for name := range f {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+func (f userFlag) Set(s string) error {
+	i := strings.IndexByte(s, ':')
+	if i <= 0 {
+		return fmt.Errorf("%q is not name:password", s)
+	}
+	name, password := s[:i], s[i+1:]
+	if _, ok := f[name]; ok {
+		return fmt.Errorf("duplicate user %s", name)
+	}
+	f[name] = password
+	return nil
+}
+
+// envUsers returns the accounts in the comma separated name:password list of
+// the environment variable name.
+func envUsers(name string) (userFlag, error) {
+	users := userFlag{}
+	for _, s := range strings.Split(os.Getenv(name), ",") {
+		if s == "" {
+			continue
+		}
+		if err := users.Set(s); err != nil {
+			return nil, fmt.Errorf("%s: %s", name, err)
+		}
+	}
+	return users, nil
+}
+
+// envString returns the value of the environment variable name, or def if
+// it isn't set.
+func envString(name, def string) string {
+	if v, ok := os.LookupEnv(name); ok {
+		return v
+	}
+	return def
+}
+
+// envInt is like envString for integers, exiting if the variable isn't one.
+func envInt(name string, def int) int {
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "mysqlite: %s: %q is not an integer\n", name, v)
+		os.Exit(2)
+	}
+	return n
+}
+
+// envDuration is like envString for durations, exiting if the variable isn't
+// one.
+func envDuration(name string, def time.Duration) time.Duration {
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "mysqlite: %s: %q is not a duration\n", name, v)
+		os.Exit(2)
+	}
+	return d
+}
+
+// envBool is like envString for booleans, exiting if the variable isn't one.
+func envBool(name string, def bool) bool {
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "mysqlite: %s: %q is not a boolean\n", name, v)
+		os.Exit(2)
+	}
+	return b
+}
+
+// randomPassword returns a password for an account nobody configured, so that
+// the server never accepts a well-known one.
+func randomPassword() string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestUserFlag(t *testing.T) {
+	users := userFlag{}
+	for _, s := range []string{"alice:secret", "bob:pass:word", "carol:"} {
+		if err := users.Set(s); err != nil {
+			t.Errorf("Set(%q): %v", s, err)
+		}
+	}
+	for _, s := range []string{"alice:again", "nobody", ":secret"} {
+		if err := users.Set(s); err == nil {
+			t.Errorf("Set(%q) succeeded", s)
+		}
+	}
+	if want := (userFlag{"alice": "secret", "bob": "pass:word", "carol": ""}); !reflect.DeepEqual(users, want) {
+		t.Errorf("users are %v, want %v", users, want)
+	}
+	if s := users.String(); s != "alice,bob,carol" {
+		t.Errorf("String() = %q, want alice,bob,carol", s)
+	}
+}
+
+func TestEnvUsers(t *testing.T) {
+	const name = "MYSQLITE_TEST_USERS"
+	defer os.Unsetenv(name)
+
+	os.Setenv(name, "alice:secret,,bob:hunter2")
+	users, err := envUsers(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (userFlag{"alice": "secret", "bob": "hunter2"}); !reflect.DeepEqual(users, want) {
+		t.Errorf("users are %v, want %v", users, want)
+	}
+
+	os.Setenv(name, "alice")
+	if _, err := envUsers(name); err == nil {
+		t.Error("a user without a password was accepted")
+	}
+	os.Unsetenv(name)
+	if users, err := envUsers(name); err != nil || len(users) != 0 {
+		t.Errorf("unset variable gives %v, %v", users, err)
+	}
+	if s := envString(name, "default"); s != "default" {
+		t.Errorf("envString of an unset variable = %q", s)
+	}
+}
+
+func TestDatabaseFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mysqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "app.db")
+	if got, _ := databaseFiles(file); !reflect.DeepEqual(got, map[string]string{"default": file}) {
+		t.Errorf("a file that doesn't exist yet gives %v", got)
+	}
+	if got, _ := databaseFiles(dir); !reflect.DeepEqual(got, map[string]string{"default": filepath.Join(dir, "default.db")}) {
+		t.Errorf("an empty directory gives %v", got)
+	}
+	for _, name := range []string{"app.db", "logs.db", "notes.txt"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := map[string]string{"app": file, "logs": filepath.Join(dir, "logs.db")}
+	if got, _ := databaseFiles(dir); !reflect.DeepEqual(got, want) {
+		t.Errorf("a directory gives %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// gencert writes a self-signed certificate and its key, for trying TLS out
+// without a CA. Clients can't verify it, so it suits --ssl-mode=REQUIRED but
+// not VERIFY_CA or VERIFY_IDENTITY:
+//
+//	mysqlite gencert [-host names] [-days n] cert.pem key.pem
+func gencert(args []string) error {
+	fs := flag.NewFlagSet("gencert", flag.ExitOnError)
+	hosts := fs.String("host", "localhost,127.0.0.1,::1", "Comma separated host `names` and IP addresses the certificate is for.")
+	days := fs.Int("days", 365, "Days the certificate is valid for.")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: mysqlite gencert [-host names] [-days n] cert.pem key.pem\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 2 || *days < 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"mysqlite"}, CommonName: "mysqlite development certificate"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.AddDate(0, 0, *days),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	for _, h := range strings.Split(*hosts, ",") {
+		if h = strings.TrimSpace(h); h == "" {
+			continue
+		}
+		if ip := net.ParseIP(h); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, h)
+		}
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(fs.Arg(0), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert}), 0644); err != nil {
+		return err
+	}
+	// The key is written last, and only readable by its owner
+	return ioutil.WriteFile(fs.Arg(1), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600)
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/kevin-cantwell/mysqlite/internal/sqlite"
+	sqle "github.com/liquidata-inc/go-mysql-server"
+	"github.com/liquidata-inc/go-mysql-server/sql"
+	"github.com/liquidata-inc/vitess/go/vt/sqlparser"
+)
+
+// importFile loads a mysqldump file into a mysqlite database file, creating it
+// if it doesn't exist:
+//
+//	mysqlite import database.db dump.sql
+//
+// Tables are created and rows inserted by the engine, as they would be by a
+// client. Statements with no counterpart in mysqlite, like LOCK TABLES and SET,
+// are skipped and reported on stderr, as are foreign key constraints.
+func importFile(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: mysqlite import database.db [dump.sql]\n")
+		fmt.Fprintf(fs.Output(), "Reads the dump from stdin if no file is given.\n")
+	}
+	fs.Parse(args)
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	var r io.Reader = os.Stdin
+	if fs.NArg() == 2 {
+		f, err := os.Open(fs.Arg(1))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	db, err := sqlite.NewDatabase("default", fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	im, err := newImporter(db)
+	if err != nil {
+		return err
+	}
+	err = splitStatements(r, im.exec)
+	fmt.Fprintf(os.Stderr, "imported %d tables and %d rows, skipped %d statements\n", im.tables, im.rows, im.skipped)
+	return err
+}
+
+var createTableRegex = regexp.MustCompile(`(?is)^\s*create\s+table\s`)
+
+type importer struct {
+	e       *sqle.Engine
+	db      *sqlite.Database
+	session sql.Session
+
+	tables, rows, skipped int
+}
+
+// newImporter returns an importer into db.
+func newImporter(db *sqlite.Database) (*importer, error) {
+	e := newEngine()
+	e.AddDatabase(db)
+	e.Catalog.MustRegister(sqlite.SpatialFunctions...)
+	e.Catalog.MustRegister(sqlite.MySQLFunctions...)
+
+	// Values that don't fit their columns fail the import rather than being
+	// truncated
+	session := sql.NewBaseSession()
+	if err := session.Set(context.Background(), "sql_mode", sql.LongText, sqlite.DefaultSQLMode); err != nil {
+		return nil, err
+	}
+	return &importer{e: e, db: db, session: session}, nil
+}
+
+// exec runs a statement of the dump beginning on the given line.
+func (im *importer) exec(line int, stmt string) error {
+	query := stmt
+	if createTableRegex.MatchString(stmt) {
+		var fks []string
+		stmt, fks = sqlite.StripForeignKeys(stmt)
+		for _, fk := range fks {
+			im.skip(line, fk)
+		}
+		// A dump of a mysqlite table declares its rowtime column, which the
+		// table then adds itself
+		stmt = sqlite.StripRowtime(stmt)
+		query = sqlite.RewriteCreateTable(stmt)
+	}
+
+	parsed, err := sqlparser.Parse(query)
+	if err == sqlparser.ErrEmpty {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("line %d: %s", line, err)
+	}
+
+	ctx := sql.NewContext(context.Background(), sql.WithSession(im.session), sql.WithQuery(stmt))
+	ctx.SetCurrentDatabase(im.db.Name())
+
+	switch s := parsed.(type) {
+	case *sqlparser.DDL:
+		switch s.Action {
+		case sqlparser.CreateStr:
+			im.tables++
+		case sqlparser.DropStr:
+		default:
+			im.skip(line, stmt)
+			return nil
+		}
+	case *sqlparser.Insert:
+		if s.Action != sqlparser.InsertStr {
+			im.skip(line, stmt)
+			return nil
+		}
+		if len(s.Columns) == 0 {
+			if err := im.insertColumns(ctx, s); err != nil {
+				return fmt.Errorf("line %d: %s", line, err)
+			}
+			query = sqlparser.String(s)
+		}
+	default:
+		im.skip(line, stmt)
+		return nil
+	}
+
+	if _, ok := parsed.(*sqlparser.Insert); ok {
+		query, _ = sqlite.ExactDecimalLiterals(query)
+	}
+	_, iter, err := im.e.Query(ctx, query)
+	if err != nil {
+		return fmt.Errorf("line %d: %s", line, err)
+	}
+	rows, err := sql.RowIterToRows(iter)
+	if err != nil {
+		return fmt.Errorf("line %d: %s", line, err)
+	}
+	if _, ok := parsed.(*sqlparser.Insert); ok && len(rows) == 1 {
+		if ok, isOk := rows[0][0].(sql.OkResult); isOk {
+			im.rows += int(ok.RowsAffected)
+		}
+	}
+	return nil
+}
+
+// insertColumns lists the columns an INSERT without a column list inserts
+// into. A dump from MySQL has no values for mysqlite's rowtime column, which
+// is then left for the table to fill in.
+func (im *importer) insertColumns(ctx *sql.Context, ins *sqlparser.Insert) error {
+	table, ok, err := im.db.GetTableInsensitive(ctx, ins.Table.Name.String())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return sql.ErrTableNotFound.New(ins.Table.Name.String())
+	}
+	schema := table.Schema()
+	if values, ok := ins.Rows.(sqlparser.Values); ok && len(values) > 0 &&
+		len(values[0]) == len(schema)-1 && schema[0].Name == "rowtime" {
+		schema = schema[1:]
+	}
+	for _, col := range schema {
+		ins.Columns = append(ins.Columns, sqlparser.NewColIdent(col.Name))
+	}
+	return nil
+}
+
+func (im *importer) skip(line int, stmt string) {
+	im.skipped++
+	stmt = strings.Join(strings.Fields(stmt), " ")
+	if len(stmt) > 72 {
+		stmt = stmt[:69] + "..."
+	}
+	fmt.Fprintf(os.Stderr, "line %d: skipped %s\n", line, stmt)
+}
+
+// splitStatements reads the semicolon terminated statements of a SQL script,
+// calling f with each one and the line it begins on. Semicolons in quoted
+// strings and identifiers, and in comments, don't end a statement. Comments
+// within statements are left for the parser to skip.
+func splitStatements(r io.Reader, f func(line int, stmt string) error) error {
+	const (
+		normal = iota
+		quoted
+		lineComment
+		blockComment
+	)
+	var (
+		br    = bufio.NewReader(r)
+		stmt  strings.Builder
+		state = normal
+		quote byte
+		prev  byte
+		line  = 1
+		start = 0 // the line the statement begins on, once it has begun
+		begin int // where in stmt it begins, after any comments
+	)
+	for {
+		c, err := br.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if c == '\n' {
+			line++
+		}
+		if state == normal && c == ';' {
+			if err := f(start, stmt.String()[begin:]); err != nil {
+				return err
+			}
+			stmt.Reset()
+			start, begin, prev = 0, 0, 0
+			continue
+		}
+		stmt.WriteByte(c)
+
+		switch state {
+		case lineComment:
+			if c == '\n' {
+				state = normal
+			}
+		case blockComment:
+			if c == '/' && prev == '*' {
+				state = normal
+				c = 0 // the * can't also close the next comment
+			}
+		case quoted:
+			if c == '\\' && quote != '`' {
+				// The escaped character can't end the string
+				if c, err = br.ReadByte(); err != nil {
+					break
+				}
+				stmt.WriteByte(c)
+				if c == '\n' {
+					line++
+				}
+				c = 0
+			} else if c == quote {
+				state = normal
+			}
+		default:
+			switch {
+			case c == '#' || (c == '-' && prev == '-'):
+				state = lineComment
+			case c == '*' && prev == '/':
+				// Versioned comments, /*!40101 ... */, hold the SQL of the
+				// statement rather than commenting
+				if p, _ := br.Peek(1); len(p) > 0 && p[0] == '!' {
+					if start == 0 {
+						start, begin = line, stmt.Len()-2
+					}
+				} else {
+					state = blockComment
+				}
+				c = 0 // the * can't also close the comment
+			case c == '\'' || c == '"' || c == '`':
+				state, quote = quoted, c
+				fallthrough
+			case c != ' ' && c != '\t' && c != '\r' && c != '\n' && c != '-' && c != '/':
+				if start == 0 {
+					start, begin = line, stmt.Len()-1
+				}
+			}
+		}
+		prev = c
+	}
+	if start != 0 {
+		return f(start, stmt.String()[begin:])
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kevin-cantwell/mysqlite/internal/sqlite"
+	"github.com/liquidata-inc/go-mysql-server/sql"
+)
+
+// importInto imports script into a new database, and returns the database.
+func importInto(t *testing.T, dir, name, script string) *sqlite.Database {
+	t.Helper()
+	db, err := sqlite.NewDatabase("default", filepath.Join(dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	im, err := newImporter(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := splitStatements(strings.NewReader(script), im.exec); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// dumpString returns db dumped as MySQL statements.
+func dumpString(t *testing.T, db *sqlite.Database) string {
+	t.Helper()
+	var b bytes.Buffer
+	ctx := sql.NewContext(context.Background(), sql.WithSession(sql.NewBaseSession()))
+	if err := db.Dump(ctx, &b); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestImport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mysqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// A dump from MySQL has no rowtime column
+	source := importInto(t, dir, "source.db", `
+CREATE TABLE events (
+  id int NOT NULL,
+  name varchar(20) DEFAULT NULL,
+  PRIMARY KEY (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+LOCK TABLES events WRITE;
+INSERT INTO events VALUES (1,'one'),(2,'two');
+UNLOCK TABLES;
+`)
+	dumped := dumpString(t, source)
+	if !strings.Contains(dumped, "`rowtime` bigint") {
+		t.Fatalf("dump has no rowtime column:\n%s", dumped)
+	}
+
+	// A dump of a mysqlite table declares rowtime, and has values for it
+	copied := importInto(t, dir, "copy.db", dumped)
+	if got := dumpString(t, copied); got != dumped {
+		t.Errorf("dump of the imported copy differs:\n%s\nwant\n%s", got, dumped)
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	type statement struct {
+		line int
+		stmt string
+	}
+	tests := []struct {
+		name   string
+		script string
+		want   []statement
+	}{
+		{
+			"empty",
+			"",
+			nil,
+		},
+		{
+			"only comments",
+			"-- mysqldump\n# comment\n/* block; */\n",
+			nil,
+		},
+		{
+			"statements",
+			"CREATE TABLE t (id INT);\nINSERT INTO t VALUES (1);\n\nINSERT INTO t VALUES (2);\n",
+			[]statement{
+				{1, "CREATE TABLE t (id INT)"},
+				{2, "INSERT INTO t VALUES (1)"},
+				{4, "INSERT INTO t VALUES (2)"},
+			},
+		},
+		{
+			"no final semicolon",
+			"SELECT 1;\nSELECT 2\n",
+			[]statement{{1, "SELECT 1"}, {2, "SELECT 2\n"}},
+		},
+		{
+			"multiline statement",
+			"\n\nCREATE TABLE t (\n  id INT\n);\n",
+			[]statement{{3, "CREATE TABLE t (\n  id INT\n)"}},
+		},
+		{
+			"leading comments",
+			"-- Table structure;\n/* for t; */ CREATE TABLE t (id INT);\n",
+			[]statement{{2, "CREATE TABLE t (id INT)"}},
+		},
+		{
+			"comments within",
+			"INSERT INTO t -- rows;\nVALUES (1) /* one; */;\n",
+			[]statement{{1, "INSERT INTO t -- rows;\nVALUES (1) /* one; */"}},
+		},
+		{
+			"quoted semicolons",
+			"INSERT INTO t VALUES ('a;b', \"c;d\");\nSELECT `e;f` FROM t;\n",
+			[]statement{
+				{1, "INSERT INTO t VALUES ('a;b', \"c;d\")"},
+				{2, "SELECT `e;f` FROM t"},
+			},
+		},
+		{
+			"escaped quotes",
+			"INSERT INTO t VALUES ('it\\'s;', 'it''s;', \"\\\";\");\nSELECT 1;\n",
+			[]statement{
+				{1, "INSERT INTO t VALUES ('it\\'s;', 'it''s;', \"\\\";\")"},
+				{2, "SELECT 1"},
+			},
+		},
+		{
+			"backslashes in identifiers",
+			"SELECT `a\\`;\nSELECT 2;\n",
+			[]statement{{1, "SELECT `a\\`"}, {2, "SELECT 2"}},
+		},
+		{
+			"newlines in strings",
+			"INSERT INTO t VALUES ('a\nb', 'c\\\nd');\nSELECT 1;\n",
+			[]statement{
+				{1, "INSERT INTO t VALUES ('a\nb', 'c\\\nd')"},
+				{4, "SELECT 1"},
+			},
+		},
+		{
+			"versioned comments",
+			"/*!40101 SET NAMES utf8 */;\n/*!40000 ALTER TABLE t DISABLE KEYS */;\n",
+			[]statement{
+				{1, "/*!40101 SET NAMES utf8 */"},
+				{2, "/*!40000 ALTER TABLE t DISABLE KEYS */"},
+			},
+		},
+		{
+			"comment ending like it starts",
+			"/*/ SELECT 1; */ SELECT 2;\n",
+			[]statement{{1, "SELECT 2"}},
+		},
+	}
+	for _, tt := range tests {
+		var got []statement
+		err := splitStatements(strings.NewReader(tt.script), func(line int, stmt string) error {
+			got = append(got, statement{line, stmt})
+			return nil
+		})
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
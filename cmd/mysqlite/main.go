@@ -1,42 +1,343 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
+	"github.com/kevin-cantwell/mysqlite/internal/server"
 	"github.com/kevin-cantwell/mysqlite/internal/sqlite"
 	sqle "github.com/liquidata-inc/go-mysql-server"
-	"github.com/liquidata-inc/go-mysql-server/auth"
 	"github.com/liquidata-inc/go-mysql-server/memory"
-	"github.com/liquidata-inc/go-mysql-server/server"
+	sqleserver "github.com/liquidata-inc/go-mysql-server/server"
 	"github.com/liquidata-inc/go-mysql-server/sql"
+	"github.com/liquidata-inc/go-mysql-server/sql/analyzer"
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/sirupsen/logrus"
 )
 
+// subcommands run instead of the server when named by the first argument.
+var subcommands = map[string]func(args []string) error{
+	"dump":    dump,
+	"gencert": gencert,
+	"import":  importFile,
+}
+
 func main() {
-	driver := sqle.NewDefault()
-	driver.AddDatabase(createSQLiteDatabase(os.Args[1]))
+	if len(os.Args) > 1 {
+		if cmd, ok := subcommands[os.Args[1]]; ok {
+			if err := cmd(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "mysqlite %s: %s\n", os.Args[1], err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	builtin := map[string]bool{}
+	flag.VisitAll(func(f *flag.Flag) { builtin[f.Name] = true })
 
-	config := server.Config{
-		Protocol: "tcp",
-		Address:  "localhost:3306",
-		Auth:     auth.NewNativeSingle("user", "pass", auth.AllPermissions),
+	configFile := flag.String("config", os.Getenv("MYSQLITE_CONFIG"), "YAML config file describing the server, instead of the other flags. SIGHUP reloads it ($MYSQLITE_CONFIG).")
+	opts := sqlite.DefaultOptions()
+	opts.AddFlags(flag.CommandLine)
+	secureFilePriv := flag.String("secure-file-priv", "", "Directory SELECT ... INTO OUTFILE writes files to and LOAD DATA reads them from.")
+	data := flag.String("data", os.Getenv("MYSQLITE_DATA"), "Database file, or directory of .db files to serve as databases named after them ($MYSQLITE_DATA).")
+	bindAddress := flag.String("bind-address", envString("MYSQLITE_BIND_ADDRESS", "localhost"), "Address to listen on for TCP connections ($MYSQLITE_BIND_ADDRESS).")
+	port := flag.Int("port", envInt("MYSQLITE_PORT", 3306), "Port to listen on for TCP connections ($MYSQLITE_PORT).")
+	skipNetworking := flag.Bool("skip-networking", envBool("MYSQLITE_SKIP_NETWORKING", false), "Only listen on -socket, not on TCP ($MYSQLITE_SKIP_NETWORKING).")
+	socket := flag.String("socket", os.Getenv("MYSQLITE_SOCKET"), "Unix socket to listen on as well ($MYSQLITE_SOCKET).")
+	sslCert := flag.String("ssl-cert", os.Getenv("MYSQLITE_SSL_CERT"), "PEM certificate to offer clients TLS with ($MYSQLITE_SSL_CERT).")
+	sslKey := flag.String("ssl-key", os.Getenv("MYSQLITE_SSL_KEY"), "PEM private key of -ssl-cert ($MYSQLITE_SSL_KEY).")
+	sslCA := flag.String("ssl-ca", os.Getenv("MYSQLITE_SSL_CA"), "PEM CA certificates TLS clients must present a certificate signed by ($MYSQLITE_SSL_CA).")
+	requireSecureTransport := flag.Bool("require-secure-transport", envBool("MYSQLITE_REQUIRE_SECURE_TRANSPORT", false), "Refuse TCP connections without TLS ($MYSQLITE_REQUIRE_SECURE_TRANSPORT).")
+	shutdownTimeout := flag.Duration("shutdown-timeout", envDuration("MYSQLITE_SHUTDOWN_TIMEOUT", defaultShutdownTimeout), "How long running queries get to finish on SIGINT or SIGTERM before they're killed ($MYSQLITE_SHUTDOWN_TIMEOUT).")
+	users := userFlag{}
+	flag.Var(users, "user", "Account clients log in as, as name:password. Repeatable; replaces the comma separated list in $MYSQLITE_USERS.")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: mysqlite [flags] database.db|directory\n       mysqlite -config mysqlite.yaml\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if *configFile != "" {
+		flag.Visit(func(f *flag.Flag) {
+			if !builtin[f.Name] && f.Name != "config" {
+				fatal(fmt.Errorf("-%s can't be combined with -config", f.Name))
+			}
+		})
+		if flag.NArg() > 0 {
+			fatal(errors.New("a database can't be given with -config"))
+		}
+		cfg, err := loadConfig(*configFile)
+		if err != nil {
+			fatal(err)
+		}
+		if err := serve(cfg, *configFile); err != nil {
+			fatal(err)
+		}
+		return
 	}
 
-	s, err := server.NewDefaultServer(config, driver)
+	if flag.NArg() == 1 {
+		*data = flag.Arg(0)
+	}
+	if *data == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if len(users) == 0 {
+		var err error
+		if users, err = envUsers("MYSQLITE_USERS"); err != nil {
+			fatal(err)
+		}
+	}
+	if len(users) == 0 {
+		users["root"] = randomPassword()
+		fmt.Fprintf(os.Stderr, "mysqlite: no users given, generated password for root: %s\n", users["root"])
+	}
+
+	cfg := &config{
+		Listeners: listenersConfig{Socket: *socket},
+		TLS: tlsConfig{
+			Cert:                   *sslCert,
+			Key:                    *sslKey,
+			ClientCA:               *sslCA,
+			RequireSecureTransport: *requireSecureTransport,
+		},
+		MaxConnections: defaultMaxConnections,
+		Timeouts:       timeoutsConfig{Shutdown: *shutdownTimeout},
+		SecureFilePriv: *secureFilePriv,
+		Databases:      map[string]databaseConfig{},
+		Users:          map[string]userConfig{},
+	}
+	if !*skipNetworking {
+		cfg.Listeners.TCP = net.JoinHostPort(*bindAddress, strconv.Itoa(*port))
+	}
+	files, err := databaseFiles(*data)
 	if err != nil {
-		panic(err)
+		fatal(err)
+	}
+	for name, dsn := range files {
+		cfg.Databases[name] = databaseConfig{DSN: dsn, Options: opts}
 	}
+	for name, password := range users {
+		cfg.Users[name] = userConfig{Password: password}
+	}
+	if err := cfg.validate(); err != nil {
+		fatal(err)
+	}
+	if err := serve(cfg, ""); err != nil {
+		fatal(err)
+	}
+}
 
-	s.Start()
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "mysqlite: %s\n", err)
+	os.Exit(1)
 }
 
-func createSQLiteDatabase(dsn string) *sqlite.Database {
-	db, err := sqlite.NewDatabase("default", dsn)
+// serve runs the server described by cfg until SIGINT or SIGTERM. If cfg was
+// read from the file at path, SIGHUP reloads it.
+//
+// On SIGINT or SIGTERM the server stops accepting connections and gives
+// running queries the shutdown timeout to finish, or until a second signal,
+// before killing them. Then it closes the databases, checkpointing their logs,
+// as it does if the server fails to start.
+func serve(cfg *config, path string) error {
+	if err := cfg.Logging.apply(); err != nil {
+		return err
+	}
+
+	driver := newEngine()
+	defer closeDatabases(driver)
+	var usersDB *sqlite.Database
+	for _, name := range sortedKeys(cfg.Databases) {
+		db, err := sqlite.NewDatabaseWithOptions(name, cfg.Databases[name].DSN, cfg.Databases[name].Options)
+		if err != nil {
+			return fmt.Errorf("database %s: %s", name, err)
+		}
+		driver.AddDatabase(db)
+		if name == cfg.usersDatabase() {
+			usersDB = db
+		}
+	}
+	accounts, err := server.NewAccounts(context.Background(), usersDB, cfg.accounts())
+	if err != nil {
+		return fmt.Errorf("users: %s", err)
+	}
+	driver.AddDatabase(sqlite.NewInformationSchemaDatabase(driver.Catalog, accounts.Visible))
+	driver.Catalog.MustRegister(sqlite.MatchFunction(driver.Catalog))
+	driver.Catalog.MustRegister(sqlite.SpatialFunctions...)
+	driver.Catalog.MustRegister(sqlite.MySQLFunctions...)
+	driver.Auth = accounts
+	tlsConfig, err := cfg.TLS.serverConfig()
+	if err != nil {
+		return fmt.Errorf("tls: %s", err)
+	}
+
+	s, err := server.NewServer(server.Config{
+		Config: sqleserver.Config{
+			Protocol:         "tcp",
+			Address:          cfg.Listeners.TCP,
+			Auth:             accounts,
+			ConnReadTimeout:  cfg.Timeouts.Read,
+			ConnWriteTimeout: cfg.Timeouts.Write,
+			MaxConnections:   cfg.MaxConnections,
+		},
+		Socket:                 cfg.Listeners.Socket,
+		SecureFilePriv:         cfg.SecureFilePriv,
+		TLSConfig:              tlsConfig,
+		RequireSecureTransport: cfg.TLS.RequireSecureTransport,
+	}, driver)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	return db
+
+	if path != "" {
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		defer signal.Stop(hup)
+		go func() {
+			for range hup {
+				cfg = reload(cfg, path, accounts, driver)
+			}
+		}()
+	}
+
+	stop := make(chan os.Signal, 2)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(stop)
+	shutdownTimeout := cfg.Timeouts.Shutdown
+	shutdown := make(chan error, 1)
+	go func() {
+		logrus.Infof("received %s, shutting down", <-stop)
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		go func() {
+			select {
+			case <-stop:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		shutdown <- s.Shutdown(ctx)
+	}()
+
+	if err := s.Start(); err != nil {
+		return err
+	}
+	if err := <-shutdown; err != nil {
+		logrus.Warnf("running queries killed: %s", err)
+	}
+	logrus.Info("shut down")
+	return nil
+}
+
+// closeDatabases closes the mysqlite databases of e, including those added
+// by a reload.
+func closeDatabases(e *sqle.Engine) {
+	for _, db := range e.Catalog.AllDatabases() {
+		if sdb, ok := db.(*sqlite.Database); ok {
+			if err := sdb.Close(); err != nil {
+				logrus.Errorf("database %s: %s", sdb.Name(), err)
+			}
+		}
+	}
+}
+
+// reload reads the config file at path again and applies what can change
+// without dropping connections: users and their grants, logging, and new
+// databases. Other changes are logged and wait for a restart. If the file is
+// invalid, cfg stays in effect. reload returns the config now in effect.
+func reload(cfg *config, path string, accounts *server.Accounts, e *sqle.Engine) *config {
+	next, err := loadConfig(path)
+	if err != nil {
+		logrus.Errorf("config not reloaded: %s", err)
+		return cfg
+	}
+	if err := next.Logging.apply(); err != nil {
+		logrus.Errorf("config not reloaded: %s", err)
+		return cfg
+	}
+	accounts.SetConfigured(next.accounts())
+
+	if changed := cfg.restartSettings(next); len(changed) > 0 {
+		logrus.Warnf("config changes to %s take effect on restart", strings.Join(changed, ", "))
+	}
+	reloaded := *next
+	reloaded.Listeners = cfg.Listeners
+	reloaded.TLS = cfg.TLS
+	reloaded.MaxConnections = cfg.MaxConnections
+	reloaded.Timeouts = cfg.Timeouts
+	reloaded.SecureFilePriv = cfg.SecureFilePriv
+	reloaded.UsersDatabase = cfg.usersDatabase()
+	reloaded.Databases = map[string]databaseConfig{}
+	for name, db := range cfg.Databases {
+		reloaded.Databases[name] = db
+	}
+	for _, name := range sortedKeys(next.Databases) {
+		if _, ok := cfg.Databases[name]; ok {
+			continue
+		}
+		db, err := sqlite.NewDatabaseWithOptions(name, next.Databases[name].DSN, next.Databases[name].Options)
+		if err != nil {
+			logrus.Errorf("database %s not added: %s", name, err)
+			continue
+		}
+		e.AddDatabase(db)
+		reloaded.Databases[name] = next.Databases[name]
+	}
+
+	logrus.Infof("reloaded config %s", path)
+	return &reloaded
+}
+
+// newEngine returns an engine that analyzes queries with mysqlite's rules as
+// well as go-mysql-server's.
+func newEngine() *sqle.Engine {
+	catalog := sql.NewCatalog()
+	a := analyzer.NewBuilder(catalog).
+		AddPostAnalyzeRule("exact_decimals", sqlite.ExactDecimals).
+		AddPostAnalyzeRule("validate_writes", sqlite.ValidateWrites).
+		AddPostAnalyzeRule("count_characters", sqlite.CountCharacters).
+		Build()
+	return sqle.New(catalog, a, nil)
+}
+
+// databaseFiles returns the database file at path as the default database. If
+// path is a directory, each .db file in it is a database named after the
+// file, and default.db is created if there are none.
+func databaseFiles(path string) (map[string]string, error) {
+	fi, err := os.Stat(path)
+	if err != nil || !fi.IsDir() {
+		return map[string]string{"default": path}, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(path, "*.db"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		files = []string{filepath.Join(path, "default.db")}
+	}
+	dbs := map[string]string{}
+	for _, file := range files {
+		dbs[strings.TrimSuffix(filepath.Base(file), ".db")] = file
+	}
+	return dbs, nil
 }
 
 func createInMemoryDatabase() *memory.Database {
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kevin-cantwell/mysqlite/internal/sqlite"
+)
+
+func TestServeClosesDatabasesOnError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mysqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The server can't listen on an address already in use
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	opts := sqlite.DefaultOptions()
+	opts.JournalMode = "WAL"
+	file := filepath.Join(dir, "default.db")
+	cfg := &config{
+		Listeners:      listenersConfig{TCP: l.Addr().String()},
+		MaxConnections: defaultMaxConnections,
+		Databases:      map[string]databaseConfig{"default": {DSN: file, Options: opts}},
+		Users:          map[string]userConfig{"root": {Password: "secret"}},
+	}
+	if err := serve(cfg, ""); err == nil {
+		t.Fatal("served on an address in use")
+	}
+	// Closing the database checkpoints and removes its write-ahead log
+	if _, err := os.Stat(file + "-wal"); !os.IsNotExist(err) {
+		t.Errorf("database left open: %s-wal exists", file)
+	}
+}
@@ -31,6 +31,7 @@ func main() {
 	var (
 		query   string
 		dataDir string
+		opts    = sqlite.DefaultOptions()
 	)
 	{
 		flag.StringVar(&query, "query", "", "SQL Query.")
@@ -41,13 +42,14 @@ func main() {
 		}
 		flag.StringVar(&dataDir, "data-dir", dataDirDefault, "Data directory.")
 		flag.StringVar(&dataDir, "d", dataDirDefault, "Data directory.")
+		opts.AddFlags(flag.CommandLine)
 		flag.Parse()
 	}
 
 	ctx := sql.NewContext(context.Background())
 
 	engine := sqle.NewDefault()
-	db := createStreamDatabase(dataDir)
+	db := createStreamDatabase(dataDir, opts)
 	engine.AddDatabase(db)
 
 	enc := json.NewEncoder(os.Stdout)
@@ -142,9 +144,9 @@ func main() {
 	}
 }
 
-func createStreamDatabase(dataDir string) *sqlite.Database {
+func createStreamDatabase(dataDir string, opts sqlite.Options) *sqlite.Database {
 	dsn := filepath.Join(dataDir, "stream.db")
-	db, err := sqlite.NewDatabase("", dsn)
+	db, err := sqlite.NewDatabaseWithOptions("", dsn, opts)
 	if err != nil {
 		panic(err)
 	}
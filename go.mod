@@ -5,8 +5,12 @@ go 1.14
 require (
 	github.com/liquidata-inc/go-mysql-server v0.6.0
 	github.com/liquidata-inc/vitess v0.0.0-20200807222445-2db8e9fb6365
-	github.com/mattn/go-sqlite3 v1.14.4
+	github.com/mattn/go-sqlite3 v1.14.15
+	github.com/opentracing/opentracing-go v1.1.0
 	github.com/pkg/errors v0.8.1
+	github.com/shopspring/decimal v0.0.0-20191130220710-360f2bc03045
+	github.com/sirupsen/logrus v1.4.2
+	gopkg.in/yaml.v2 v2.2.4
 )
 
 replace vitess.io/vitess => github.com/liquidata-inc/vitess v0.0.0-20200430040751-192bb76ecd8b
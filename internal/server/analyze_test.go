@@ -0,0 +1,57 @@
+package server
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/kevin-cantwell/mysqlite/internal/sqlite"
+	"github.com/liquidata-inc/vitess/go/sqltypes"
+)
+
+func TestTableStatus(t *testing.T) {
+	e, _ := testEngine(t)
+	e.AddDatabase(sqlite.NewInformationSchemaDatabase(e.Catalog, nil))
+	_, addr := startServer(t, Config{}, e)
+	c := connect(t, addr, "root", "")
+	exec(t, c, "CREATE TABLE events (id INT, msg TEXT)")
+	exec(t, c, "CREATE TABLE empty (id INT)")
+	exec(t, c, "INSERT INTO events (id, msg) VALUES (1, 'one'), (2, 'two'), (3, 'three')")
+
+	status := func() map[string]sqltypes.Value {
+		t.Helper()
+		r := exec(t, c, "SHOW TABLE STATUS LIKE 'events'")
+		if len(r.Rows) != 1 {
+			t.Fatalf("SHOW TABLE STATUS LIKE 'events' returned %d rows", len(r.Rows))
+		}
+		values := map[string]sqltypes.Value{}
+		for i, f := range r.Fields {
+			values[f.Name] = r.Rows[0][i]
+		}
+		return values
+	}
+	check := func(when string) {
+		t.Helper()
+		s := status()
+		if rows := s["Rows"].ToString(); rows != "3" {
+			t.Errorf("%s, Rows is %s, want 3", when, rows)
+		}
+		if n, _ := strconv.Atoi(s["Data_length"].ToString()); n <= 0 {
+			t.Errorf("%s, Data_length is %s", when, s["Data_length"].ToString())
+		}
+		if n, _ := strconv.Atoi(s["Avg_row_length"].ToString()); n <= 0 {
+			t.Errorf("%s, Avg_row_length is %s", when, s["Avg_row_length"].ToString())
+		}
+	}
+	check("before ANALYZE")
+
+	r := exec(t, c, "ANALYZE TABLE events, missing")
+	if len(r.Rows) != 2 || r.Rows[0][3].ToString() != "OK" || r.Rows[1][2].ToString() != "Error" {
+		t.Errorf("ANALYZE TABLE events, missing = %v", r.Rows)
+	}
+	check("after ANALYZE")
+
+	r = exec(t, c, "SELECT table_rows FROM information_schema.tables WHERE table_schema = 'mydb' AND table_name = 'empty'")
+	if got := column(r); len(got) != 1 || got[0] != "0" {
+		t.Errorf("empty table has table_rows %v, want 0", got)
+	}
+}
@@ -0,0 +1,510 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/kevin-cantwell/mysqlite/internal/sqlite"
+	"github.com/liquidata-inc/go-mysql-server/auth"
+	"github.com/liquidata-inc/go-mysql-server/sql"
+	"github.com/liquidata-inc/vitess/go/mysql"
+)
+
+// Privilege is a set of MySQL privileges.
+type Privilege uint32
+
+const (
+	SelectPriv Privilege = 1 << iota
+	InsertPriv
+	UpdatePriv
+	DeletePriv
+	CreatePriv
+	DropPriv
+	FilePriv
+	IndexPriv
+	AlterPriv
+	CreateUserPriv
+	GrantOptionPriv
+
+	// AllPrivileges are the privileges GRANT ALL gives on every database.
+	// Like MySQL's, they don't include GRANT OPTION.
+	AllPrivileges = GrantOptionPriv - 1
+	// tablePrivileges are the privileges that can be granted on a database or
+	// a table, rather than only on every database.
+	tablePrivileges = AllPrivileges&^(FilePriv|CreateUserPriv) | GrantOptionPriv
+)
+
+// privilegeNames are the privileges' names, in the order SHOW GRANTS lists them.
+var privilegeNames = []struct {
+	priv Privilege
+	name string
+}{
+	{SelectPriv, "SELECT"},
+	{InsertPriv, "INSERT"},
+	{UpdatePriv, "UPDATE"},
+	{DeletePriv, "DELETE"},
+	{CreatePriv, "CREATE"},
+	{DropPriv, "DROP"},
+	{FilePriv, "FILE"},
+	{IndexPriv, "INDEX"},
+	{AlterPriv, "ALTER"},
+	{CreateUserPriv, "CREATE USER"},
+	{GrantOptionPriv, "GRANT OPTION"},
+}
+
+// ParsePrivilege returns the privilege with the given name, in any case.
+func ParsePrivilege(name string) (Privilege, bool) {
+	name = strings.Join(strings.Fields(strings.ToUpper(name)), " ")
+	for _, p := range privilegeNames {
+		if p.name == name {
+			return p.priv, true
+		}
+	}
+	return 0, false
+}
+
+// Names returns the names of the privileges in p.
+func (p Privilege) Names() []string {
+	var names []string
+	for _, n := range privilegeNames {
+		if p&n.priv != 0 {
+			names = append(names, n.name)
+		}
+	}
+	return names
+}
+
+func (p Privilege) String() string {
+	return strings.Join(p.Names(), ", ")
+}
+
+// Scope is what privileges are granted on: every database if Database is
+// empty, every table of Database if Table is empty, or a table. Names are
+// lower case, since mysqlite's are case insensitive.
+type Scope struct {
+	Database string
+	Table    string
+}
+
+// String returns the scope as GRANT writes it, e.g. `db`.*
+func (s Scope) String() string {
+	switch {
+	case s.Database == "":
+		return "*.*"
+	case s.Table == "":
+		return quoteIdentifier(s.Database) + ".*"
+	default:
+		return quoteIdentifier(s.Database) + "." + quoteIdentifier(s.Table)
+	}
+}
+
+// Account is a user clients can log in as.
+type Account struct {
+	// PasswordHash is the account's mysql_native_password hash, as returned by
+	// auth.NativePassword, or empty if it has no password.
+	PasswordHash string
+	// Privileges are the privileges granted on each scope.
+	Privileges map[Scope]Privilege
+}
+
+// granted returns the privileges the account has on the table of db, or on db
+// itself if table is empty, or on every database if db is empty too.
+func (acc Account) granted(db, table string) Privilege {
+	db, table = strings.ToLower(db), strings.ToLower(table)
+	granted := acc.Privileges[Scope{}]
+	if db != "" {
+		granted |= acc.Privileges[Scope{Database: db}]
+	}
+	if table != "" {
+		granted |= acc.Privileges[Scope{Database: db, Table: table}]
+	}
+	return granted
+}
+
+// hasAny reports whether the account has any privilege on db or one of its
+// tables.
+func (acc Account) hasAny(db string) bool {
+	db = strings.ToLower(db)
+	for s, p := range acc.Privileges {
+		if p != 0 && (s.Database == "" || s.Database == db) {
+			return true
+		}
+	}
+	return false
+}
+
+// Accounts authenticates clients with mysql_native_password and authorizes
+// their statements with per-database and per-table privileges. There are two
+// kinds of accounts: configured ones, which the server's flags or config file
+// describe and which can be replaced while the server runs, and accounts
+// managed with CREATE USER and GRANT, which are stored in a database's
+// mysqlite_users table. Configured accounts take precedence. Changes affect
+// new logins and every session's next statement, without dropping
+// connections.
+type Accounts struct {
+	db *sqlite.Database
+
+	mu         sync.RWMutex
+	configured map[string]Account
+	managed    map[string]Account
+	static     *mysql.AuthServerStatic
+}
+
+// NewAccounts returns Accounts with the given configured accounts, managing
+// others in db.
+func NewAccounts(ctx context.Context, db *sqlite.Database, configured map[string]Account) (*Accounts, error) {
+	grants, err := db.Users(ctx)
+	if err != nil {
+		return nil, err
+	}
+	managed := map[string]Account{}
+	for _, g := range grants {
+		acc, ok := managed[g.User]
+		if !ok {
+			acc = Account{Privileges: map[Scope]Privilege{}}
+		}
+		if g.Database == "" {
+			acc.PasswordHash = g.PasswordHash
+		}
+		var priv Privilege
+		for _, name := range g.Privileges {
+			p, ok := ParsePrivilege(name)
+			if !ok {
+				return nil, fmt.Errorf("user %s: unknown privilege %q", g.User, name)
+			}
+			priv |= p
+		}
+		if priv != 0 {
+			acc.Privileges[Scope{Database: g.Database, Table: g.Table}] = priv
+		}
+		managed[g.User] = acc
+	}
+
+	a := &Accounts{db: db, managed: managed}
+	a.SetConfigured(configured)
+	return a, nil
+}
+
+// SetConfigured replaces the configured accounts.
+func (a *Accounts) SetConfigured(configured map[string]Account) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.configured = configured
+	a.update()
+}
+
+// update rebuilds the login entries after a change to the accounts.
+func (a *Accounts) update() {
+	static := mysql.NewAuthServerStatic()
+	for _, accounts := range []map[string]Account{a.managed, a.configured} {
+		for name, acc := range accounts {
+			static.Entries[name] = []*mysql.AuthServerStaticEntry{
+				{MysqlNativePassword: acc.PasswordHash},
+			}
+		}
+	}
+	a.static = static
+}
+
+// account returns the account of user, and whether it's a configured one.
+func (a *Accounts) account(user string) (acc Account, configured, ok bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if acc, ok := a.configured[user]; ok {
+		return acc, true, true
+	}
+	acc, ok = a.managed[user]
+	return acc, false, ok
+}
+
+// Mysql implements auth.Auth.
+func (a *Accounts) Mysql() mysql.AuthServer {
+	return accountsServer{a}
+}
+
+// Allowed implements auth.Auth. go-mysql-server only knows whether statements
+// read or write, so it only checks that the user still exists; the handler
+// checks each statement's privileges with Check.
+func (a *Accounts) Allowed(ctx *sql.Context, permission auth.Permission) error {
+	if _, _, ok := a.account(ctx.Client().User); !ok {
+		return auth.ErrNotAuthorized.Wrap(auth.ErrNoPermission.New(permission))
+	}
+	return nil
+}
+
+// Check returns an error like MySQL's unless the session's user has all of
+// priv on the table of db, or on db itself if table is empty, or on every
+// database if db is empty too.
+func (a *Accounts) Check(ctx *sql.Context, priv Privilege, db, table string) error {
+	user := ctx.Client().User
+	acc, _, _ := a.account(user)
+	missing := priv &^ acc.granted(db, table)
+	if missing == 0 {
+		return nil
+	}
+	switch {
+	case db == "":
+		return mysql.NewSQLError(mysql.ERSpecifiedAccessDenied, "42000",
+			"Access denied; you need (at least one of) the %s privilege(s) for this operation", missing.Names()[0])
+	case table == "":
+		return mysql.NewSQLError(mysql.ERDBAccessDenied, "42000",
+			"Access denied for user '%s'@'%%' to database '%s'", user, db)
+	default:
+		return mysql.NewSQLError(erTableAccessDenied, "42000",
+			"%s command denied to user '%s'@'%%' for table '%s'", missing.Names()[0], user, table)
+	}
+}
+
+// CheckAny is like Check, but for any privilege on db or one of its tables,
+// as USE requires.
+func (a *Accounts) CheckAny(ctx *sql.Context, db string) error {
+	user := ctx.Client().User
+	if acc, _, ok := a.account(user); ok && acc.hasAny(db) {
+		return nil
+	}
+	return mysql.NewSQLError(mysql.ERDBAccessDenied, "42000",
+		"Access denied for user '%s'@'%%' to database '%s'", user, db)
+}
+
+// Visible reports whether the session's user may see the table of db, or db
+// itself if table is empty, in INFORMATION_SCHEMA: whether they have any
+// privilege on it, as MySQL requires.
+func (a *Accounts) Visible(ctx *sql.Context, db, table string) bool {
+	acc, _, ok := a.account(ctx.Client().User)
+	if !ok {
+		return false
+	}
+	if table == "" {
+		return acc.hasAny(db)
+	}
+	return acc.granted(db, table) != 0
+}
+
+// Codes MySQL gives that vitess has no names for
+const (
+	erTableAccessDenied        = 1142
+	erCannotUser               = 1396
+	erCantCreateUserWithGrant  = 1410
+	erPasswordFormat           = 1827
+	erClientLocalFilesDisabled = 3948
+)
+
+// Grants returns the privileges user has, as SHOW GRANTS lists them.
+func (a *Accounts) Grants(user string) ([]string, error) {
+	acc, _, ok := a.account(user)
+	if !ok {
+		return nil, mysql.NewSQLError(mysql.ERNonExistingGrant, "42000",
+			"There is no such grant defined for user '%s' on host '%%'", user)
+	}
+	scopes := make([]Scope, 0, len(acc.Privileges))
+	for s := range acc.Privileges {
+		scopes = append(scopes, s)
+	}
+	sort.Slice(scopes, func(i, j int) bool {
+		if scopes[i].Database != scopes[j].Database {
+			return scopes[i].Database < scopes[j].Database
+		}
+		return scopes[i].Table < scopes[j].Table
+	})
+
+	grantee := quoteIdentifier(user) + "@`%`"
+	grants := []string{}
+	if acc.Privileges[Scope{}]&^GrantOptionPriv == 0 {
+		grants = append(grants, "GRANT USAGE ON *.* TO "+grantee)
+	}
+	for _, s := range scopes {
+		p := acc.Privileges[s]
+		var privileges string
+		switch {
+		case s.Database == "" && p&AllPrivileges == AllPrivileges,
+			s.Database != "" && p&tablePrivileges&^GrantOptionPriv == tablePrivileges&^GrantOptionPriv:
+			privileges = "ALL PRIVILEGES"
+		default:
+			privileges = (p &^ GrantOptionPriv).String()
+		}
+		if privileges == "" {
+			if s != (Scope{}) {
+				grants = append(grants, "GRANT USAGE ON "+s.String()+" TO "+grantee+" WITH GRANT OPTION")
+			}
+			continue
+		}
+		grant := "GRANT " + privileges + " ON " + s.String() + " TO " + grantee
+		if p&GrantOptionPriv != 0 {
+			grant += " WITH GRANT OPTION"
+		}
+		grants = append(grants, grant)
+	}
+	return grants, nil
+}
+
+// CreateUser adds a managed account, failing if it exists unless ifNotExists.
+func (a *Accounts) CreateUser(ctx context.Context, user, passwordHash string, ifNotExists bool) error {
+	return a.change(ctx, "CREATE USER", user, func(acc *Account, exists bool) (bool, error) {
+		if exists {
+			if ifNotExists {
+				return false, errSkip
+			}
+			return false, cannotUser("CREATE USER", user)
+		}
+		acc.PasswordHash = passwordHash
+		return true, nil
+	})
+}
+
+// DropUser removes a managed account, failing if there's none unless ifExists.
+func (a *Accounts) DropUser(ctx context.Context, user string, ifExists bool) error {
+	return a.change(ctx, "DROP USER", user, func(acc *Account, exists bool) (bool, error) {
+		if !exists {
+			if ifExists {
+				return false, errSkip
+			}
+			return false, cannotUser("DROP USER", user)
+		}
+		return false, nil
+	})
+}
+
+// SetPassword changes a managed account's password, failing if there's no
+// such account unless ifExists.
+func (a *Accounts) SetPassword(ctx context.Context, user, passwordHash string, ifExists bool) error {
+	return a.change(ctx, "ALTER USER", user, func(acc *Account, exists bool) (bool, error) {
+		if !exists {
+			if ifExists {
+				return false, errSkip
+			}
+			return false, cannotUser("ALTER USER", user)
+		}
+		acc.PasswordHash = passwordHash
+		return true, nil
+	})
+}
+
+// Grant gives a managed account priv on scope.
+func (a *Accounts) Grant(ctx context.Context, user string, scope Scope, priv Privilege) error {
+	return a.change(ctx, "GRANT", user, func(acc *Account, exists bool) (bool, error) {
+		if !exists {
+			return false, mysql.NewSQLError(erCantCreateUserWithGrant, "42000", "You are not allowed to create a user with GRANT")
+		}
+		acc.Privileges[scope] |= priv
+		return true, nil
+	})
+}
+
+// Revoke takes priv on scope away from a managed account, or every privilege
+// on any scope if everywhere is set.
+func (a *Accounts) Revoke(ctx context.Context, user string, scope Scope, priv Privilege, everywhere bool) error {
+	return a.change(ctx, "REVOKE", user, func(acc *Account, exists bool) (bool, error) {
+		if !exists {
+			return false, mysql.NewSQLError(mysql.ERNonExistingGrant, "42000",
+				"There is no such grant defined for user '%s' on host '%%'", user)
+		}
+		if everywhere {
+			acc.Privileges = map[Scope]Privilege{}
+			return true, nil
+		}
+		if _, ok := acc.Privileges[scope]; !ok {
+			if scope.Table != "" {
+				return false, mysql.NewSQLError(mysql.ERNonExistingTableGrant, "42000",
+					"There is no such grant defined for user '%s' on host '%%' on table '%s'", user, scope.Table)
+			}
+			return false, mysql.NewSQLError(mysql.ERNonExistingGrant, "42000",
+				"There is no such grant defined for user '%s' on host '%%'", user)
+		}
+		acc.Privileges[scope] &^= priv
+		if acc.Privileges[scope] == 0 {
+			delete(acc.Privileges, scope)
+		}
+		return true, nil
+	})
+}
+
+// errSkip is returned by change's callbacks to leave the account as it is, as
+// IF EXISTS and IF NOT EXISTS do.
+var errSkip = errors.New("skip")
+
+func cannotUser(op, user string) error {
+	return mysql.NewSQLError(erCannotUser, "HY000", "Operation %s failed for '%s'@'%%'", op, user)
+}
+
+// change applies f to a copy of user's managed account, and stores the result:
+// the account if f returns true, or no account if it returns false. Configured
+// accounts can't be changed.
+func (a *Accounts) change(ctx context.Context, op, user string, f func(acc *Account, exists bool) (bool, error)) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, ok := a.configured[user]; ok {
+		return mysql.NewSQLError(erCannotUser, "HY000",
+			"Operation %s failed for '%s'@'%%': the account is configured rather than managed with SQL", op, user)
+	}
+	current, exists := a.managed[user]
+	acc := Account{PasswordHash: current.PasswordHash, Privileges: map[Scope]Privilege{}}
+	for s, p := range current.Privileges {
+		acc.Privileges[s] = p
+	}
+
+	keep, err := f(&acc, exists)
+	if err == errSkip {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var grants []sqlite.UserGrant
+	if keep {
+		grants = append(grants, sqlite.UserGrant{User: user, PasswordHash: acc.PasswordHash, Privileges: acc.Privileges[Scope{}].Names()})
+		for s, p := range acc.Privileges {
+			if s != (Scope{}) {
+				grants = append(grants, sqlite.UserGrant{User: user, Database: s.Database, Table: s.Table, Privileges: p.Names()})
+			}
+		}
+	}
+	if err := a.db.SetUser(ctx, user, grants); err != nil {
+		return err
+	}
+
+	if keep {
+		a.managed[user] = acc
+	} else {
+		delete(a.managed, user)
+	}
+	a.update()
+	return nil
+}
+
+func (a *Accounts) server() *mysql.AuthServerStatic {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.static
+}
+
+// accountsServer checks logins against the current accounts.
+type accountsServer struct {
+	a *Accounts
+}
+
+func (s accountsServer) AuthMethod(user string) (string, error) {
+	return s.a.server().AuthMethod(user)
+}
+
+func (s accountsServer) Salt() ([]byte, error) {
+	return s.a.server().Salt()
+}
+
+func (s accountsServer) ValidateHash(salt []byte, user string, authResponse []byte, remoteAddr net.Addr) (mysql.Getter, error) {
+	return s.a.server().ValidateHash(salt, user, authResponse, remoteAddr)
+}
+
+func (s accountsServer) Negotiate(c *mysql.Conn, user string, remoteAddr net.Addr) (mysql.Getter, error) {
+	return s.a.server().Negotiate(c, user, remoteAddr)
+}
+
+func quoteIdentifier(name string) string {
+	return "`" + strings.Replace(name, "`", "``", -1) + "`"
+}
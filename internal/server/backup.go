@@ -0,0 +1,70 @@
+package server
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/kevin-cantwell/mysqlite/internal/sqlite"
+	"github.com/liquidata-inc/go-mysql-server/auth"
+	"github.com/liquidata-inc/go-mysql-server/sql"
+	"github.com/liquidata-inc/vitess/go/sqltypes"
+	"github.com/sirupsen/logrus"
+)
+
+// BACKUP DATABASE [db_name] TO 'file_name'
+var backupRegex = regexp.MustCompile(`(?is)^backup\s+database\s+(?:(\S+)\s+)?to\s+'((?:[^'\\]|\\.|'')*)'$`)
+
+// backupNeeds returns SELECT on the whole database, which a backup reads, and
+// FILE, since it writes a file on the server.
+func backupNeeds(ctx *sql.Context, match []string) []need {
+	db := ctx.GetCurrentDatabase()
+	if match[1] != "" {
+		db = unquote(match[1])
+	}
+	return []need{{priv: SelectPriv, db: db}, {priv: FilePriv}}
+}
+
+// backup copies a live database to a new SQLite file with the online backup
+// API, logging its progress. Like other queries it can be stopped with KILL.
+func (h *Handler) backup(ctx *sql.Context, match []string) (*sqltypes.Result, error) {
+	if err := h.e.Auth.Allowed(ctx, auth.ReadPerm); err != nil {
+		return nil, err
+	}
+
+	name := ctx.GetCurrentDatabase()
+	if match[1] != "" {
+		name = unquote(match[1])
+	}
+	db, err := h.e.Catalog.Database(name)
+	if err != nil {
+		return nil, err
+	}
+	sdb, ok := db.(*sqlite.Database)
+	if !ok {
+		return nil, fmt.Errorf("database does not support BACKUP: %s", db.Name())
+	}
+	// The copy includes mysqlite_users' password hashes, so it's confined like
+	// INTO OUTFILE
+	path, err := h.secureFile(unquoteString(match[2]))
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, err = h.e.Catalog.AddProcess(ctx, sql.QueryProcess, ctx.Query())
+	if err != nil {
+		return nil, err
+	}
+	defer h.e.Catalog.Done(ctx.Pid())
+
+	var pages int
+	err = sdb.Backup(ctx, path, func(p sqlite.BackupProgress) {
+		pages = p.Total
+		logrus.Infof("backup of %s to %s: %d of %d pages copied", db.Name(), path, p.Total-p.Remaining, p.Total)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return textResult([]string{"Database", "Path", "Pages"}, []string{db.Name(), path, strconv.Itoa(pages)}), nil
+}
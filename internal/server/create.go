@@ -0,0 +1,35 @@
+package server
+
+import (
+	"regexp"
+
+	"github.com/kevin-cantwell/mysqlite/internal/sqlite"
+	"github.com/liquidata-inc/go-mysql-server/sql"
+	"github.com/liquidata-inc/vitess/go/sqltypes"
+)
+
+// CREATE TABLE tbl_name (create_definition,...), where a column definition may
+// be generated: col_name data_type [GENERATED ALWAYS] AS (expr) [VIRTUAL|STORED]
+var createTableRegex = regexp.MustCompile(`(?is)^create\s+table\s.*$`)
+
+func createTableNeeds(ctx *sql.Context, match []string) []need {
+	return queryNeeds(ctx, sqlite.RewriteCreateTable(match[0]))
+}
+
+// createTable runs CREATE TABLE statements declaring generated columns, which
+// vitess can't parse, with the generation clauses removed. The context keeps
+// the original statement so the database can recover them.
+func (h *Handler) createTable(ctx *sql.Context, match []string) (*sqltypes.Result, error) {
+	query := sqlite.RewriteCreateTable(match[0])
+	if query == match[0] {
+		return nil, nil
+	}
+	_, iter, err := h.e.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := sql.RowIterToRows(iter); err != nil {
+		return nil, err
+	}
+	return &sqltypes.Result{}, nil
+}
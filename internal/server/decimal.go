@@ -0,0 +1,31 @@
+package server
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/kevin-cantwell/mysqlite/internal/sqlite"
+	"github.com/liquidata-inc/go-mysql-server/sql"
+)
+
+// Statements with a number written with a decimal point and more digits than a
+// double holds exactly. Integers, like rowtimes, are parsed exactly already.
+var decimalLiteralRegex = regexp.MustCompile(`(?is)^(?:select|insert|replace|update|delete)\s.*(?:` + decimalPattern(16) + `).*$`)
+
+// decimalPattern returns a pattern matching a decimal point with the given
+// number of digits around it.
+func decimalPattern(digits int) string {
+	alts := make([]string, digits+1)
+	for i := range alts {
+		alts[i] = fmt.Sprintf(`[0-9]{%d}\.[0-9]{%d}`, i, digits-i)
+	}
+	return strings.Join(alts, "|")
+}
+
+// exactDecimalLiterals rewrites decimal literals that go-mysql-server would
+// round to doubles, so that values like money amounts are stored as written.
+func (h *Handler) exactDecimalLiterals(ctx *sql.Context, match []string) (string, error) {
+	q, _ := sqlite.ExactDecimalLiterals(match[0])
+	return q, nil
+}
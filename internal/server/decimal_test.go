@@ -0,0 +1,25 @@
+package server
+
+import "testing"
+
+func TestDecimalLiteralRegex(t *testing.T) {
+	tests := []struct {
+		query string
+		match bool
+	}{
+		{"SELECT 12345678901234.56", true},
+		{"INSERT INTO t (amount) VALUES (0.1234567890123456)", true},
+		{"SELECT 1234567890123456.", true},
+		{"select\n.1234567890123456 + 1", true},
+		{"SELECT 1.5", false},
+		{"INSERT INTO t (rowtime, id) VALUES (1603101600000000000, 1)", false},
+		{"SELECT * FROM t WHERE rowtime > 1603101600000000000", false},
+		{"SELECT 1.5, 1603101600000000000", false},
+		{"SHOW TABLES LIKE '12345678901234.56'", false},
+	}
+	for _, tt := range tests {
+		if match := decimalLiteralRegex.MatchString(tt.query); match != tt.match {
+			t.Errorf("decimalLiteralRegex.MatchString(%q) = %v, want %v", tt.query, match, tt.match)
+		}
+	}
+}
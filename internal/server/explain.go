@@ -0,0 +1,91 @@
+package server
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/kevin-cantwell/mysqlite/internal/sqlite"
+	"github.com/liquidata-inc/go-mysql-server/sql"
+	"github.com/liquidata-inc/go-mysql-server/sql/parse"
+	"github.com/liquidata-inc/go-mysql-server/sql/plan"
+	"github.com/liquidata-inc/vitess/go/sqltypes"
+)
+
+// {EXPLAIN | DESCRIBE | DESC} [FORMAT = format_name] select_statement
+var explainRegex = regexp.MustCompile(`(?is)^(?:explain|describe|desc)\s+(?:format\s*=\s*\w+\s+)?((?:select|with)\s.*)$`)
+
+func explainNeeds(ctx *sql.Context, match []string) []need {
+	return queryNeeds(ctx, match[1])
+}
+
+// explain describes the plan of a query like go-mysql-server does, adding
+// beneath each mysqlite table the SQL its scan runs and SQLite's plan for it.
+func (h *Handler) explain(ctx *sql.Context, match []string) (*sqltypes.Result, error) {
+	parsed, err := parse.Parse(ctx, match[1])
+	if err != nil {
+		// leave it to the engine to report
+		return nil, nil
+	}
+
+	ctx, err = h.e.Catalog.AddProcess(ctx, sql.QueryProcess, ctx.Query())
+	if err != nil {
+		return nil, err
+	}
+	defer h.e.Catalog.Done(ctx.Pid())
+
+	analyzed, err := h.e.Analyzer.Analyze(ctx, parsed, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	tables := map[string]*sqlite.Table{} // by String()
+	plan.Inspect(analyzed, func(n sql.Node) bool {
+		rt, ok := n.(*plan.ResolvedTable)
+		if !ok {
+			return true
+		}
+		t := rt.Table
+		for {
+			w, ok := t.(sql.TableWrapper)
+			if !ok {
+				break
+			}
+			t = w.Underlying()
+		}
+		if st, ok := t.(*sqlite.Table); ok {
+			tables[st.String()] = st
+		}
+		return true
+	})
+
+	// Children of a node are indented by its prefix with the branches drawn to
+	// it replaced by the lines continuing past it
+	indent := strings.NewReplacer("├─ ", "│  ", "└─ ", "   ")
+
+	var rows [][]string
+	for _, line := range strings.Split(analyzed.String(), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		prefix := strings.TrimRight(line, " ")
+		t, ok := tables[strings.TrimLeft(prefix, " │├└─")]
+		if !ok {
+			rows = append(rows, []string{line})
+			continue
+		}
+		prefix = line[:strings.Index(line, t.String())]
+
+		explained, err := t.Explain(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for i, l := range strings.Split(strings.TrimRight(explained, "\n"), "\n") {
+			if i == 0 {
+				rows = append(rows, []string{prefix + l})
+			} else {
+				rows = append(rows, []string{indent.Replace(prefix) + l})
+			}
+		}
+	}
+	return textResult([]string{"plan"}, rows...), nil
+}
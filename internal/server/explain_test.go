@@ -0,0 +1,43 @@
+package server
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	e, _ := testEngine(t)
+	_, addr := startServer(t, Config{}, e)
+	c := connect(t, addr, "root", "")
+	exec(t, c, "CREATE TABLE events (id INT, msg TEXT)")
+
+	for _, query := range []string{
+		"EXPLAIN SELECT id FROM events WHERE id > 1",
+		"DESCRIBE SELECT id FROM events",
+		"explain format=tree select * from events",
+	} {
+		plan := strings.Join(column(exec(t, c, query)), "\n")
+		for _, want := range []string{"SQLite: SELECT", "SQLite query plan", "SCAN"} {
+			if !strings.Contains(plan, want) {
+				t.Errorf("%s gives\n%s\nwithout %q", query, plan, want)
+			}
+		}
+	}
+	want := []string{
+		"Project(events.id)",
+		" └─ Filter(events.id > 1)",
+		"     └─ Table(events)",
+		"         ├─ SQLite: SELECT * FROM \"events\"",
+		"         └─ SQLite query plan",
+		"             └─ SCAN events",
+	}
+	if got := column(exec(t, c, "EXPLAIN SELECT id FROM events WHERE id > 1")); !reflect.DeepEqual(got, want) {
+		t.Errorf("EXPLAIN gives\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// Other statements are still described by the engine
+	if r := exec(t, c, "DESCRIBE events"); len(r.Rows) != 3 {
+		t.Errorf("DESCRIBE events gives %d columns, want 3", len(r.Rows))
+	}
+}
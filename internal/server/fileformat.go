@@ -0,0 +1,344 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// fileFormat is how LOAD DATA and SELECT ... INTO OUTFILE lay rows out in a
+// file, as set by their FIELDS and LINES clauses.
+type fileFormat struct {
+	fieldsTerminated   string
+	enclosed           byte // 0 if fields aren't enclosed
+	optionallyEnclosed bool
+	escaped            byte // 0 if nothing is escaped
+	linesStarting      string
+	linesTerminated    string
+}
+
+// defaultFileFormat is MySQL's: tab separated fields, escaped with backslashes,
+// on newline terminated lines.
+func defaultFileFormat() fileFormat {
+	return fileFormat{
+		fieldsTerminated: "\t",
+		escaped:          '\\',
+		linesTerminated:  "\n",
+	}
+}
+
+// stringLiteral matches a single quoted string literal.
+const stringLiteral = `'((?:[^'\\]|\\.|'')*)'`
+
+var (
+	fieldsRegex     = regexp.MustCompile(`(?is)^\s*(?:fields|columns)\b`)
+	linesRegex      = regexp.MustCompile(`(?is)^\s*lines\b`)
+	terminatedRegex = regexp.MustCompile(`(?is)^\s*terminated\s+by\s+` + stringLiteral)
+	enclosedRegex   = regexp.MustCompile(`(?is)^\s*(optionally\s+)?enclosed\s+by\s+` + stringLiteral)
+	escapedRegex    = regexp.MustCompile(`(?is)^\s*escaped\s+by\s+` + stringLiteral)
+	startingRegex   = regexp.MustCompile(`(?is)^\s*starting\s+by\s+` + stringLiteral)
+)
+
+// errFieldTerminators is MySQL's error for field and line options it can't
+// read or write files with.
+var errFieldTerminators = errors.New("Field separator argument is not what is expected; check the manual")
+
+// parseFileFormat parses the FIELDS and LINES clauses at the start of s,
+// returning the format they describe and the rest of s.
+func parseFileFormat(s string) (fileFormat, string, error) {
+	f := defaultFileFormat()
+	for {
+		if m := fieldsRegex.FindStringIndex(s); m != nil {
+			s = s[m[1]:]
+			for {
+				if m := terminatedRegex.FindStringSubmatchIndex(s); m != nil {
+					f.fieldsTerminated = unquoteString(s[m[2]:m[3]])
+					s = s[m[1]:]
+				} else if m := enclosedRegex.FindStringSubmatchIndex(s); m != nil {
+					enclosed := unquoteString(s[m[4]:m[5]])
+					if len(enclosed) > 1 {
+						return f, s, errFieldTerminators
+					}
+					f.enclosed, f.optionallyEnclosed = firstByte(enclosed), m[2] >= 0
+					s = s[m[1]:]
+				} else if m := escapedRegex.FindStringSubmatchIndex(s); m != nil {
+					escaped := unquoteString(s[m[2]:m[3]])
+					if len(escaped) > 1 {
+						return f, s, errFieldTerminators
+					}
+					f.escaped = firstByte(escaped)
+					s = s[m[1]:]
+				} else {
+					break
+				}
+			}
+		} else if m := linesRegex.FindStringIndex(s); m != nil {
+			s = s[m[1]:]
+			for {
+				if m := startingRegex.FindStringSubmatchIndex(s); m != nil {
+					f.linesStarting = unquoteString(s[m[2]:m[3]])
+					s = s[m[1]:]
+				} else if m := terminatedRegex.FindStringSubmatchIndex(s); m != nil {
+					f.linesTerminated = unquoteString(s[m[2]:m[3]])
+					s = s[m[1]:]
+				} else {
+					break
+				}
+			}
+		} else {
+			break
+		}
+	}
+	// Fixed width rows aren't supported
+	if f.fieldsTerminated == "" || f.linesTerminated == "" {
+		return f, s, errFieldTerminators
+	}
+	return f, s, nil
+}
+
+func firstByte(s string) byte {
+	if s == "" {
+		return 0
+	}
+	return s[0]
+}
+
+// fileReader reads the rows of a file in a fileFormat.
+type fileReader struct {
+	r *bufio.Reader
+	f fileFormat
+}
+
+func newFileReader(r io.Reader, f fileFormat) *fileReader {
+	return &fileReader{r: bufio.NewReader(r), f: f}
+}
+
+// next returns the fields of the next row, each either a string or nil for
+// NULL, or io.EOF once there are no more rows.
+func (fr *fileReader) next() ([]interface{}, error) {
+	if fr.f.linesStarting != "" {
+		// Lines without the prefix are skipped, as is anything before it
+		if err := fr.skipPast(fr.f.linesStarting); err != nil {
+			return nil, err
+		}
+	} else if _, err := fr.r.Peek(1); err != nil {
+		return nil, err
+	}
+
+	var (
+		row   []interface{}
+		field bytes.Buffer
+		// enclosed is true while reading an enclosed field, quoted once its
+		// closing enclosure is reached
+		enclosed, quoted bool
+		null             bool
+	)
+	// write adds c to the field. \N followed by more is the text N, not NULL.
+	write := func(c byte) {
+		if null {
+			field.WriteByte('N')
+			null = false
+		}
+		field.WriteByte(c)
+	}
+	endField := func() {
+		switch {
+		case null:
+			row = append(row, nil)
+		case !quoted && fr.f.enclosed != 0 && field.String() == "NULL":
+			// Unenclosed NULL is NULL when fields are enclosed
+			row = append(row, nil)
+		default:
+			row = append(row, field.String())
+		}
+		field.Reset()
+		enclosed, quoted, null = false, false, false
+	}
+	for {
+		c, err := fr.r.ReadByte()
+		if err == io.EOF {
+			endField()
+			return row, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case fr.f.escaped != 0 && c == fr.f.escaped:
+			e, err := fr.r.ReadByte()
+			if err != nil {
+				write(c)
+				continue
+			}
+			if e == 'N' && field.Len() == 0 && !enclosed && !quoted && !null {
+				null = true
+				continue
+			}
+			write(unescapeByte(e))
+			continue
+		case enclosed:
+			if c != fr.f.enclosed {
+				field.WriteByte(c)
+				continue
+			}
+			if p, _ := fr.r.Peek(1); len(p) == 1 && p[0] == fr.f.enclosed {
+				// A doubled enclosure is one enclosure character
+				fr.r.ReadByte()
+				field.WriteByte(c)
+				continue
+			}
+			enclosed, quoted = false, true
+			continue
+		case fr.f.enclosed != 0 && c == fr.f.enclosed && field.Len() == 0 && !quoted && !null:
+			enclosed = true
+			continue
+		}
+
+		if fr.at(c, fr.f.linesTerminated) {
+			endField()
+			return row, nil
+		}
+		if fr.at(c, fr.f.fieldsTerminated) {
+			endField()
+			continue
+		}
+		write(c)
+	}
+}
+
+// at reports whether c, just read, begins the terminator s, reading the rest
+// of s if it does.
+func (fr *fileReader) at(c byte, s string) bool {
+	if c != s[0] {
+		return false
+	}
+	if len(s) > 1 {
+		p, _ := fr.r.Peek(len(s) - 1)
+		if string(p) != s[1:] {
+			return false
+		}
+		fr.r.Discard(len(s) - 1)
+	}
+	return true
+}
+
+// skipPast reads up to and including the next occurrence of s.
+func (fr *fileReader) skipPast(s string) error {
+	for {
+		c, err := fr.r.ReadByte()
+		if err != nil {
+			return err
+		}
+		if fr.at(c, s) {
+			return nil
+		}
+	}
+}
+
+// fileWriter writes rows to a file in a fileFormat, such that a fileReader
+// reads them back.
+type fileWriter struct {
+	w *bufio.Writer
+	f fileFormat
+}
+
+func newFileWriter(w io.Writer, f fileFormat) *fileWriter {
+	return &fileWriter{w: bufio.NewWriter(w), f: f}
+}
+
+// write writes a row of fields, each either nil for NULL or the text of a
+// value. quote says which fields are strings, to be enclosed when the format
+// only optionally encloses fields.
+func (fw *fileWriter) write(fields []interface{}, quote []bool) error {
+	fw.w.WriteString(fw.f.linesStarting)
+	for i, field := range fields {
+		if i > 0 {
+			fw.w.WriteString(fw.f.fieldsTerminated)
+		}
+		s, ok := field.(string)
+		if !ok {
+			if fw.f.escaped != 0 {
+				fw.w.WriteByte(fw.f.escaped)
+				fw.w.WriteByte('N')
+			} else {
+				fw.w.WriteString("NULL")
+			}
+			continue
+		}
+		enclose := fw.f.enclosed != 0 && (!fw.f.optionallyEnclosed || quote[i])
+		if enclose {
+			fw.w.WriteByte(fw.f.enclosed)
+		}
+		for j := 0; j < len(s); j++ {
+			c := s[j]
+			switch {
+			case fw.f.escaped == 0:
+				if enclose && c == fw.f.enclosed {
+					// Without an escape, a doubled enclosure is one enclosure
+					// character
+					fw.w.WriteByte(c)
+				}
+			case c == fw.f.escaped || (c == fw.f.enclosed && fw.f.enclosed != 0):
+				fw.w.WriteByte(fw.f.escaped)
+			case c == 0:
+				fw.w.WriteByte(fw.f.escaped)
+				c = '0'
+			case !enclose && (c == fw.f.fieldsTerminated[0] || c == fw.f.linesTerminated[0]):
+				fw.w.WriteByte(fw.f.escaped)
+			}
+			fw.w.WriteByte(c)
+		}
+		if enclose {
+			fw.w.WriteByte(fw.f.enclosed)
+		}
+	}
+	_, err := fw.w.WriteString(fw.f.linesTerminated)
+	return err
+}
+
+// flush writes any buffered rows to the underlying writer.
+func (fw *fileWriter) flush() error {
+	return fw.w.Flush()
+}
+
+// unescapeByte returns the character an escape sequence ending in c stands
+// for, as in MySQL string literals.
+func unescapeByte(c byte) byte {
+	switch c {
+	case '0':
+		return 0
+	case 'b':
+		return '\b'
+	case 'n':
+		return '\n'
+	case 'r':
+		return '\r'
+	case 't':
+		return '\t'
+	case 'Z':
+		return 0x1a
+	}
+	return c
+}
+
+// unquoteString returns the contents of a single quoted string literal,
+// decoding MySQL's backslash escapes and doubled quotes.
+func unquoteString(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s):
+			i++
+			c = unescapeByte(s[i])
+		case c == '\'' && i+1 < len(s):
+			i++
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
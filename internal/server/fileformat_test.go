@@ -0,0 +1,245 @@
+package server
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseFileFormat(t *testing.T) {
+	tests := []struct {
+		clauses string
+		want    fileFormat
+		rest    string
+		err     bool
+	}{
+		{
+			"",
+			defaultFileFormat(),
+			"",
+			false,
+		},
+		{
+			" IGNORE 1 LINES",
+			defaultFileFormat(),
+			" IGNORE 1 LINES",
+			false,
+		},
+		{
+			` FIELDS TERMINATED BY ',' OPTIONALLY ENCLOSED BY '"' LINES TERMINATED BY '\r\n' IGNORE 1 LINES`,
+			fileFormat{fieldsTerminated: ",", enclosed: '"', optionallyEnclosed: true, escaped: '\\', linesTerminated: "\r\n"},
+			" IGNORE 1 LINES",
+			false,
+		},
+		{
+			` columns enclosed by '''' escaped by '' terminated by '\t|'`,
+			fileFormat{fieldsTerminated: "\t|", enclosed: '\'', linesTerminated: "\n"},
+			"",
+			false,
+		},
+		{
+			` LINES STARTING BY 'row: ' TERMINATED BY ';' FIELDS TERMINATED BY ','`,
+			fileFormat{fieldsTerminated: ",", escaped: '\\', linesStarting: "row: ", linesTerminated: ";"},
+			"",
+			false,
+		},
+		{
+			` FIELDS ESCAPED BY '\\' ENCLOSED BY '"' (a, b)`,
+			fileFormat{fieldsTerminated: "\t", enclosed: '"', escaped: '\\', linesTerminated: "\n"},
+			" (a, b)",
+			false,
+		},
+		{` FIELDS ENCLOSED BY '""'`, fileFormat{}, "", true},
+		{` FIELDS ESCAPED BY '\\\\'`, fileFormat{}, "", true},
+		{` FIELDS TERMINATED BY ''`, fileFormat{}, "", true},
+		{` LINES TERMINATED BY ''`, fileFormat{}, "", true},
+	}
+	for _, tt := range tests {
+		f, rest, err := parseFileFormat(tt.clauses)
+		if tt.err {
+			if err == nil {
+				t.Errorf("parseFileFormat(%q) = %+v, want an error", tt.clauses, f)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseFileFormat(%q): %v", tt.clauses, err)
+			continue
+		}
+		if f != tt.want || rest != tt.rest {
+			t.Errorf("parseFileFormat(%q) = %+v, %q, want %+v, %q", tt.clauses, f, rest, tt.want, tt.rest)
+		}
+	}
+}
+
+func TestFileReader(t *testing.T) {
+	csv := fileFormat{fieldsTerminated: ",", enclosed: '"', optionallyEnclosed: true, escaped: '\\', linesTerminated: "\n"}
+	tests := []struct {
+		name string
+		f    fileFormat
+		file string
+		want [][]interface{}
+	}{
+		{
+			"empty",
+			defaultFileFormat(),
+			"",
+			nil,
+		},
+		{
+			"tab separated",
+			defaultFileFormat(),
+			"1\tone\n2\ttwo\n",
+			[][]interface{}{{"1", "one"}, {"2", "two"}},
+		},
+		{
+			"no final line terminator",
+			defaultFileFormat(),
+			"1\tone\n2\ttwo",
+			[][]interface{}{{"1", "one"}, {"2", "two"}},
+		},
+		{
+			"empty fields",
+			defaultFileFormat(),
+			"\t\n",
+			[][]interface{}{{"", ""}},
+		},
+		{
+			"escapes",
+			defaultFileFormat(),
+			"a\\tb\tc\\\nd\t\\0\\\\\n",
+			[][]interface{}{{"a\tb", "c\nd", "\x00\\"}},
+		},
+		{
+			"NULL",
+			defaultFileFormat(),
+			"\\N\tNULL\t\\Nx\t\\N\\N\tx\\N\n",
+			[][]interface{}{{nil, "NULL", "Nx", "NN", "xN"}},
+		},
+		{
+			"enclosed",
+			csv,
+			"1,\"a,b\",\"say \"\"hi\"\"\",\"\\\"q\\\"\"\n2,\"line\nbreak\",,\"\"\n",
+			[][]interface{}{{"1", "a,b", `say "hi"`, `"q"`}, {"2", "line\nbreak", "", ""}},
+		},
+		{
+			"enclosed NULL",
+			csv,
+			"NULL,\"NULL\",\\N\n",
+			[][]interface{}{{nil, "NULL", nil}},
+		},
+		{
+			"multibyte terminators",
+			fileFormat{fieldsTerminated: "||", linesTerminated: "\r\n"},
+			"a|b||c\r\nd||e|\r\n",
+			[][]interface{}{{"a|b", "c"}, {"d", "e|"}},
+		},
+		{
+			"lines starting",
+			fileFormat{fieldsTerminated: ",", escaped: '\\', linesStarting: "row: ", linesTerminated: "\n"},
+			"header\nrow: 1,a\nskipped\njunk row: 2,b\n",
+			[][]interface{}{{"1", "a"}, {"2", "b"}},
+		},
+		{
+			"no escapes",
+			fileFormat{fieldsTerminated: ",", linesTerminated: "\n"},
+			"a\\N,\\n\n",
+			[][]interface{}{{"a\\N", "\\n"}},
+		},
+	}
+	for _, tt := range tests {
+		fr := newFileReader(strings.NewReader(tt.file), tt.f)
+		var rows [][]interface{}
+		for {
+			row, err := fr.next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			rows = append(rows, row)
+		}
+		if !reflect.DeepEqual(rows, tt.want) {
+			t.Errorf("%s: read %q, want %q", tt.name, rows, tt.want)
+		}
+	}
+}
+
+func TestFileWriter(t *testing.T) {
+	csv := fileFormat{fieldsTerminated: ",", enclosed: '"', optionallyEnclosed: true, escaped: '\\', linesTerminated: "\n"}
+	rows := [][]interface{}{
+		{"1", "plain", nil},
+		{"2", "tab\there", "line\nbreak"},
+		{"3", `quote " and \ backslash`, "nul \x00"},
+		{"4", "comma, here", ""},
+		{"5", "NULL", "\\N"},
+	}
+	quote := []bool{false, true, true}
+	tests := []struct {
+		name string
+		f    fileFormat
+		want string
+	}{
+		{
+			"default",
+			defaultFileFormat(),
+			"1\tplain\t\\N\n" +
+				"2\ttab\\\there\tline\\\nbreak\n" +
+				"3\tquote \" and \\\\ backslash\tnul \\0\n" +
+				"4\tcomma, here\t\n" +
+				"5\tNULL\t\\\\N\n",
+		},
+		{
+			"optionally enclosed",
+			csv,
+			"1,\"plain\",\\N\n" +
+				"2,\"tab\there\",\"line\nbreak\"\n" +
+				"3,\"quote \\\" and \\\\ backslash\",\"nul \\0\"\n" +
+				"4,\"comma, here\",\"\"\n" +
+				"5,\"NULL\",\"\\\\N\"\n",
+		},
+		{
+			"enclosed without escapes",
+			fileFormat{fieldsTerminated: ",", enclosed: '"', linesStarting: "> ", linesTerminated: "\r\n"},
+			"> \"1\",\"plain\",NULL\r\n" +
+				"> \"2\",\"tab\there\",\"line\nbreak\"\r\n" +
+				"> \"3\",\"quote \"\" and \\ backslash\",\"nul \x00\"\r\n" +
+				"> \"4\",\"comma, here\",\"\"\r\n" +
+				"> \"5\",\"NULL\",\"\\N\"\r\n",
+		},
+	}
+	for _, tt := range tests {
+		var b strings.Builder
+		fw := newFileWriter(&b, tt.f)
+		for _, row := range rows {
+			if err := fw.write(row, quote); err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+		}
+		if err := fw.flush(); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if b.String() != tt.want {
+			t.Errorf("%s: wrote %q, want %q", tt.name, b.String(), tt.want)
+		}
+
+		// What's written reads back as it was
+		fr := newFileReader(strings.NewReader(b.String()), tt.f)
+		var read [][]interface{}
+		for {
+			row, err := fr.next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			read = append(read, row)
+		}
+		if !reflect.DeepEqual(read, rows) {
+			t.Errorf("%s: read back %q, want %q", tt.name, read, rows)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package server
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/liquidata-inc/go-mysql-server/sql"
+	"github.com/liquidata-inc/go-mysql-server/sql/parse"
+	"github.com/liquidata-inc/go-mysql-server/sql/plan"
+	"github.com/liquidata-inc/vitess/go/vt/sqlparser"
+)
+
+// need is a privilege a statement requires on a table, or on a whole
+// database if table is empty, or on every database if db is empty too. A zero
+// priv needs any privilege on the database, as USE does.
+type need struct {
+	priv  Privilege
+	db    string
+	table string
+}
+
+// authorize returns an error like MySQL's unless the session's user has the
+// privileges in needs.
+func (h *Handler) authorize(ctx *sql.Context, needs []need) error {
+	if h.accounts == nil {
+		return nil
+	}
+	for _, n := range needs {
+		if strings.EqualFold(n.db, "information_schema") {
+			continue
+		}
+		var err error
+		if n.priv == 0 {
+			err = h.accounts.CheckAny(ctx, n.db)
+		} else {
+			err = h.accounts.Check(ctx, n.priv, n.db, n.table)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LOCK TABLES tbl_name [[AS] alias] lock_type [, ...]
+var lockTablesRegex = regexp.MustCompile(`(?is)^\s*lock\s+tables?\s`)
+
+// queryNeeds returns the privileges a statement the engine runs needs. Those
+// it can't parse need none, since the engine won't run them either.
+func queryNeeds(ctx *sql.Context, query string) []need {
+	if lockTablesRegex.MatchString(query) {
+		return lockTablesNeeds(ctx, query)
+	}
+	stmt, err := sqlparser.Parse(query)
+	if err != nil {
+		return nil
+	}
+	return statementNeeds(ctx, stmt)
+}
+
+// lockTablesNeeds returns SELECT on each table LOCK TABLES locks, which vitess
+// can't parse, so it's parsed as the engine parses it. mysqlite has no LOCK
+// TABLES privilege, so a lock needs no more.
+func lockTablesNeeds(ctx *sql.Context, query string) []need {
+	node, err := parse.Parse(ctx, query)
+	if err != nil {
+		return nil
+	}
+	var needs []need
+	plan.Inspect(node, func(n sql.Node) bool {
+		if t, ok := n.(*plan.UnresolvedTable); ok {
+			db := t.Database
+			if db == "" {
+				db = ctx.GetCurrentDatabase()
+			}
+			needs = append(needs, need{priv: SelectPriv, db: db, table: t.Name()})
+		}
+		return true
+	})
+	return needs
+}
+
+// statementNeeds returns the privileges stmt needs, like MySQL's: SELECT on the
+// tables a statement reads, INSERT, UPDATE or DELETE on those it changes, and
+// CREATE, DROP, ALTER or INDEX on those whose definitions it changes.
+func statementNeeds(ctx *sql.Context, stmt sqlparser.Statement) []need {
+	db := ctx.GetCurrentDatabase()
+	table := func(priv Privilege, t sqlparser.TableName) need {
+		n := need{priv: priv, db: db, table: t.Name.String()}
+		if !t.Qualifier.IsEmpty() {
+			n.db = t.Qualifier.String()
+		}
+		return n
+	}
+	var needs []need
+	reads := func(nodes ...sqlparser.SQLNode) {
+		needs = append(needs, tablesNeed(SelectPriv, db, nodes...)...)
+	}
+
+	switch stmt := stmt.(type) {
+	case sqlparser.SelectStatement:
+		reads(stmt)
+	case *sqlparser.Insert:
+		priv := InsertPriv
+		if stmt.Action == sqlparser.ReplaceStr {
+			priv |= DeletePriv
+		}
+		if len(stmt.OnDup) > 0 {
+			priv |= UpdatePriv
+		}
+		needs = append(needs, table(priv, stmt.Table))
+		reads(stmt.Rows)
+	case *sqlparser.Update:
+		needs = append(needs, tablesNeed(UpdatePriv, db, stmt.TableExprs)...)
+		reads(stmt.Exprs, stmt.Where)
+	case *sqlparser.Delete:
+		if len(stmt.Targets) > 0 {
+			for _, t := range stmt.Targets {
+				needs = append(needs, table(DeletePriv, t))
+			}
+			reads(stmt.TableExprs)
+		} else {
+			needs = append(needs, tablesNeed(DeletePriv, db, stmt.TableExprs)...)
+		}
+		reads(stmt.Where)
+	case *sqlparser.DDL:
+		switch stmt.Action {
+		case sqlparser.CreateStr:
+			if !stmt.View.IsEmpty() {
+				needs = append(needs, table(CreatePriv, stmt.View))
+				reads(stmt.ViewExpr)
+			} else {
+				needs = append(needs, table(CreatePriv, stmt.Table))
+			}
+			if stmt.OptLike != nil {
+				needs = append(needs, table(SelectPriv, stmt.OptLike.LikeTable))
+			}
+		case sqlparser.DropStr:
+			for _, t := range append(stmt.FromTables, stmt.FromViews...) {
+				needs = append(needs, table(DropPriv, t))
+			}
+		case sqlparser.TruncateStr:
+			needs = append(needs, table(DropPriv, stmt.Table))
+		case sqlparser.RenameStr:
+			for _, t := range stmt.FromTables {
+				needs = append(needs, table(AlterPriv|DropPriv, t))
+			}
+			for _, t := range stmt.ToTables {
+				needs = append(needs, table(CreatePriv|InsertPriv, t))
+			}
+		case sqlparser.AlterStr:
+			priv := AlterPriv
+			if stmt.IndexSpec != nil {
+				priv = IndexPriv
+			}
+			needs = append(needs, table(priv, stmt.Table))
+		}
+	case *sqlparser.Show:
+		if stmt.HasOnTable() {
+			needs = append(needs, table(SelectPriv, stmt.OnTable))
+		}
+	case *sqlparser.Set:
+		reads(stmt.Exprs)
+	case *sqlparser.Explain:
+		return statementNeeds(ctx, stmt.Statement)
+	case *sqlparser.Use:
+		if name := stmt.DBName.String(); name != "" {
+			needs = append(needs, need{db: name})
+		}
+	}
+	return needs
+}
+
+// tablesNeed returns priv on each table named in nodes' FROM clauses and
+// subqueries.
+func tablesNeed(priv Privilege, db string, nodes ...sqlparser.SQLNode) []need {
+	var needs []need
+	_ = sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		t, ok := node.(*sqlparser.AliasedTableExpr)
+		if !ok {
+			return true, nil
+		}
+		name, ok := t.Expr.(sqlparser.TableName)
+		// vitess gives statements without a FROM clause one from dual
+		if !ok || name.Name.IsEmpty() || name.Qualifier.IsEmpty() && strings.EqualFold(name.Name.String(), "dual") {
+			return true, nil
+		}
+		n := need{priv: priv, db: db, table: name.Name.String()}
+		if !name.Qualifier.IsEmpty() {
+			n.db = name.Qualifier.String()
+		}
+		needs = append(needs, n)
+		return true, nil
+	}, nodes...)
+	return needs
+}
+
+// tableNeed returns priv on the possibly qualified and quoted table name.
+func tableNeed(ctx *sql.Context, priv Privilege, name string) need {
+	n := need{priv: priv, db: ctx.GetCurrentDatabase()}
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		n.db, name = unquote(name[:i]), name[i+1:]
+	}
+	n.table = unquote(name)
+	return n
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	sqleserver "github.com/liquidata-inc/go-mysql-server/server"
+	"github.com/liquidata-inc/go-mysql-server/sql"
+	"github.com/liquidata-inc/vitess/go/mysql"
+)
+
+func TestQueryNeeds(t *testing.T) {
+	ctx := sql.NewContext(context.Background(), sql.WithSession(sql.NewBaseSession()))
+	ctx.SetCurrentDatabase("mydb")
+
+	tests := []struct {
+		query string
+		needs []need
+	}{
+		{"SELECT 1", nil},
+		{"SELECT * FROM t JOIN other.u", []need{{SelectPriv, "mydb", "t"}, {SelectPriv, "other", "u"}}},
+		{"INSERT INTO t SELECT * FROM u", []need{{InsertPriv, "mydb", "t"}, {SelectPriv, "mydb", "u"}}},
+		{"REPLACE INTO t VALUES (1)", []need{{InsertPriv | DeletePriv, "mydb", "t"}}},
+		{"UPDATE t SET a = (SELECT max(b) FROM u)", []need{{UpdatePriv, "mydb", "t"}, {SelectPriv, "mydb", "u"}}},
+		{"DELETE FROM t WHERE a IN (SELECT b FROM u)", []need{{DeletePriv, "mydb", "t"}, {SelectPriv, "mydb", "u"}}},
+		{"DROP TABLE t, other.u", []need{{DropPriv, "mydb", "t"}, {DropPriv, "other", "u"}}},
+		{"SET @x = (SELECT secret FROM t)", []need{{SelectPriv, "mydb", "t"}}},
+		{"SET @x = 1, @y = (SELECT count(*) FROM other.u)", []need{{SelectPriv, "other", "u"}}},
+		{"SET SESSION sql_mode = (SELECT mode FROM t)", []need{{SelectPriv, "mydb", "t"}}},
+		{"SET @x = 1", nil},
+		{"LOCK TABLES t READ, u AS v WRITE", []need{{SelectPriv, "mydb", "t"}, {SelectPriv, "mydb", "u"}}},
+		{"EXPLAIN SELECT * FROM t", []need{{SelectPriv, "mydb", "t"}}},
+		{"USE other", []need{{db: "other"}}},
+		{"NOT SQL", nil},
+	}
+	for _, tt := range tests {
+		if needs := queryNeeds(ctx, tt.query); !reflect.DeepEqual(needs, tt.needs) {
+			t.Errorf("queryNeeds(%q) = %+v, want %+v", tt.query, needs, tt.needs)
+		}
+	}
+}
+
+func TestReadOnlyAccess(t *testing.T) {
+	e, db := testEngine(t)
+	accounts := testAccounts(t, e, db, map[string]Account{
+		"root":      {Privileges: map[Scope]Privilege{{}: AllPrivileges | GrantOptionPriv}},
+		"dashboard": {Privileges: map[Scope]Privilege{{Database: "mydb", Table: "public"}: SelectPriv}},
+	})
+	_, addr := startServer(t, Config{Config: sqleserver.Config{Auth: accounts}}, e)
+
+	root := connect(t, addr, "root", "")
+	exec(t, root, "CREATE TABLE public (id INT)")
+	exec(t, root, "CREATE TABLE secret (id INT)")
+	exec(t, root, "INSERT INTO secret (id) VALUES (42)")
+
+	c := connect(t, addr, "dashboard", "")
+	if got := column(exec(t, c, "SHOW TABLES")); !reflect.DeepEqual(got, []string{"public"}) {
+		t.Errorf("SHOW TABLES lists %v, want [public]", got)
+	}
+	if got := column(exec(t, root, "SHOW TABLES")); !reflect.DeepEqual(got, []string{"public", "secret"}) {
+		t.Errorf("SHOW TABLES lists %v for root, want [public secret]", got)
+	}
+	r := exec(t, c, "SHOW FULL TABLES FROM mydb LIKE 'p%'")
+	if len(r.Rows) != 1 || r.Fields[0].Name != "Tables_in_mydb" || r.Rows[0][1].ToString() != "BASE TABLE" {
+		t.Errorf("SHOW FULL TABLES = %v %v", r.Fields, r.Rows)
+	}
+
+	for _, query := range []string{
+		"SELECT * FROM secret",
+		"SET @x = (SELECT id FROM secret)",
+		"LOCK TABLES secret READ",
+		"INSERT INTO public (id) VALUES (1)",
+	} {
+		if _, err := c.ExecuteFetch(query, 10, false); sqlErrorNum(err) != erTableAccessDenied {
+			t.Errorf("%s: got %v, want access denied", query, err)
+		}
+	}
+	exec(t, c, "SELECT * FROM public")
+
+	// The client counts the rows of a result set itself, so check the result
+	// the server sends.
+	if len(column(exec(t, c, "SHOW GRANTS"))) == 0 {
+		t.Error("SHOW GRANTS returned no grants")
+	}
+	if r := textResult([]string{"Grants"}, []string{"GRANT USAGE ON *.* TO `dashboard`"}); r.RowsAffected != 0 {
+		t.Errorf("result set has RowsAffected %d, want 0", r.RowsAffected)
+	}
+	if _, err := c.ExecuteFetch("SHOW TABLES FROM other", 10, false); sqlErrorNum(err) != mysql.ERDBAccessDenied {
+		t.Errorf("SHOW TABLES FROM other: got %v, want access denied", err)
+	}
+}
@@ -0,0 +1,198 @@
+package server
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	sqle "github.com/liquidata-inc/go-mysql-server"
+	sqleserver "github.com/liquidata-inc/go-mysql-server/server"
+	"github.com/liquidata-inc/go-mysql-server/sql"
+	"github.com/liquidata-inc/vitess/go/mysql"
+	"github.com/liquidata-inc/vitess/go/sqltypes"
+)
+
+// Handler is a connection handler for a mysqlite engine. Queries matching one of
+// the registered commands are executed directly; all others fall through to the
+// embedded go-mysql-server Handler.
+type Handler struct {
+	*sqleserver.Handler
+	e  *sqle.Engine
+	sm *sqleserver.SessionManager
+
+	secureFilePriv string
+	// accounts authorizes statements, if the server authenticates with them
+	accounts *Accounts
+	// requireSecureTransport refuses logins over TCP without TLS
+	requireSecureTransport bool
+
+	mu       sync.Mutex
+	draining bool           // shutting down, so refusing queries
+	queries  sync.WaitGroup // running queries, to drain at shutdown
+}
+
+// NewHandler creates a new Handler given a SQLe engine.
+func NewHandler(e *sqle.Engine, sm *sqleserver.SessionManager, rt time.Duration) *Handler {
+	return &Handler{
+		Handler: sqleserver.NewHandler(e, sm, rt),
+		e:       e,
+		sm:      sm,
+	}
+}
+
+// erSecureTransportRequired is MySQL's error for insecure connections when
+// require_secure_transport is on, which vitess has no name for.
+const erSecureTransportRequired = 3159
+
+// command is a statement that go-mysql-server can't parse, or can't answer
+// correctly, but mysqlite can run. The regex is matched against the whole query
+// and its submatches are passed on to exec, if the user has the privileges
+// needs returns. Commands without needs check privileges themselves. A nil
+// result from exec hands the query on to the engine, which checks them again.
+type command struct {
+	regex *regexp.Regexp
+	needs func(ctx *sql.Context, match []string) []need
+	exec  func(h *Handler, ctx *sql.Context, match []string) (*sqltypes.Result, error)
+}
+
+var commands = []command{
+	{truncateRegex, truncateNeeds, (*Handler).truncate},
+	{createTemporaryRegex, createTemporaryNeeds, (*Handler).createTemporaryTable},
+	{dropTemporaryRegex, nil, (*Handler).dropTemporaryTable},
+	{createTableRegex, createTableNeeds, (*Handler).createTable},
+	{showCreateTableRegex, showCreateTableNeeds, (*Handler).showCreateTable},
+	{backupRegex, backupNeeds, (*Handler).backup},
+	{analyzeTableRegex, analyzeTableNeeds, (*Handler).analyzeTable},
+	{explainRegex, explainNeeds, (*Handler).explain},
+	{loadDataRegex, loadDataNeeds, (*Handler).loadData},
+	{selectIntoRegex, selectIntoNeeds, (*Handler).selectInto},
+	{createUserRegex, nil, (*Handler).createUser},
+	{dropUserRegex, nil, (*Handler).dropUser},
+	{alterUserRegex, nil, (*Handler).alterUser},
+	{grantRegex, nil, (*Handler).grant},
+	{revokeRegex, nil, (*Handler).revoke},
+	{showGrantsRegex, nil, (*Handler).showGrants},
+}
+
+// rewrite turns a statement using syntax go-mysql-server can't parse into an
+// equivalent one it can. An empty query from exec leaves the statement as is.
+type rewrite struct {
+	regex *regexp.Regexp
+	exec  func(h *Handler, ctx *sql.Context, match []string) (string, error)
+}
+
+var rewrites = []rewrite{
+	{matchAgainstRegex, (*Handler).matchAgainst},
+	{showTableStatusRegex, (*Handler).showTableStatus},
+	{showTablesRegex, (*Handler).showTables},
+	{decimalLiteralRegex, (*Handler).exactDecimalLiterals},
+}
+
+// ConnectionClosed reports that a connection has been closed.
+func (h *Handler) ConnectionClosed(c *mysql.Conn) {
+	h.Handler.ConnectionClosed(c)
+	h.endSession(c.ConnectionID)
+}
+
+// ComInitDB changes the session's database, if the user has any privilege on
+// it. It's also called once a client has logged in, so it refuses logins over
+// insecure transport when the server requires a secure one.
+func (h *Handler) ComInitDB(c *mysql.Conn, schemaName string) error {
+	if h.requireSecureTransport && c.Capabilities&mysql.CapabilityClientSSL == 0 && c.RemoteAddr().Network() != "unix" {
+		return mysql.NewSQLError(erSecureTransportRequired, mysql.SSUnknownSQLState,
+			"Connections using insecure transport are prohibited while --require_secure_transport=ON.")
+	}
+	if h.accounts != nil && schemaName != "" {
+		ctx, err := h.sm.NewContext(c)
+		if err != nil {
+			return err
+		}
+		if err := h.authorize(ctx, []need{{db: schemaName}}); err != nil {
+			return err
+		}
+	}
+	return h.Handler.ComInitDB(c, schemaName)
+}
+
+// ComQuery executes a SQL query, applying rewrites, intercepting mysqlite
+// commands and passing SELECTs through to SQLite where the session allows.
+func (h *Handler) ComQuery(
+	c *mysql.Conn,
+	query string,
+	callback func(*sqltypes.Result) error,
+) error {
+	if err := h.startQuery(); err != nil {
+		return err
+	}
+	defer h.queries.Done()
+
+	q := strings.TrimRight(strings.TrimSpace(query), ";")
+	for _, rw := range rewrites {
+		match := rw.regex.FindStringSubmatch(q)
+		if match == nil {
+			continue
+		}
+		ctx, err := h.sm.NewContextWithQuery(c, query)
+		if err != nil {
+			return err
+		}
+		rewritten, err := rw.exec(h, ctx, match)
+		if err != nil {
+			return err
+		}
+		if rewritten != "" {
+			query, q = rewritten, rewritten
+		}
+	}
+	for _, cmd := range commands {
+		match := cmd.regex.FindStringSubmatch(q)
+		if match == nil {
+			continue
+		}
+		ctx, err := h.sm.NewContextWithQuery(c, query)
+		if err != nil {
+			return err
+		}
+		if cmd.needs != nil {
+			if err := h.authorize(ctx, cmd.needs(ctx, match)); err != nil {
+				return err
+			}
+		}
+		r, err := cmd.exec(h, ctx, match)
+		if err != nil {
+			return err
+		}
+		if r == nil {
+			break
+		}
+		return callback(r)
+	}
+	if h.accounts != nil {
+		ctx, err := h.sm.NewContextWithQuery(c, query)
+		if err != nil {
+			return err
+		}
+		if err := h.authorize(ctx, queryNeeds(ctx, query)); err != nil {
+			return err
+		}
+	}
+	if ok, err := h.passthrough(c, query, callback); ok {
+		return err
+	}
+	return h.Handler.ComQuery(c, query, callback)
+}
+
+// table resolves a possibly qualified and backquoted table name against the
+// session's current database.
+func (h *Handler) table(ctx *sql.Context, name string) (sql.Table, error) {
+	db := ctx.GetCurrentDatabase()
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		db, name = unquote(name[:i]), name[i+1:]
+	}
+	return h.e.Catalog.Table(ctx, db, unquote(name))
+}
+
+func unquote(ident string) string {
+	return strings.Trim(ident, "`\"")
+}
@@ -0,0 +1,183 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/kevin-cantwell/mysqlite/internal/sqlite"
+	"github.com/liquidata-inc/go-mysql-server/auth"
+	"github.com/liquidata-inc/go-mysql-server/sql"
+	"github.com/liquidata-inc/vitess/go/mysql"
+	"github.com/liquidata-inc/vitess/go/sqltypes"
+)
+
+// LOAD DATA [LOCAL] INFILE 'file_name' [REPLACE | IGNORE] INTO TABLE tbl_name [CHARACTER SET charset_name]
+// [{FIELDS | COLUMNS} ...] [LINES ...] [IGNORE number {LINES | ROWS}] [(col_name, ...)]
+var loadDataRegex = regexp.MustCompile(`(?is)^load\s+data\s+(?:(?:low_priority|concurrent)\s+)?(local\s+)?infile\s+` +
+	stringLiteral + `\s+(?:(replace|ignore)\s+)?into\s+table\s+([^\s(]+)(.*)$`)
+
+var (
+	charsetRegex     = regexp.MustCompile(`(?is)^\s*character\s+set\s+\S+`)
+	ignoreLinesRegex = regexp.MustCompile(`(?is)^\s*ignore\s+(\d+)\s+(?:lines|rows)\b`)
+	loadColumnsRegex = regexp.MustCompile(`(?s)^\s*\(([^)]*)\)`)
+)
+
+// loadDataNeeds returns INSERT on the table, and DELETE too with REPLACE, and
+// FILE, since the file is read on the server.
+func loadDataNeeds(ctx *sql.Context, match []string) []need {
+	priv := InsertPriv
+	if strings.EqualFold(match[3], "replace") {
+		priv |= DeletePriv
+	}
+	return []need{tableNeed(ctx, priv, match[4]), {priv: FilePriv}}
+}
+
+// loadData bulk loads a delimited text file on the server into a table, in
+// one transaction. The file must be in the secure file directory.
+//
+// The server can't ask the client for a file, so LOCAL is refused rather than
+// reading a file of the same name on the server.
+func (h *Handler) loadData(ctx *sql.Context, match []string) (*sqltypes.Result, error) {
+	if err := h.e.Auth.Allowed(ctx, auth.WritePerm); err != nil {
+		return nil, err
+	}
+	if match[1] != "" {
+		return nil, mysql.NewSQLError(erClientLocalFilesDisabled, "42000",
+			"LOAD DATA LOCAL is not supported: the server can't read files from the client. Copy the file into the server's secure file directory and use LOAD DATA INFILE")
+	}
+	onDuplicate := strings.ToUpper(match[3])
+	path, err := h.secureReadFile(unquoteString(match[2]))
+	if err != nil {
+		return nil, err
+	}
+
+	rest := match[5]
+	if m := charsetRegex.FindStringIndex(rest); m != nil {
+		// Files are read as they are, which is UTF-8 for SQLite
+		rest = rest[m[1]:]
+	}
+	format, rest, err := parseFileFormat(rest)
+	if err != nil {
+		return nil, err
+	}
+	var ignore int
+	if m := ignoreLinesRegex.FindStringSubmatch(rest); m != nil {
+		if ignore, err = strconv.Atoi(m[1]); err != nil {
+			return nil, err
+		}
+		rest = rest[len(m[0]):]
+	}
+	var columns []string
+	if m := loadColumnsRegex.FindStringSubmatch(rest); m != nil {
+		for _, col := range strings.Split(m[1], ",") {
+			col = strings.TrimSpace(col)
+			if strings.HasPrefix(col, "@") {
+				return nil, fmt.Errorf("LOAD DATA doesn't support user variables: %s", col)
+			}
+			columns = append(columns, unquote(col))
+		}
+		rest = rest[len(m[0]):]
+	}
+	if strings.TrimSpace(rest) != "" {
+		return nil, fmt.Errorf("LOAD DATA doesn't support: %s", strings.TrimSpace(rest))
+	}
+
+	table, err := h.table(ctx, match[4])
+	if err != nil {
+		return nil, err
+	}
+	t, ok := table.(sqlite.BulkLoadableTable)
+	if !ok {
+		return nil, fmt.Errorf("table does not support LOAD DATA: %s", table.Name())
+	}
+
+	// The fields of each record, by the index of the column they're loaded into
+	schema := t.Schema()
+	var indexes []int
+	if columns == nil {
+		for i, col := range schema {
+			if col.Name == "rowtime" && i == 0 {
+				// Like INSERT without a column list, leave it for the table to fill in
+				continue
+			}
+			indexes = append(indexes, i)
+		}
+	} else {
+		for _, name := range columns {
+			i := schema.IndexOf(name, schema[0].Source)
+			if i < 0 {
+				return nil, sql.ErrTableColumnNotFound.New(table.Name(), name)
+			}
+			indexes = append(indexes, i)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ctx, err = h.e.Catalog.AddProcess(ctx, sql.QueryProcess, ctx.Query())
+	if err != nil {
+		return nil, err
+	}
+	defer h.e.Catalog.Done(ctx.Pid())
+	// The engine clears the warnings of the statements it runs
+	ctx.ClearWarnings()
+
+	r := newFileReader(f, format)
+	for i := 0; i < ignore; i++ {
+		if _, err := r.next(); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	inserted, skipped, err := t.BulkLoad(ctx, onDuplicate, func() (sql.Row, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		record, err := r.next()
+		if err != nil {
+			return nil, err
+		}
+
+		row := make(sql.Row, len(schema))
+		listed := make([]bool, len(schema))
+		for i, idx := range indexes {
+			listed[idx] = true
+			col := schema[idx]
+			if i >= len(record) {
+				// Missing fields are left at their default
+				row[idx] = col.Default
+				continue
+			}
+			v := record[i]
+			if s, ok := v.(string); ok && s == "" && !sql.IsText(col.Type) && !sql.IsBlob(col.Type) {
+				v = col.Type.Zero()
+			}
+			// The table checks the value against the column's type
+			row[idx] = v
+		}
+		for idx, col := range schema {
+			if !listed[idx] {
+				row[idx] = col.Default
+			}
+		}
+		return row, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &sqltypes.Result{
+		RowsAffected: uint64(inserted),
+		Info:         fmt.Sprintf("Records: %d  Deleted: 0  Skipped: %d  Warnings: %d", inserted+skipped, skipped, ctx.WarningCount()),
+	}, nil
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadData(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mysqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "rows.csv"), []byte("id,name\n1,\"one\"\n2,two\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	e, _ := testEngine(t)
+	_, addr := startServer(t, Config{SecureFilePriv: dir}, e)
+	c := connect(t, addr, "root", "")
+	exec(t, c, "CREATE TABLE t (id INT, name VARCHAR(10))")
+
+	file := filepath.Join(dir, "rows.csv")
+	r := exec(t, c, `LOAD DATA INFILE '`+file+`' INTO TABLE t FIELDS TERMINATED BY ',' OPTIONALLY ENCLOSED BY '"' IGNORE 1 LINES (id, name)`)
+	if r.RowsAffected != 2 {
+		t.Errorf("loaded %d rows, want 2", r.RowsAffected)
+	}
+	if got := column(exec(t, c, "SELECT name FROM t ORDER BY id")); !reflect.DeepEqual(got, []string{"one", "two"}) {
+		t.Errorf("loaded %q", got)
+	}
+
+	// LOCAL names a file on the client, which the server can't read, even if
+	// the server has a file by that name
+	_, err = c.ExecuteFetch(`LOAD DATA LOCAL INFILE '`+file+`' INTO TABLE t FIELDS TERMINATED BY ','`, 10, false)
+	if sqlErrorNum(err) != erClientLocalFilesDisabled {
+		t.Errorf("LOAD DATA LOCAL: got %v, want error %d", err, erClientLocalFilesDisabled)
+	}
+	if got := column(exec(t, c, "SELECT count(*) FROM t")); !reflect.DeepEqual(got, []string{"2"}) {
+		t.Errorf("after LOAD DATA LOCAL, table has %s rows", got)
+	}
+}
@@ -0,0 +1,17 @@
+package server
+
+import (
+	"regexp"
+
+	"github.com/kevin-cantwell/mysqlite/internal/sqlite"
+	"github.com/liquidata-inc/go-mysql-server/sql"
+)
+
+// ... MATCH (col1,col2,...) AGAINST (expr [search_modifier]) ...
+var matchAgainstRegex = regexp.MustCompile(`(?is)^.*\bmatch\s*\(.*\)\s*against\s*\(.*$`)
+
+// matchAgainst rewrites full-text searches, which go-mysql-server can't run, to
+// calls to the sqlite match function registered with the engine.
+func (h *Handler) matchAgainst(ctx *sql.Context, match []string) (string, error) {
+	return sqlite.RewriteMatchAgainst(match[0])
+}
@@ -0,0 +1,201 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/liquidata-inc/go-mysql-server/sql"
+	"github.com/liquidata-inc/vitess/go/sqltypes"
+)
+
+// SELECT ... INTO {OUTFILE 'file_name' [CHARACTER SET charset_name] [{FIELDS | COLUMNS} ...] [LINES ...] | DUMPFILE 'file_name'} [...]
+var selectIntoRegex = regexp.MustCompile(`(?is)^(select\s.*?)\s+into\s+(outfile|dumpfile)\s+` + stringLiteral + `(.*)$`)
+
+// errSecureFilePriv is returned when there's no directory to write files to.
+var errSecureFilePriv = errors.New("The server is running without --secure-file-priv so it cannot execute this statement")
+
+// selectIntoNeeds returns FILE, since the file is written on the server.
+// selectInto checks the SELECT once it has taken the INTO clause out.
+func selectIntoNeeds(ctx *sql.Context, match []string) []need {
+	return []need{{priv: FilePriv}}
+}
+
+// selectInto writes the rows of a SELECT to a new file in the secure file
+// directory, formatted with the OUTFILE options LOAD DATA reads them back
+// with, or as the raw bytes of a single row with DUMPFILE.
+func (h *Handler) selectInto(ctx *sql.Context, match []string) (*sqltypes.Result, error) {
+	dumpfile := strings.EqualFold(match[2], "dumpfile")
+	rest := match[4]
+	format := defaultFileFormat()
+	if !dumpfile {
+		if m := charsetRegex.FindStringIndex(rest); m != nil {
+			// Values are written as they are, which is UTF-8 for SQLite
+			rest = rest[m[1]:]
+		}
+		var err error
+		if format, rest, err = parseFileFormat(rest); err != nil {
+			return nil, err
+		}
+	}
+	// The INTO clause may come before the FROM clause
+	query := match[1] + " " + rest
+	if err := h.authorize(ctx, queryNeeds(ctx, query)); err != nil {
+		return nil, err
+	}
+
+	path, err := h.secureFile(unquoteString(match[3]))
+	if err != nil {
+		return nil, err
+	}
+
+	schema, iter, err := h.e.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if os.IsExist(err) {
+		return nil, fmt.Errorf("File '%s' already exists", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var n uint64
+	if dumpfile {
+		n, err = writeDumpfile(f, schema, iter)
+	} else {
+		n, err = writeOutfile(f, format, schema, iter)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		// Don't leave a partial file behind
+		os.Remove(path)
+		return nil, err
+	}
+	return &sqltypes.Result{RowsAffected: n}, nil
+}
+
+// secureFile resolves the path of a file to write against the secure file
+// directory, refusing paths outside it.
+func (h *Handler) secureFile(path string) (string, error) {
+	return h.securePath(path, false)
+}
+
+// secureReadFile is secureFile for a file to read, which may itself be a link
+// elsewhere.
+func (h *Handler) secureReadFile(path string) (string, error) {
+	return h.securePath(path, true)
+}
+
+func (h *Handler) securePath(path string, exists bool) (string, error) {
+	if h.secureFilePriv == "" {
+		return "", errSecureFilePriv
+	}
+	dir, err := filepath.EvalSymlinks(h.secureFilePriv)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	var resolved string
+	if exists {
+		if resolved, err = filepath.EvalSymlinks(path); err != nil {
+			return "", err
+		}
+	} else {
+		// The file doesn't exist yet, but its directory may be a link elsewhere
+		parent, err := filepath.EvalSymlinks(filepath.Dir(path))
+		if err != nil {
+			return "", err
+		}
+		resolved = filepath.Join(parent, filepath.Base(path))
+	}
+	if rel, err := filepath.Rel(dir, filepath.Dir(resolved)); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside the secure file directory %s", path, h.secureFilePriv)
+	}
+	return resolved, nil
+}
+
+func writeOutfile(w io.Writer, format fileFormat, schema sql.Schema, iter sql.RowIter) (uint64, error) {
+	quote := make([]bool, len(schema))
+	for i, col := range schema {
+		quote[i] = !sql.IsNumber(col.Type)
+	}
+	fw := newFileWriter(w, format)
+	fields := make([]interface{}, len(schema))
+	var n uint64
+	for {
+		row, err := iter.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return n, err
+		}
+		for i, v := range row {
+			if fields[i], err = fieldText(schema[i].Type, v); err != nil {
+				return n, err
+			}
+		}
+		if err := fw.write(fields, quote); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, fw.flush()
+}
+
+// writeDumpfile writes the values of a single row with no formatting at all,
+// as DUMPFILE does for BLOBs.
+func writeDumpfile(w io.Writer, schema sql.Schema, iter sql.RowIter) (uint64, error) {
+	row, err := iter.Next()
+	if err == io.EOF {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if _, err := iter.Next(); err != io.EOF {
+		if err == nil {
+			err = errors.New("Result consisted of more than one row")
+		}
+		return 0, err
+	}
+	for i, v := range row {
+		s, err := fieldText(schema[i].Type, v)
+		if err != nil {
+			return 0, err
+		}
+		if s, ok := s.(string); ok {
+			if _, err := io.WriteString(w, s); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return 1, nil
+}
+
+// fieldText returns the text of a value as a client would receive it, or nil
+// for NULL.
+func fieldText(t sql.Type, v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	sv, err := t.SQL(v)
+	if err != nil {
+		return nil, err
+	}
+	if sv.IsNull() {
+		return nil, nil
+	}
+	return sv.ToString(), nil
+}
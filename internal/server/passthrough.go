@@ -0,0 +1,156 @@
+package server
+
+import (
+	"io"
+	"strings"
+
+	"github.com/kevin-cantwell/mysqlite/internal/sqlite"
+	"github.com/liquidata-inc/go-mysql-server/auth"
+	"github.com/liquidata-inc/go-mysql-server/sql"
+	"github.com/liquidata-inc/go-mysql-server/sql/parse"
+	"github.com/liquidata-inc/vitess/go/mysql"
+	"github.com/liquidata-inc/vitess/go/sqltypes"
+	"github.com/liquidata-inc/vitess/go/vt/proto/query"
+	"github.com/liquidata-inc/vitess/go/vt/sqlparser"
+	"github.com/sirupsen/logrus"
+)
+
+// passthroughVariable is the session variable that turns passthrough on, with
+// SET mysqlite_passthrough = 1.
+const passthroughVariable = "mysqlite_passthrough"
+
+// passthroughBatch is how many rows are sent to the client at a time.
+const passthroughBatch = 100
+
+// passthrough runs a SELECT entirely in SQLite when the session has turned
+// passthrough on and every table it reads is a table of the same mysqlite
+// database, streaming the rows back. The engine still parses and analyzes the
+// query, so that it reports the same errors and result columns either way. It
+// reports false if the query is left to the engine, as it is when SQLite fails
+// to run it before any rows are sent.
+func (h *Handler) passthrough(c *mysql.Conn, q string, callback func(*sqltypes.Result) error) (bool, error) {
+	ctx, err := h.sm.NewContextWithQuery(c, q)
+	if err != nil {
+		return false, err
+	}
+	if !passthroughEnabled(ctx) {
+		return false, nil
+	}
+	stmt, err := sqlparser.Parse(q)
+	if err != nil {
+		return false, nil
+	}
+	sel, ok := stmt.(*sqlparser.Select)
+	if !ok {
+		return false, nil
+	}
+	db, err := h.e.Catalog.Database(ctx.GetCurrentDatabase())
+	if err != nil {
+		return false, nil
+	}
+	sdb, ok := db.(*sqlite.Database)
+	if !ok {
+		return false, nil
+	}
+	translated, ok := sdb.TranslateSelect(ctx, sel)
+	if !ok {
+		return false, nil
+	}
+
+	if err := h.e.Auth.Allowed(ctx, auth.ReadPerm); err != nil {
+		return true, err
+	}
+	parsed, err := parse.Parse(ctx, q)
+	if err != nil {
+		return false, nil
+	}
+
+	ctx, err = h.e.Catalog.AddProcess(ctx, sql.QueryProcess, q)
+	if err != nil {
+		return true, err
+	}
+	defer h.e.Catalog.Done(ctx.Pid())
+
+	analyzed, err := h.e.Analyzer.Analyze(ctx, parsed, nil)
+	if err != nil {
+		return true, err
+	}
+	schema := analyzed.Schema()
+	iter, err := sdb.Passthrough(ctx, translated, schema)
+	if err != nil {
+		return fallback(ctx, err)
+	}
+	defer iter.Close()
+
+	fields := schemaToFields(schema)
+	r := &sqltypes.Result{Fields: fields}
+	sent := false
+	for {
+		row, err := iter.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if sent {
+				return true, err
+			}
+			return fallback(ctx, err)
+		}
+		values := make([]sqltypes.Value, len(row))
+		for i, v := range row {
+			if values[i], err = schema[i].Type.SQL(v); err != nil {
+				return true, err
+			}
+		}
+		r.Rows = append(r.Rows, values)
+		r.RowsAffected++
+		if r.RowsAffected == passthroughBatch {
+			if err := callback(r); err != nil {
+				return true, err
+			}
+			sent = true
+			r = &sqltypes.Result{Fields: fields}
+		}
+	}
+	return true, callback(r)
+}
+
+// fallback hands a query SQLite failed to run to the engine, unless it failed
+// because the query was killed.
+func fallback(ctx *sql.Context, err error) (bool, error) {
+	if ctx.Err() != nil {
+		return true, err
+	}
+	logrus.Debugf("passthrough failed, running the query in the engine: %v", err)
+	return false, nil
+}
+
+// passthroughEnabled reports whether the session has turned passthrough on.
+func passthroughEnabled(ctx *sql.Context) bool {
+	_, v := ctx.Get(passthroughVariable)
+	switch v := v.(type) {
+	case nil:
+		return false
+	case string:
+		return strings.EqualFold(v, "on") || v == "1"
+	}
+	n, err := sql.Int64.Convert(v)
+	return err == nil && n.(int64) != 0
+}
+
+// schemaToFields describes result columns like go-mysql-server does.
+func schemaToFields(s sql.Schema) []*query.Field {
+	fields := make([]*query.Field, len(s))
+	for i, c := range s {
+		var charset uint32 = mysql.CharacterSetUtf8
+		if sql.IsBlob(c.Type) {
+			charset = mysql.CharacterSetBinary
+		}
+		fields[i] = &query.Field{
+			Name:    c.Name,
+			Type:    c.Type.Type(),
+			Charset: charset,
+		}
+	}
+	return fields
+}
@@ -0,0 +1,199 @@
+// Package server serves mysqlite databases over the MySQL wire protocol. It is a
+// thin layer over go-mysql-server's server that intercepts the statements mysqlite
+// executes natively against SQLite before handing everything else to the engine.
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"os"
+	"sync"
+
+	"github.com/kevin-cantwell/mysqlite/internal/sqlite"
+	sqle "github.com/liquidata-inc/go-mysql-server"
+	sqleserver "github.com/liquidata-inc/go-mysql-server/server"
+	"github.com/liquidata-inc/go-mysql-server/sql"
+	"github.com/liquidata-inc/vitess/go/mysql"
+	"github.com/opentracing/opentracing-go"
+)
+
+// Config for the mysql server.
+type Config struct {
+	sqleserver.Config
+	// Socket is the path of a Unix socket to accept connections on as well as
+	// Address. If Address is empty, the server only listens on the socket.
+	Socket string
+	// SecureFilePriv is the directory SELECT ... INTO OUTFILE writes files to
+	// and LOAD DATA reads them from, like MySQL's secure_file_priv. Files
+	// can't be written or read if it's empty.
+	SecureFilePriv string
+	// TLSConfig, if set, lets clients upgrade their connections to TLS.
+	TLSConfig *tls.Config
+	// RequireSecureTransport refuses connections that use neither TLS nor
+	// the Unix socket, like MySQL's require_secure_transport.
+	RequireSecureTransport bool
+}
+
+// Server is a MySQL server for mysqlite engines.
+type Server struct {
+	Listener *mysql.Listener
+	// Socket accepts connections on the configured Unix socket, if any.
+	Socket *mysql.Listener
+	h      *Handler
+}
+
+// NewServer creates a server with the given protocol, address and authentication
+// details, mirroring go-mysql-server's NewDefaultServer but using a Handler that
+// understands mysqlite-specific statements. Connections on the Unix socket, if
+// one is configured, are served by the same handler.
+func NewServer(cfg Config, e *sqle.Engine) (*Server, error) {
+	var tracer opentracing.Tracer
+	if cfg.Tracer != nil {
+		tracer = cfg.Tracer
+	} else {
+		tracer = opentracing.NoopTracer{}
+	}
+
+	if cfg.ConnReadTimeout < 0 {
+		cfg.ConnReadTimeout = 0
+	}
+
+	if cfg.ConnWriteTimeout < 0 {
+		cfg.ConnWriteTimeout = 0
+	}
+
+	if cfg.MaxConnections == 0 {
+		cfg.MaxConnections = 1
+	}
+
+	if cfg.Address == "" && cfg.Socket == "" {
+		return nil, errors.New("no address or socket to listen on")
+	}
+
+	addr := cfg.Address
+	if addr == "" {
+		addr = cfg.Socket
+	}
+	handler := NewHandler(e,
+		sqleserver.NewSessionManager(
+			sessionBuilder,
+			tracer,
+			e.Catalog.HasDB,
+			e.Catalog.MemoryManager,
+			addr),
+		cfg.ConnReadTimeout)
+	handler.secureFilePriv = cfg.SecureFilePriv
+	handler.accounts, _ = cfg.Auth.(*Accounts)
+	handler.requireSecureTransport = cfg.RequireSecureTransport
+
+	s := &Server{h: handler}
+	if cfg.Address != "" {
+		l, err := sqleserver.NewListener(cfg.Protocol, cfg.Address, handler.Handler)
+		if err != nil {
+			return nil, err
+		}
+		if s.Listener, err = newListener(cfg, l, handler); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Socket != "" {
+		removeStaleSocket(cfg.Socket)
+		l, err := sqleserver.NewListener("unix", cfg.Socket, handler.Handler)
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+		if s.Socket, err = newListener(cfg, l, handler); err != nil {
+			s.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// newListener returns a MySQL protocol listener accepting connections from l.
+func newListener(cfg Config, l net.Listener, handler *Handler) (*mysql.Listener, error) {
+	vtListnr, err := mysql.NewListenerWithConfig(mysql.ListenerConfig{
+		Listener:           l,
+		AuthServer:         cfg.Auth.Mysql(),
+		Handler:            handler,
+		ConnReadTimeout:    cfg.ConnReadTimeout,
+		ConnWriteTimeout:   cfg.ConnWriteTimeout,
+		MaxConns:           cfg.MaxConnections,
+		ConnReadBufferSize: mysql.DefaultConnBufferSize,
+	})
+	if err != nil {
+		l.Close()
+		return nil, err
+	}
+
+	if cfg.Version != "" {
+		vtListnr.ServerVersion = cfg.Version
+	}
+	vtListnr.TLSConfig = cfg.TLSConfig
+	return vtListnr, nil
+}
+
+// removeStaleSocket removes the socket file at path if no server is listening
+// on it, like one left behind by a server that didn't shut down cleanly.
+func removeStaleSocket(path string) {
+	fi, err := os.Stat(path)
+	if err != nil || fi.Mode()&os.ModeSocket == 0 {
+		return
+	}
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return
+	}
+	os.Remove(path)
+}
+
+// Start starts accepting connections on the server, returning once all its
+// listeners are closed.
+func (s *Server) Start() error {
+	var wg sync.WaitGroup
+	for _, l := range s.listeners() {
+		wg.Add(1)
+		go func(l *mysql.Listener) {
+			defer wg.Done()
+			l.Accept()
+		}(l)
+	}
+	wg.Wait()
+	return nil
+}
+
+// Close closes the server connection.
+func (s *Server) Close() error {
+	for _, l := range s.listeners() {
+		l.Close()
+	}
+	return nil
+}
+
+func (s *Server) listeners() []*mysql.Listener {
+	var ls []*mysql.Listener
+	if s.Listener != nil {
+		ls = append(ls, s.Listener)
+	}
+	if s.Socket != nil {
+		ls = append(ls, s.Socket)
+	}
+	return ls
+}
+
+// sessionBuilder builds sessions like go-mysql-server's default builder, but
+// starting with MySQL's default sql_mode, so that writes are strict unless a
+// client asks otherwise.
+func sessionBuilder(ctx context.Context, c *mysql.Conn, addr string) (sql.Session, *sql.IndexRegistry, *sql.ViewRegistry, error) {
+	s, ir, vr, err := sqleserver.DefaultSessionBuilder(ctx, c, addr)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err := s.Set(ctx, "sql_mode", sql.LongText, sqlite.DefaultSQLMode); err != nil {
+		return nil, nil, nil, err
+	}
+	return s, ir, vr, nil
+}
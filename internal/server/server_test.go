@@ -0,0 +1,135 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/kevin-cantwell/mysqlite/internal/sqlite"
+	sqle "github.com/liquidata-inc/go-mysql-server"
+	"github.com/liquidata-inc/go-mysql-server/auth"
+	"github.com/liquidata-inc/vitess/go/mysql"
+	"github.com/liquidata-inc/vitess/go/sqltypes"
+)
+
+// testEngine returns an engine with a new database named mydb in a temporary
+// directory.
+func testEngine(t *testing.T) (*sqle.Engine, *sqlite.Database) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "mysqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	db, err := sqlite.NewDatabase("mydb", filepath.Join(dir, "mydb.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	e := sqle.NewDefault()
+	e.AddDatabase(db)
+	return e, db
+}
+
+// testAccounts makes the engine authenticate and authorize with accounts, and
+// returns them.
+func testAccounts(t *testing.T, e *sqle.Engine, db *sqlite.Database, accounts map[string]Account) *Accounts {
+	t.Helper()
+	a, err := NewAccounts(context.Background(), db, accounts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.AddDatabase(sqlite.NewInformationSchemaDatabase(e.Catalog, a.Visible))
+	e.Auth = a
+	return a
+}
+
+// startServer starts a server for e on a free local port, authenticating with
+// cfg.Auth or letting anyone in, and returns it with its address.
+func startServer(t *testing.T, cfg Config, e *sqle.Engine) (*Server, string) {
+	t.Helper()
+	cfg.Protocol = "tcp"
+	cfg.Address = "127.0.0.1:0"
+	if cfg.Auth == nil {
+		cfg.Auth = new(auth.None)
+	}
+	if cfg.MaxConnections == 0 {
+		cfg.MaxConnections = 10
+	}
+	s, err := NewServer(cfg, e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		s.Start()
+		close(done)
+	}()
+	t.Cleanup(func() {
+		s.Close()
+		<-done
+	})
+	return s, s.Listener.Addr().String()
+}
+
+// connect opens a connection to the server at addr as user, using mydb.
+func connect(t *testing.T, addr, user, pass string) *mysql.Conn {
+	t.Helper()
+	c, err := dial(addr, user, pass)
+	if err != nil {
+		t.Fatalf("connecting as %s: %v", user, err)
+	}
+	t.Cleanup(c.Close)
+	return c
+}
+
+func dial(addr, user, pass string) (*mysql.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		return nil, err
+	}
+	return mysql.Connect(context.Background(), &mysql.ConnParams{
+		Host:   host,
+		Port:   p,
+		Uname:  user,
+		Pass:   pass,
+		DbName: "mydb",
+	})
+}
+
+// exec runs query on c, failing the test if it fails.
+func exec(t *testing.T, c *mysql.Conn, query string) *sqltypes.Result {
+	t.Helper()
+	r, err := c.ExecuteFetch(query, 1000, true)
+	if err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	return r
+}
+
+// sqlErrorNum returns the MySQL error number of err, or 0 if it has none.
+func sqlErrorNum(err error) int {
+	if serr, ok := err.(*mysql.SQLError); ok {
+		return serr.Number()
+	}
+	return 0
+}
+
+// column returns the text of a result's first column.
+func column(r *sqltypes.Result) []string {
+	var values []string
+	for _, row := range r.Rows {
+		values = append(values, row[0].ToString())
+	}
+	return values
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/liquidata-inc/go-mysql-server/sql"
+	"github.com/liquidata-inc/vitess/go/sqltypes"
+	"github.com/liquidata-inc/vitess/go/vt/proto/query"
+)
+
+// SHOW CREATE TABLE tbl_name
+var showCreateTableRegex = regexp.MustCompile(`(?is)^show\s+create\s+table\s+(\S+)$`)
+
+func showCreateTableNeeds(ctx *sql.Context, match []string) []need {
+	return []need{tableNeed(ctx, SelectPriv, match[1])}
+}
+
+// createTableStatementer is implemented by tables that can produce their own
+// CREATE TABLE statement, including the table options go-mysql-server discards.
+type createTableStatementer interface {
+	sql.Table
+	CreateTableStatement(ctx *sql.Context) (string, error)
+}
+
+func (h *Handler) showCreateTable(ctx *sql.Context, match []string) (*sqltypes.Result, error) {
+	table, err := h.table(ctx, match[1])
+	if err != nil {
+		return nil, err
+	}
+	t, ok := table.(createTableStatementer)
+	if !ok {
+		return nil, nil
+	}
+	stmt, err := t.CreateTableStatement(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return textResult([]string{"Table", "Create Table"}, []string{t.Name(), stmt}), nil
+}
+
+// SHOW TABLE STATUS [{FROM | IN} db_name] [LIKE 'pattern' | WHERE expr]
+var showTableStatusRegex = regexp.MustCompile(`(?is)^show\s+table\s+status(?:\s+(?:from|in)\s+(\S+))?(?:\s+(like|where)\s+(.*))?$`)
+
+// tableStatusColumns are the columns of SHOW TABLE STATUS and the columns of
+// INFORMATION_SCHEMA.TABLES they're read from.
+var tableStatusColumns = [][2]string{
+	{"Name", "table_name"},
+	{"Engine", "engine"},
+	{"Version", "version"},
+	{"Row_format", "row_format"},
+	{"Rows", "table_rows"},
+	{"Avg_row_length", "avg_row_length"},
+	{"Data_length", "data_length"},
+	{"Max_data_length", "max_data_length"},
+	{"Index_length", "index_length"},
+	{"Data_free", "data_free"},
+	{"Auto_increment", "auto_increment"},
+	{"Create_time", "create_time"},
+	{"Update_time", "update_time"},
+	{"Check_time", "check_time"},
+	{"Collation", "table_collation"},
+	{"Checksum", "checksum"},
+	{"Create_options", "create_options"},
+	{"Comment", "table_comment"},
+}
+
+// showTableStatus rewrites SHOW TABLE STATUS, for which go-mysql-server reports
+// the same hardcoded sizes for every table, to a query of
+// INFORMATION_SCHEMA.TABLES, which reports mysqlite tables' real statistics.
+func (h *Handler) showTableStatus(ctx *sql.Context, match []string) (string, error) {
+	db := ctx.GetCurrentDatabase()
+	if match[1] != "" {
+		db = unquote(match[1])
+	}
+	columns := make([]string, len(tableStatusColumns))
+	for i, c := range tableStatusColumns {
+		columns[i] = fmt.Sprintf("`%s` AS `%s`", c[1], c[0])
+	}
+	query := fmt.Sprintf(
+		"SELECT * FROM (SELECT %s FROM information_schema.tables WHERE table_schema = '%s' ORDER BY table_name) AS status",
+		strings.Join(columns, ", "),
+		strings.ReplaceAll(db, "'", "''"),
+	)
+	switch strings.ToLower(match[2]) {
+	case "like":
+		query += " WHERE `Name` LIKE " + match[3]
+	case "where":
+		query += " WHERE " + match[3]
+	}
+	return query, nil
+}
+
+// SHOW [FULL] TABLES [{FROM | IN} db_name] [LIKE 'pattern' | WHERE expr]
+var showTablesRegex = regexp.MustCompile(`(?is)^show\s+(full\s+)?tables(?:\s+(?:from|in)\s+(\S+))?(?:\s+(like|where)\s+(.*))?$`)
+
+// showTables rewrites SHOW TABLES, which go-mysql-server answers with every
+// table in the database, to a query of INFORMATION_SCHEMA.TABLES, which lists
+// only those the session's user has privileges on. Without accounts, every
+// table is visible and the query is left alone.
+func (h *Handler) showTables(ctx *sql.Context, match []string) (string, error) {
+	if h.accounts == nil {
+		return "", nil
+	}
+	db := ctx.GetCurrentDatabase()
+	if match[2] != "" {
+		db = unquote(match[2])
+	}
+	if err := h.authorize(ctx, []need{{db: db}}); err != nil {
+		return "", err
+	}
+	name := "`Tables_in_" + strings.ReplaceAll(db, "`", "``") + "`"
+	columns := "table_name AS " + name
+	if match[1] != "" {
+		columns += ", table_type AS `Table_type`"
+	}
+	query := fmt.Sprintf(
+		"SELECT * FROM (SELECT %s FROM information_schema.tables WHERE table_schema = '%s' ORDER BY table_name) AS tables",
+		columns,
+		strings.ReplaceAll(db, "'", "''"),
+	)
+	switch strings.ToLower(match[3]) {
+	case "like":
+		query += " WHERE " + name + " LIKE " + match[4]
+	case "where":
+		query += " WHERE " + match[4]
+	}
+	return query, nil
+}
+
+// textResult builds a result set of text columns.
+func textResult(columns []string, rows ...[]string) *sqltypes.Result {
+	r := &sqltypes.Result{}
+	for _, name := range columns {
+		r.Fields = append(r.Fields, &query.Field{Name: name, Type: sqltypes.Text})
+	}
+	for _, row := range rows {
+		values := make([]sqltypes.Value, len(row))
+		for i, v := range row {
+			values[i] = sqltypes.NewVarChar(v)
+		}
+		r.Rows = append(r.Rows, values)
+	}
+	return r
+}
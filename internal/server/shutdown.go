@@ -0,0 +1,56 @@
+package server
+
+import (
+	"context"
+
+	"github.com/liquidata-inc/vitess/go/mysql"
+	"github.com/sirupsen/logrus"
+)
+
+// Shutdown stops accepting connections and waits for running queries to
+// finish, refusing new ones. If ctx ends first, the queries still running are
+// killed, and Shutdown returns ctx's error once they have stopped. Start
+// returns as soon as the listeners are closed.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.Close()
+	return s.h.drain(ctx)
+}
+
+// startQuery counts a query as running, unless the server is shutting down.
+// Each successful call must be matched by a call to h.queries.Done.
+func (h *Handler) startQuery() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.draining {
+		return mysql.NewSQLError(mysql.ERServerShutdown, "08S01", "Server shutdown in progress")
+	}
+	h.queries.Add(1)
+	return nil
+}
+
+// drain refuses new queries and waits for running ones, killing them if ctx
+// ends first.
+func (h *Handler) drain(ctx context.Context) error {
+	h.mu.Lock()
+	h.draining = true
+	h.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		h.queries.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	procs := h.e.Catalog.Processes()
+	logrus.Warnf("killing %d queries still running at shutdown", len(procs))
+	for _, p := range procs {
+		h.e.Catalog.Kill(p.Connection)
+	}
+	<-done
+	return ctx.Err()
+}
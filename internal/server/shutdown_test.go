@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/liquidata-inc/vitess/go/mysql"
+)
+
+// startSleep runs SLEEP(seconds) on c, returning once the server is running it
+// and the query's outcome on the channel.
+func startSleep(t *testing.T, s *Server, c *mysql.Conn, seconds string) <-chan error {
+	t.Helper()
+	errs := make(chan error, 1)
+	go func() {
+		_, err := c.ExecuteFetch("SELECT SLEEP("+seconds+")", 10, false)
+		errs <- err
+	}()
+	for deadline := time.Now().Add(5 * time.Second); len(s.h.e.Catalog.Processes()) == 0; time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("query didn't start")
+		}
+	}
+	return errs
+}
+
+func TestShutdownDrains(t *testing.T) {
+	e, _ := testEngine(t)
+	s, addr := startServer(t, Config{}, e)
+	c := connect(t, addr, "root", "")
+	errs := startSleep(t, s, c, "0.2")
+
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		t.Errorf("Shutdown: %v", err)
+	}
+	if d := time.Since(start); d < 100*time.Millisecond {
+		t.Errorf("Shutdown returned after %v, before the running query finished", d)
+	}
+	if err := <-errs; err != nil {
+		t.Errorf("running query failed: %v", err)
+	}
+
+	if _, err := c.ExecuteFetch("SELECT 1", 10, false); sqlErrorNum(err) != mysql.ERServerShutdown {
+		t.Errorf("query after shutdown: got %v, want error %d", err, mysql.ERServerShutdown)
+	}
+	if c, err := dial(addr, "root", ""); err == nil {
+		c.Close()
+		t.Error("connected after shutdown")
+	}
+}
+
+func TestShutdownKills(t *testing.T) {
+	e, _ := testEngine(t)
+	s, addr := startServer(t, Config{}, e)
+	c := connect(t, addr, "root", "")
+	errs := startSleep(t, s, c, "30")
+
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Shutdown: got %v, want %v", err, context.DeadlineExceeded)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("Shutdown took %v", d)
+	}
+	select {
+	case err := <-errs:
+		if err == nil {
+			t.Error("killed query succeeded")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("killed query still running")
+	}
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/liquidata-inc/go-mysql-server/auth"
+	sqleserver "github.com/liquidata-inc/go-mysql-server/server"
+	"github.com/liquidata-inc/vitess/go/mysql"
+)
+
+func TestSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mysqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "mysqlite.sock")
+
+	// A socket left behind by a server that didn't shut down is replaced
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	l.Close()
+
+	e, db := testEngine(t)
+	accounts := testAccounts(t, e, db, map[string]Account{
+		"root": {PasswordHash: auth.NativePassword("secret"), Privileges: map[Scope]Privilege{{}: AllPrivileges}},
+	})
+	s, err := NewServer(Config{
+		Config: sqleserver.Config{Auth: accounts, MaxConnections: 10},
+		Socket: socket,
+	}, e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		s.Start()
+		close(done)
+	}()
+	defer func() {
+		s.Close()
+		<-done
+	}()
+	if s.Listener != nil {
+		t.Error("listening on TCP without an address")
+	}
+
+	connect := func(pass string) (*mysql.Conn, error) {
+		return mysql.Connect(context.Background(), &mysql.ConnParams{
+			UnixSocket: socket,
+			Uname:      "root",
+			Pass:       pass,
+			DbName:     "mydb",
+		})
+	}
+	c, err := connect("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	exec(t, c, "CREATE TABLE t (id INT)")
+	if _, err := connect("wrong"); sqlErrorNum(err) != mysql.ERAccessDeniedError {
+		t.Errorf("wrong password: got %v, want access denied", err)
+	}
+
+	if _, err := NewServer(Config{Config: sqleserver.Config{Auth: new(auth.None)}}, e); err == nil {
+		t.Error("created a server with nowhere to listen")
+	}
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/kevin-cantwell/mysqlite/internal/sqlite"
+	"github.com/liquidata-inc/go-mysql-server/auth"
+	"github.com/liquidata-inc/go-mysql-server/sql"
+	"github.com/liquidata-inc/go-mysql-server/sql/parse"
+	"github.com/liquidata-inc/vitess/go/sqltypes"
+	"github.com/liquidata-inc/vitess/go/vt/sqlparser"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// CREATE TEMPORARY TABLE [IF NOT EXISTS] tbl_name (create_definition,...)
+	createTemporaryRegex = regexp.MustCompile(`(?is)^create\s+temporary\s+(table\s.*)$`)
+	// DROP TEMPORARY TABLE [IF EXISTS] tbl_name [, tbl_name] ...
+	dropTemporaryRegex = regexp.MustCompile(`(?is)^drop\s+temporary\s+table\s+(if\s+exists\s+)?(.+)$`)
+)
+
+func createTemporaryNeeds(ctx *sql.Context, match []string) []need {
+	return queryNeeds(ctx, sqlite.RewriteCreateTable("create "+match[1]))
+}
+
+func (h *Handler) createTemporaryTable(ctx *sql.Context, match []string) (*sqltypes.Result, error) {
+	if err := h.e.Auth.Allowed(ctx, auth.ReadPerm|auth.WritePerm); err != nil {
+		return nil, err
+	}
+
+	// With TEMPORARY and any generated columns removed this is an ordinary CREATE
+	// TABLE, which vitess parses
+	stmt, err := sqlparser.ParseStrictDDL(sqlite.RewriteCreateTable("create " + match[1]))
+	if err != nil {
+		return nil, err
+	}
+	ddl, ok := stmt.(*sqlparser.DDL)
+	if !ok || ddl.Action != sqlparser.CreateStr || ddl.TableSpec == nil {
+		return nil, parse.ErrUnsupportedSyntax.New(ctx.Query())
+	}
+	schema, err := parse.TableSpecToSchema(ctx, ddl.TableSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := h.temporaryTableCreator(ctx, ddl.Table.Qualifier.String())
+	if err != nil {
+		return nil, err
+	}
+	name := ddl.Table.Name.String()
+	for _, col := range schema {
+		col.Source = name
+	}
+	err = db.CreateTemporaryTable(ctx, name, schema)
+	if err != nil && !(sql.ErrTableAlreadyExists.Is(err) && ddl.IfNotExists) {
+		return nil, err
+	}
+	return &sqltypes.Result{}, nil
+}
+
+func (h *Handler) dropTemporaryTable(ctx *sql.Context, match []string) (*sqltypes.Result, error) {
+	if err := h.e.Auth.Allowed(ctx, auth.ReadPerm|auth.WritePerm); err != nil {
+		return nil, err
+	}
+
+	ifExists := match[1] != ""
+	for _, name := range strings.Split(match[2], ",") {
+		var dbName string
+		name = strings.TrimSpace(name)
+		if i := strings.LastIndex(name, "."); i >= 0 {
+			dbName, name = unquote(name[:i]), name[i+1:]
+		}
+		db, err := h.temporaryTableCreator(ctx, dbName)
+		if err != nil {
+			return nil, err
+		}
+		err = db.DropTemporaryTable(ctx, unquote(name))
+		if err != nil && !(sql.ErrTableNotFound.Is(err) && ifExists) {
+			return nil, err
+		}
+	}
+	return &sqltypes.Result{}, nil
+}
+
+func (h *Handler) temporaryTableCreator(ctx *sql.Context, dbName string) (sqlite.TemporaryTableCreator, error) {
+	if dbName == "" {
+		dbName = ctx.GetCurrentDatabase()
+	}
+	db, err := h.e.Catalog.Database(dbName)
+	if err != nil {
+		return nil, err
+	}
+	tc, ok := db.(sqlite.TemporaryTableCreator)
+	if !ok {
+		return nil, fmt.Errorf("database does not support temporary tables: %s", db.Name())
+	}
+	return tc, nil
+}
+
+// endSession drops the temporary tables of a closed connection in every database
+// that supports them.
+func (h *Handler) endSession(id uint32) {
+	for _, db := range h.e.Catalog.AllDatabases() {
+		if tc, ok := db.(sqlite.TemporaryTableCreator); ok {
+			if err := tc.EndSession(id); err != nil {
+				logrus.Errorf("unable to drop temporary tables on session close: %s", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/liquidata-inc/go-mysql-server/sql"
+)
+
+func TestTemporaryTables(t *testing.T) {
+	e, db := testEngine(t)
+	_, addr := startServer(t, Config{}, e)
+	c := connect(t, addr, "root", "")
+	other := connect(t, addr, "root", "")
+
+	exec(t, c, "CREATE TABLE t (id INT)")
+	exec(t, c, "INSERT INTO t (id) VALUES (1)")
+	exec(t, c, "CREATE TEMPORARY TABLE t (id INT)")
+	exec(t, c, "CREATE TEMPORARY TABLE scratch (id INT)")
+	exec(t, c, "INSERT INTO scratch (id) VALUES (2), (3)")
+
+	// A temporary table hides the table it's named after, and only from its
+	// session
+	if got := column(exec(t, c, "SELECT id FROM scratch ORDER BY id")); !reflect.DeepEqual(got, []string{"2", "3"}) {
+		t.Errorf("temporary table holds %v, want [2 3]", got)
+	}
+	if got := column(exec(t, c, "SELECT id FROM t")); got != nil {
+		t.Errorf("temporary t holds %v, want nothing", got)
+	}
+	if got := column(exec(t, other, "SELECT id FROM t")); !reflect.DeepEqual(got, []string{"1"}) {
+		t.Errorf("another session sees %v in t, want [1]", got)
+	}
+	if _, err := other.ExecuteFetch("SELECT id FROM scratch", 10, false); err == nil || !strings.Contains(err.Error(), "table not found") {
+		t.Errorf("another session selecting from scratch: got %v, want table not found", err)
+	}
+	exec(t, other, "CREATE TEMPORARY TABLE scratch (name TEXT)")
+	exec(t, other, "DROP TEMPORARY TABLE scratch")
+
+	exec(t, c, "DROP TEMPORARY TABLE t")
+	if got := column(exec(t, c, "SELECT id FROM t")); !reflect.DeepEqual(got, []string{"1"}) {
+		t.Errorf("after dropping temporary t, t holds %v, want [1]", got)
+	}
+
+	// Closing the connection drops its tables
+	ctx := sql.NewContext(context.Background(), sql.WithSession(sql.NewSession("", "", "root", c.ConnectionID)))
+	if _, ok, err := db.GetTableInsensitive(ctx, "scratch"); err != nil || !ok {
+		t.Fatalf("scratch not found for its session: %v", err)
+	}
+	c.Close()
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		_, ok, err := db.GetTableInsensitive(ctx, "scratch")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("scratch not dropped when its session closed")
+		}
+	}
+}
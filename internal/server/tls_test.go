@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/liquidata-inc/vitess/go/mysql"
+)
+
+// selfSignedCert returns a certificate for localhost and the path of a file
+// holding it, for clients to verify it with.
+func selfSignedCert(t *testing.T) (tls.Certificate, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "mysqlite test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "mysqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "cert.pem")
+	if err := ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, path
+}
+
+func TestRequireSecureTransport(t *testing.T) {
+	cert, caFile := selfSignedCert(t)
+	e, _ := testEngine(t)
+	_, addr := startServer(t, Config{
+		TLSConfig:              &tls.Config{Certificates: []tls.Certificate{cert}},
+		RequireSecureTransport: true,
+	}, e)
+
+	if c, err := dial(addr, "root", ""); sqlErrorNum(err) != erSecureTransportRequired {
+		if err == nil {
+			c.Close()
+		}
+		t.Errorf("connecting without TLS: got %v, want error %d", err, erSecureTransportRequired)
+	}
+
+	host, port, _ := net.SplitHostPort(addr)
+	p, _ := strconv.Atoi(port)
+	params := &mysql.ConnParams{Host: host, Port: p, Uname: "root", DbName: "mydb", SslCa: caFile, ServerName: "localhost"}
+	params.EnableSSL()
+	c, err := mysql.Connect(context.Background(), params)
+	if err != nil {
+		t.Fatalf("connecting with TLS: %v", err)
+	}
+	defer c.Close()
+	exec(t, c, "SELECT 1")
+}
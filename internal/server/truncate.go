@@ -0,0 +1,51 @@
+package server
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/kevin-cantwell/mysqlite/internal/sqlite"
+	"github.com/liquidata-inc/go-mysql-server/auth"
+	"github.com/liquidata-inc/go-mysql-server/sql"
+	"github.com/liquidata-inc/vitess/go/sqltypes"
+)
+
+// TRUNCATE [TABLE] tbl_name [WHERE rowtime < n]
+var truncateRegex = regexp.MustCompile(`(?is)^truncate\s+(?:table\s+)?(\S+)(?:\s+where\s+rowtime\s*<\s*(-?\d+))?$`)
+
+// truncateNeeds returns the DROP privilege MySQL requires to TRUNCATE.
+func truncateNeeds(ctx *sql.Context, match []string) []need {
+	return []need{tableNeed(ctx, DropPriv, match[1])}
+}
+
+func (h *Handler) truncate(ctx *sql.Context, match []string) (*sqltypes.Result, error) {
+	if err := h.e.Auth.Allowed(ctx, auth.ReadPerm|auth.WritePerm); err != nil {
+		return nil, err
+	}
+
+	table, err := h.table(ctx, match[1])
+	if err != nil {
+		return nil, err
+	}
+	t, ok := table.(sqlite.TruncatableTable)
+	if !ok {
+		return nil, fmt.Errorf("table does not support TRUNCATE: %s", table.Name())
+	}
+
+	var n int
+	if match[2] == "" {
+		n, err = t.Truncate(ctx)
+	} else {
+		var rowtime int64
+		rowtime, err = strconv.ParseInt(match[2], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		n, err = t.TruncateBefore(ctx, rowtime)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sqltypes.Result{RowsAffected: uint64(n)}, nil
+}
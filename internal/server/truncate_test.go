@@ -0,0 +1,46 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+
+	sqleserver "github.com/liquidata-inc/go-mysql-server/server"
+)
+
+func TestTruncate(t *testing.T) {
+	e, _ := testEngine(t)
+	_, addr := startServer(t, Config{}, e)
+	c := connect(t, addr, "root", "")
+	exec(t, c, "CREATE TABLE events (id INT)")
+	exec(t, c, "INSERT INTO events (rowtime, id) VALUES (100, 1), (200, 2), (300, 3)")
+
+	// Rows at the given rowtime are kept
+	if r := exec(t, c, "TRUNCATE events WHERE rowtime < 200"); r.RowsAffected != 1 {
+		t.Errorf("TRUNCATE ... WHERE rowtime < 200 deleted %d rows, want 1", r.RowsAffected)
+	}
+	if got := column(exec(t, c, "SELECT rowtime FROM events ORDER BY rowtime")); !reflect.DeepEqual(got, []string{"200", "300"}) {
+		t.Errorf("kept rowtimes %v, want [200 300]", got)
+	}
+	if r := exec(t, c, "TRUNCATE TABLE events"); r.RowsAffected != 2 {
+		t.Errorf("TRUNCATE TABLE deleted %d rows, want 2", r.RowsAffected)
+	}
+	if got := column(exec(t, c, "SELECT id FROM events")); got != nil {
+		t.Errorf("kept %v after TRUNCATE TABLE", got)
+	}
+	exec(t, c, "INSERT INTO events (id) VALUES (4)")
+}
+
+func TestTruncateNeedsDrop(t *testing.T) {
+	e, db := testEngine(t)
+	accounts := testAccounts(t, e, db, map[string]Account{
+		"root":   {Privileges: map[Scope]Privilege{{}: AllPrivileges}},
+		"writer": {Privileges: map[Scope]Privilege{{Database: "mydb"}: SelectPriv | InsertPriv | DeletePriv}},
+	})
+	_, addr := startServer(t, Config{Config: sqleserver.Config{Auth: accounts}}, e)
+	exec(t, connect(t, addr, "root", ""), "CREATE TABLE events (id INT)")
+
+	c := connect(t, addr, "writer", "")
+	if _, err := c.ExecuteFetch("TRUNCATE events", 10, false); sqlErrorNum(err) != erTableAccessDenied {
+		t.Errorf("TRUNCATE without DROP: got %v, want access denied", err)
+	}
+}
@@ -0,0 +1,328 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/liquidata-inc/go-mysql-server/auth"
+	"github.com/liquidata-inc/go-mysql-server/sql"
+	"github.com/liquidata-inc/go-mysql-server/sql/parse"
+	"github.com/liquidata-inc/vitess/go/mysql"
+	"github.com/liquidata-inc/vitess/go/sqltypes"
+)
+
+var (
+	// CREATE USER [IF NOT EXISTS] user [auth_option] [, user [auth_option]] ...
+	createUserRegex = regexp.MustCompile(`(?is)^create\s+user\s+(if\s+not\s+exists\s+)?(.+)$`)
+	// DROP USER [IF EXISTS] user [, user] ...
+	dropUserRegex = regexp.MustCompile(`(?is)^drop\s+user\s+(if\s+exists\s+)?(.+)$`)
+	// ALTER USER [IF EXISTS] user auth_option [, user auth_option] ...
+	alterUserRegex = regexp.MustCompile(`(?is)^alter\s+user\s+(if\s+exists\s+)?(.+)$`)
+	// GRANT priv_type [, priv_type] ... ON [TABLE] priv_level TO user [, user] ... [WITH GRANT OPTION]
+	grantRegex = regexp.MustCompile(`(?is)^grant\s+(.+?)\s+on\s+(?:table\s+)?(\S+)\s+to\s+(.+?)(\s+with\s+grant\s+option)?$`)
+	// REVOKE priv_type [, priv_type] ... ON [TABLE] priv_level FROM user [, user] ...
+	// REVOKE ALL [PRIVILEGES], GRANT OPTION FROM user [, user] ...
+	revokeRegex = regexp.MustCompile(`(?is)^revoke\s+(.+?)\s+(?:on\s+(?:table\s+)?(\S+)\s+)?from\s+(.+)$`)
+	// SHOW GRANTS [FOR user]
+	showGrantsRegex = regexp.MustCompile(`(?is)^show\s+grants(?:\s+for\s+(.+))?$`)
+)
+
+// userSpecRegex matches the first user of a list: 'name'@'host', with either
+// part optionally quoted and the host optional, then an auth_option:
+// IDENTIFIED [WITH mysql_native_password] {BY 'password' | AS 'hash'}.
+// Hosts are accepted but ignored, since accounts are the same from any host.
+var userSpecRegex = regexp.MustCompile(`(?is)^\s*` +
+	"('(?:[^'\\\\]|\\\\.|'')*'|\"(?:[^\"\\\\]|\\\\.|\"\")*\"|`(?:[^`]|``)*`|[\\w$]+(?:\\s*\\(\\s*\\))?)" +
+	"(?:\\s*@\\s*(?:'[^']*'|\"[^\"]*\"|`[^`]*`|[\\w%.$:-]+))?" +
+	`(?:\s+identified\s+(?:with\s+'?mysql_native_password'?\s+)?(by|as)\s+` + stringLiteral + `)?` +
+	`\s*(?:,|$)`)
+
+// userSpec is a user named by a statement, with the password hash its
+// auth_option gives, if any.
+type userSpec struct {
+	name         string
+	identified   bool
+	passwordHash string
+}
+
+// parseUsers parses a comma separated list of users, failing if one has an
+// auth_option and identified isn't set. CURRENT_USER names the session's user.
+func parseUsers(ctx *sql.Context, list string, identified bool) ([]userSpec, error) {
+	var users []userSpec
+	for rest := strings.TrimSpace(list); rest != ""; {
+		m := userSpecRegex.FindStringSubmatch(rest)
+		if m == nil {
+			return nil, parse.ErrUnsupportedSyntax.New(ctx.Query())
+		}
+		rest = rest[len(m[0]):]
+
+		u := userSpec{name: userName(ctx, m[1])}
+		switch strings.ToLower(m[2]) {
+		case "":
+		case "by":
+			u.identified = true
+			u.passwordHash = auth.NativePassword(unquoteString(m[3]))
+		case "as":
+			u.identified = true
+			u.passwordHash = strings.ToUpper(unquoteString(m[3]))
+			if u.passwordHash != "" && !nativePasswordRegex.MatchString(u.passwordHash) {
+				return nil, mysql.NewSQLError(erPasswordFormat, "HY000",
+					"The password hash doesn't have the expected format.")
+			}
+		}
+		if u.identified && !identified {
+			return nil, parse.ErrUnsupportedSyntax.New(ctx.Query())
+		}
+		users = append(users, u)
+	}
+	if len(users) == 0 {
+		return nil, parse.ErrUnsupportedSyntax.New(ctx.Query())
+	}
+	return users, nil
+}
+
+var nativePasswordRegex = regexp.MustCompile(`^\*[0-9A-F]{40}$`)
+
+// userName unquotes the user name token of a user spec.
+func userName(ctx *sql.Context, token string) string {
+	switch token[0] {
+	case '\'', '"':
+		return unquoteString(token[1 : len(token)-1])
+	case '`':
+		return strings.Replace(token[1:len(token)-1], "``", "`", -1)
+	}
+	if name := strings.ToLower(strings.Join(strings.Fields(token), "")); name == "current_user" || name == "current_user()" {
+		return ctx.Client().User
+	}
+	return token
+}
+
+// errNoAccounts is returned by account management statements when the server
+// doesn't authenticate with Accounts.
+var errNoAccounts = errors.New("this server's accounts can't be managed with SQL")
+
+func (h *Handler) createUser(ctx *sql.Context, match []string) (*sqltypes.Result, error) {
+	if h.accounts == nil {
+		return nil, errNoAccounts
+	}
+	if err := h.accounts.Check(ctx, CreateUserPriv, "", ""); err != nil {
+		return nil, err
+	}
+	users, err := parseUsers(ctx, match[2], true)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		if err := h.accounts.CreateUser(ctx, u.name, u.passwordHash, match[1] != ""); err != nil {
+			return nil, err
+		}
+	}
+	return &sqltypes.Result{}, nil
+}
+
+func (h *Handler) dropUser(ctx *sql.Context, match []string) (*sqltypes.Result, error) {
+	if h.accounts == nil {
+		return nil, errNoAccounts
+	}
+	if err := h.accounts.Check(ctx, CreateUserPriv, "", ""); err != nil {
+		return nil, err
+	}
+	users, err := parseUsers(ctx, match[2], false)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		if err := h.accounts.DropUser(ctx, u.name, match[1] != ""); err != nil {
+			return nil, err
+		}
+	}
+	return &sqltypes.Result{}, nil
+}
+
+// alterUser changes passwords, which users may do to their own account
+// without the CREATE USER privilege.
+func (h *Handler) alterUser(ctx *sql.Context, match []string) (*sqltypes.Result, error) {
+	if h.accounts == nil {
+		return nil, errNoAccounts
+	}
+	users, err := parseUsers(ctx, match[2], true)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		if u.name != ctx.Client().User {
+			if err := h.accounts.Check(ctx, CreateUserPriv, "", ""); err != nil {
+				return nil, err
+			}
+		}
+		if !u.identified {
+			// Other account options are accepted and ignored
+			continue
+		}
+		if err := h.accounts.SetPassword(ctx, u.name, u.passwordHash, match[1] != ""); err != nil {
+			return nil, err
+		}
+	}
+	return &sqltypes.Result{}, nil
+}
+
+// grant gives users privileges, which the granting user must have on the
+// same level along with GRANT OPTION.
+func (h *Handler) grant(ctx *sql.Context, match []string) (*sqltypes.Result, error) {
+	if h.accounts == nil {
+		return nil, errNoAccounts
+	}
+	scope, err := parseScope(ctx, match[2])
+	if err != nil {
+		return nil, err
+	}
+	priv, err := parsePrivileges(scope, match[1])
+	if err != nil {
+		return nil, err
+	}
+	if match[4] != "" {
+		priv |= GrantOptionPriv
+	}
+	if err := h.accounts.Check(ctx, priv|GrantOptionPriv, scope.Database, scope.Table); err != nil {
+		return nil, err
+	}
+	users, err := parseUsers(ctx, match[3], false)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		if err := h.accounts.Grant(ctx, u.name, scope, priv); err != nil {
+			return nil, err
+		}
+	}
+	return &sqltypes.Result{}, nil
+}
+
+// revoke takes privileges away from users, which the revoking user must have
+// on the same level along with GRANT OPTION. Revoking every privilege at once
+// takes CREATE USER.
+func (h *Handler) revoke(ctx *sql.Context, match []string) (*sqltypes.Result, error) {
+	if h.accounts == nil {
+		return nil, errNoAccounts
+	}
+	var scope Scope
+	var priv Privilege
+	everywhere := match[2] == ""
+	if everywhere {
+		privs := strings.Fields(strings.ToLower(strings.Replace(match[1], ",", " , ", -1)))
+		if p := strings.Join(privs, " "); p != "all , grant option" && p != "all privileges , grant option" {
+			return nil, parse.ErrUnsupportedSyntax.New(ctx.Query())
+		}
+		if err := h.accounts.Check(ctx, CreateUserPriv, "", ""); err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		if scope, err = parseScope(ctx, match[2]); err != nil {
+			return nil, err
+		}
+		if priv, err = parsePrivileges(scope, match[1]); err != nil {
+			return nil, err
+		}
+		if err := h.accounts.Check(ctx, priv|GrantOptionPriv, scope.Database, scope.Table); err != nil {
+			return nil, err
+		}
+	}
+	users, err := parseUsers(ctx, match[3], false)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		if err := h.accounts.Revoke(ctx, u.name, scope, priv, everywhere); err != nil {
+			return nil, err
+		}
+	}
+	return &sqltypes.Result{}, nil
+}
+
+// showGrants lists a user's privileges as GRANT statements. Users can see
+// their own; seeing others' takes CREATE USER.
+func (h *Handler) showGrants(ctx *sql.Context, match []string) (*sqltypes.Result, error) {
+	if h.accounts == nil {
+		return nil, errNoAccounts
+	}
+	user := ctx.Client().User
+	if match[1] != "" {
+		users, err := parseUsers(ctx, match[1], false)
+		if err != nil {
+			return nil, err
+		}
+		if len(users) > 1 {
+			return nil, parse.ErrUnsupportedSyntax.New(ctx.Query())
+		}
+		user = users[0].name
+	}
+	if user != ctx.Client().User {
+		if err := h.accounts.Check(ctx, CreateUserPriv, "", ""); err != nil {
+			return nil, err
+		}
+	}
+
+	grants, err := h.accounts.Grants(user)
+	if err != nil {
+		return nil, err
+	}
+	var rows [][]string
+	for _, g := range grants {
+		rows = append(rows, []string{g})
+	}
+	return textResult([]string{fmt.Sprintf("Grants for %s@%%", user)}, rows...), nil
+}
+
+// parseScope parses a priv_level: *.*, db_name.*, db_name.tbl_name, tbl_name,
+// or * for the current database.
+func parseScope(ctx *sql.Context, level string) (Scope, error) {
+	db, table := ctx.GetCurrentDatabase(), level
+	if i := strings.LastIndex(level, "."); i >= 0 {
+		db, table = unquote(level[:i]), level[i+1:]
+		if db == "*" {
+			if table != "*" {
+				return Scope{}, parse.ErrUnsupportedSyntax.New(ctx.Query())
+			}
+			return Scope{}, nil
+		}
+	} else if db == "" {
+		return Scope{}, sql.ErrNoDatabaseSelected.New()
+	}
+	s := Scope{Database: strings.ToLower(db)}
+	if table != "*" {
+		s.Table = strings.ToLower(unquote(table))
+	}
+	return s, nil
+}
+
+// parsePrivileges parses a comma separated list of privileges to grant or
+// revoke on scope. ALL means every privilege that can be granted there.
+func parsePrivileges(scope Scope, list string) (Privilege, error) {
+	var priv Privilege
+	for _, name := range strings.Split(list, ",") {
+		name = strings.Join(strings.Fields(strings.ToUpper(name)), " ")
+		switch name {
+		case "ALL", "ALL PRIVILEGES":
+			if scope.Database == "" {
+				priv |= AllPrivileges
+			} else {
+				priv |= tablePrivileges &^ GrantOptionPriv
+			}
+		case "USAGE":
+		default:
+			p, ok := ParsePrivilege(name)
+			if !ok {
+				return 0, fmt.Errorf("unsupported privilege: %s", name)
+			}
+			if scope.Database != "" && p&tablePrivileges == 0 {
+				return 0, mysql.NewSQLError(mysql.ERIllegalGrantForTable, "42000",
+					"Illegal GRANT/REVOKE command; please consult the manual to see which privileges can be used")
+			}
+			priv |= p
+		}
+	}
+	return priv, nil
+}
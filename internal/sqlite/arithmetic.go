@@ -0,0 +1,511 @@
+package sqlite
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/liquidata-inc/go-mysql-server/sql"
+	"github.com/liquidata-inc/go-mysql-server/sql/analyzer"
+	"github.com/liquidata-inc/go-mysql-server/sql/expression"
+	"github.com/liquidata-inc/go-mysql-server/sql/expression/function/aggregation"
+	"github.com/liquidata-inc/go-mysql-server/sql/plan"
+	"github.com/liquidata-inc/vitess/go/vt/sqlparser"
+	"github.com/shopspring/decimal"
+)
+
+// ExactDecimals is an analyzer rule that computes arithmetic, comparisons, SUM
+// and AVG on DECIMAL values exactly, as MySQL does, rather than with the floats
+// the engine uses. Add it with analyzer.Builder.AddPostAnalyzeRule.
+func ExactDecimals(ctx *sql.Context, a *analyzer.Analyzer, n sql.Node, scope *analyzer.Scope) (sql.Node, error) {
+	return plan.TransformExpressionsUp(n, func(e sql.Expression) (sql.Expression, error) {
+		switch e := e.(type) {
+		case *expression.Arithmetic:
+			if d, ok := newDecimalArithmetic(e.Left, e.Right, strings.ToLower(e.Op)); ok {
+				return d, nil
+			}
+		case *expression.UnaryMinus:
+			if sql.IsDecimal(e.Child.Type()) {
+				return &decimalNegation{expression.UnaryExpression{Child: e.Child}}, nil
+			}
+		case expression.Comparer:
+			if l, r, ok := decimalComparison(e.Left(), e.Right()); ok {
+				return e.WithChildren(l, r)
+			}
+		case *aggregation.Sum:
+			if sql.IsDecimal(e.Child.Type()) {
+				return newDecimalSum(e.Child), nil
+			}
+		case *aggregation.Avg:
+			if sql.IsDecimal(e.Child.Type()) {
+				return newDecimalAvg(e.Child), nil
+			}
+		}
+		return e, nil
+	})
+}
+
+const (
+	maxDecimalPrecision = 65
+	maxDecimalScale     = 30
+	// divPrecisionIncrement is the scale a division adds to its dividend's,
+	// MySQL's default div_precision_increment
+	divPrecisionIncrement = 4
+)
+
+// anyDecimal converts the operands of exact arithmetic to decimals.
+var anyDecimal = sql.MustCreateDecimalType(maxDecimalPrecision, maxDecimalScale)
+
+// decimalType returns the DECIMAL type of the given precision and scale, or
+// the closest one that exists.
+func decimalType(precision, scale int) sql.DecimalType {
+	if scale > maxDecimalScale {
+		scale = maxDecimalScale
+	}
+	if precision > maxDecimalPrecision {
+		precision = maxDecimalPrecision
+	}
+	if precision < scale || precision == 0 {
+		precision = scale + 1
+	}
+	return sql.MustCreateDecimalType(uint8(precision), uint8(scale))
+}
+
+// decimalOperand returns the DECIMAL type the value of e is exact in, or false
+// if e is approximate, like a FLOAT. MySQL reads literals like 1.5 as decimals
+// but the engine has them as doubles, so they're given the type of the text
+// they were written as.
+func decimalOperand(e sql.Expression) (sql.DecimalType, bool) {
+	t := e.Type()
+	switch {
+	case sql.IsDecimal(t):
+		return t.(sql.DecimalType), true
+	case sql.IsInteger(t):
+		return decimalType(20, 0), true
+	case sql.IsFloat(t):
+		l, ok := e.(*expression.Literal)
+		if !ok || l.Value() == nil {
+			return nil, false
+		}
+		d, err := decimal.NewFromString(fmt.Sprint(l.Value()))
+		if err != nil {
+			return nil, false
+		}
+		return exactDecimalType(d), true
+	}
+	return nil, false
+}
+
+// isDecimalLiteral reports whether MySQL has e as a DECIMAL, which includes
+// literals like 1.5 that the engine has as doubles.
+func isDecimalLiteral(e sql.Expression) bool {
+	if sql.IsDecimal(e.Type()) {
+		return true
+	}
+	_, ok := e.(*expression.Literal)
+	return ok && sql.IsFloat(e.Type())
+}
+
+// exactDecimalType returns the DECIMAL type of a literal written as d.
+func exactDecimalType(d decimal.Decimal) sql.DecimalType {
+	scale := 0
+	if d.Exponent() < 0 {
+		scale = int(-d.Exponent())
+	}
+	return decimalType(len(d.Abs().Coefficient().String()), scale)
+}
+
+// decimalComparison returns the operands of a comparison converted to a DECIMAL
+// type that holds both exactly, if one is a DECIMAL and the other isn't
+// approximate. The engine compares operands of the same type as that type, but
+// otherwise rounds both to the scale of the DECIMAL one.
+func decimalComparison(left, right sql.Expression) (sql.Expression, sql.Expression, bool) {
+	if !sql.IsDecimal(left.Type()) && !sql.IsDecimal(right.Type()) {
+		return nil, nil, false
+	}
+	l, ok := decimalOperand(left)
+	if !ok {
+		return nil, nil, false
+	}
+	r, ok := decimalOperand(right)
+	if !ok {
+		return nil, nil, false
+	}
+	if l == r {
+		return nil, nil, false
+	}
+	scale := int(l.Scale())
+	if int(r.Scale()) > scale {
+		scale = int(r.Scale())
+	}
+	digits := int(l.Precision() - l.Scale())
+	if d := int(r.Precision() - r.Scale()); d > digits {
+		digits = d
+	}
+	// Both sides must have the very same type
+	typ := decimalType(digits+scale, scale)
+	return &decimalConversion{expression.UnaryExpression{Child: left}, typ},
+		&decimalConversion{expression.UnaryExpression{Child: right}, typ}, true
+}
+
+// decimalConversion converts a value to a DECIMAL type.
+type decimalConversion struct {
+	expression.UnaryExpression
+	typ sql.DecimalType
+}
+
+var _ sql.Expression = (*decimalConversion)(nil)
+
+func (c *decimalConversion) Type() sql.Type { return c.typ }
+func (c *decimalConversion) String() string { return c.Child.String() }
+
+func (c *decimalConversion) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 1 {
+		return nil, sql.ErrInvalidChildrenNumber.New(c, len(children), 1)
+	}
+	return &decimalConversion{expression.UnaryExpression{Child: children[0]}, c.typ}, nil
+}
+
+func (c *decimalConversion) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
+	v, err := c.Child.Eval(ctx, row)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	return c.typ.Convert(v)
+}
+
+// decimalNegation is unary minus on a DECIMAL value, which the engine can't
+// negate.
+type decimalNegation struct {
+	expression.UnaryExpression
+}
+
+var _ sql.Expression = (*decimalNegation)(nil)
+
+func (n *decimalNegation) Type() sql.Type { return n.Child.Type() }
+func (n *decimalNegation) String() string { return fmt.Sprintf("-%s", n.Child) }
+
+func (n *decimalNegation) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 1 {
+		return nil, sql.ErrInvalidChildrenNumber.New(n, len(children), 1)
+	}
+	return &decimalNegation{expression.UnaryExpression{Child: children[0]}}, nil
+}
+
+func (n *decimalNegation) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
+	d, err := evalDecimal(ctx, n.Child, row)
+	if err != nil || !d.Valid {
+		return nil, err
+	}
+	return n.Child.Type().Convert(d.Decimal.Neg())
+}
+
+// decimalArithmetic is +, -, * or / computed exactly.
+type decimalArithmetic struct {
+	expression.BinaryExpression
+	op  string
+	typ sql.DecimalType
+}
+
+var _ sql.Expression = (*decimalArithmetic)(nil)
+
+// newDecimalArithmetic returns exact arithmetic on the operands, typed as
+// MySQL types it, if one is a DECIMAL or a literal like 1.5 and the other isn't
+// approximate.
+func newDecimalArithmetic(left, right sql.Expression, op string) (*decimalArithmetic, bool) {
+	if !isDecimalLiteral(left) && !isDecimalLiteral(right) {
+		return nil, false
+	}
+	l, ok := decimalOperand(left)
+	if !ok {
+		return nil, false
+	}
+	r, ok := decimalOperand(right)
+	if !ok {
+		return nil, false
+	}
+	lp, ls, rp, rs := int(l.Precision()), int(l.Scale()), int(r.Precision()), int(r.Scale())
+
+	var typ sql.DecimalType
+	switch op {
+	case sqlparser.PlusStr, sqlparser.MinusStr:
+		scale := ls
+		if rs > scale {
+			scale = rs
+		}
+		digits := lp - ls
+		if rp-rs > digits {
+			digits = rp - rs
+		}
+		typ = decimalType(digits+scale+1, scale)
+	case sqlparser.MultStr:
+		typ = decimalType(lp+rp, ls+rs)
+	case sqlparser.DivStr:
+		scale := ls + divPrecisionIncrement
+		typ = decimalType(lp-ls+rs+scale, scale)
+	default:
+		return nil, false
+	}
+	return &decimalArithmetic{expression.BinaryExpression{Left: left, Right: right}, op, typ}, true
+}
+
+func (a *decimalArithmetic) Type() sql.Type { return a.typ }
+
+func (a *decimalArithmetic) IsNullable() bool {
+	// Division by zero is NULL
+	return a.op == sqlparser.DivStr || a.BinaryExpression.IsNullable()
+}
+
+func (a *decimalArithmetic) String() string {
+	return fmt.Sprintf("%s %s %s", a.Left, a.op, a.Right)
+}
+
+func (a *decimalArithmetic) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 2 {
+		return nil, sql.ErrInvalidChildrenNumber.New(a, len(children), 2)
+	}
+	d, ok := newDecimalArithmetic(children[0], children[1], a.op)
+	if !ok {
+		return expression.NewArithmetic(children[0], children[1], a.op), nil
+	}
+	return d, nil
+}
+
+func (a *decimalArithmetic) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
+	l, err := evalDecimal(ctx, a.Left, row)
+	if err != nil || !l.Valid {
+		return nil, err
+	}
+	r, err := evalDecimal(ctx, a.Right, row)
+	if err != nil || !r.Valid {
+		return nil, err
+	}
+
+	var res decimal.Decimal
+	switch a.op {
+	case sqlparser.PlusStr:
+		res = l.Decimal.Add(r.Decimal)
+	case sqlparser.MinusStr:
+		res = l.Decimal.Sub(r.Decimal)
+	case sqlparser.MultStr:
+		res = l.Decimal.Mul(r.Decimal)
+	case sqlparser.DivStr:
+		if r.Decimal.IsZero() {
+			return nil, nil
+		}
+		res = l.Decimal.DivRound(r.Decimal, int32(a.typ.Scale()))
+	}
+	return a.typ.Convert(res)
+}
+
+func evalDecimal(ctx *sql.Context, e sql.Expression, row sql.Row) (decimal.NullDecimal, error) {
+	v, err := e.Eval(ctx, row)
+	if err != nil {
+		return decimal.NullDecimal{}, err
+	}
+	return anyDecimal.ConvertToDecimal(v)
+}
+
+// decimalSum is SUM of DECIMAL values, computed exactly.
+type decimalSum struct {
+	expression.UnaryExpression
+	typ sql.DecimalType
+}
+
+var _ sql.Aggregation = (*decimalSum)(nil)
+
+func newDecimalSum(e sql.Expression) *decimalSum {
+	t := e.Type().(sql.DecimalType)
+	return &decimalSum{expression.UnaryExpression{Child: e}, decimalType(int(t.Precision())+22, int(t.Scale()))}
+}
+
+func (s *decimalSum) Type() sql.Type   { return s.typ }
+func (s *decimalSum) IsNullable() bool { return true }
+func (s *decimalSum) String() string   { return fmt.Sprintf("SUM(%s)", s.Child) }
+
+func (s *decimalSum) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 1 {
+		return nil, sql.ErrInvalidChildrenNumber.New(s, len(children), 1)
+	}
+	return newDecimalSum(children[0]), nil
+}
+
+// NewBuffer holds the sum, nil until there's a value to sum.
+func (s *decimalSum) NewBuffer() sql.Row {
+	return sql.NewRow(nil)
+}
+
+func (s *decimalSum) Update(ctx *sql.Context, buffer, row sql.Row) error {
+	d, err := evalDecimal(ctx, s.Child, row)
+	if err != nil || !d.Valid {
+		return err
+	}
+	addDecimal(buffer, 0, d.Decimal)
+	return nil
+}
+
+func (s *decimalSum) Merge(ctx *sql.Context, buffer, partial sql.Row) error {
+	if partial[0] != nil {
+		addDecimal(buffer, 0, partial[0].(decimal.Decimal))
+	}
+	return nil
+}
+
+func (s *decimalSum) Eval(ctx *sql.Context, buffer sql.Row) (interface{}, error) {
+	if buffer[0] == nil {
+		return nil, nil
+	}
+	return s.typ.Convert(buffer[0])
+}
+
+// decimalAvg is AVG of DECIMAL values, computed exactly.
+type decimalAvg struct {
+	expression.UnaryExpression
+	typ sql.DecimalType
+}
+
+var _ sql.Aggregation = (*decimalAvg)(nil)
+
+func newDecimalAvg(e sql.Expression) *decimalAvg {
+	t := e.Type().(sql.DecimalType)
+	return &decimalAvg{expression.UnaryExpression{Child: e}, decimalType(int(t.Precision())+divPrecisionIncrement, int(t.Scale())+divPrecisionIncrement)}
+}
+
+func (a *decimalAvg) Type() sql.Type   { return a.typ }
+func (a *decimalAvg) IsNullable() bool { return true }
+func (a *decimalAvg) String() string   { return fmt.Sprintf("AVG(%s)", a.Child) }
+
+func (a *decimalAvg) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 1 {
+		return nil, sql.ErrInvalidChildrenNumber.New(a, len(children), 1)
+	}
+	return newDecimalAvg(children[0]), nil
+}
+
+// NewBuffer holds the sum and count of the values.
+func (a *decimalAvg) NewBuffer() sql.Row {
+	return sql.NewRow(nil, int64(0))
+}
+
+func (a *decimalAvg) Update(ctx *sql.Context, buffer, row sql.Row) error {
+	d, err := evalDecimal(ctx, a.Child, row)
+	if err != nil || !d.Valid {
+		return err
+	}
+	addDecimal(buffer, 0, d.Decimal)
+	buffer[1] = buffer[1].(int64) + 1
+	return nil
+}
+
+func (a *decimalAvg) Merge(ctx *sql.Context, buffer, partial sql.Row) error {
+	if partial[0] != nil {
+		addDecimal(buffer, 0, partial[0].(decimal.Decimal))
+		buffer[1] = buffer[1].(int64) + partial[1].(int64)
+	}
+	return nil
+}
+
+func (a *decimalAvg) Eval(ctx *sql.Context, buffer sql.Row) (interface{}, error) {
+	if buffer[0] == nil {
+		return nil, nil
+	}
+	sum, n := buffer[0].(decimal.Decimal), decimal.NewFromInt(buffer[1].(int64))
+	return a.typ.Convert(sum.DivRound(n, int32(a.typ.Scale())))
+}
+
+func addDecimal(buffer sql.Row, i int, d decimal.Decimal) {
+	if buffer[i] == nil {
+		buffer[i] = d
+	} else {
+		buffer[i] = buffer[i].(decimal.Decimal).Add(d)
+	}
+}
+
+// decimalLiteralFunction is the function ExactDecimalLiterals wraps literals
+// in.
+const decimalLiteralFunction = "mysqlite_decimal"
+
+// ExactDecimalLiterals rewrites the literals of a query that the engine would
+// round, since it parses them as doubles where MySQL has exact decimals, to
+// calls of a function that returns the exact decimal. It reports false if the
+// query has no such literals.
+func ExactDecimalLiterals(query string) (string, bool) {
+	// The positions of tokens in special comments are relative to the comment
+	if strings.Contains(query, "/*!") {
+		return query, false
+	}
+	var b strings.Builder
+	last := 0
+	tkn := sqlparser.NewStringTokenizer(query)
+	for {
+		typ, val := tkn.Scan()
+		if typ == 0 || typ == sqlparser.LEX_ERROR {
+			break
+		}
+		// MySQL reads literals with an exponent as doubles too
+		if typ != sqlparser.FLOAT || strings.ContainsAny(string(val), "eE") {
+			continue
+		}
+		d, err := decimal.NewFromString(string(val))
+		if err != nil {
+			continue
+		}
+		if f, _ := d.Float64(); decimal.NewFromFloat(f).Equal(d) {
+			continue
+		}
+		// The tokenizer has read one character past the literal
+		end := tkn.Position - 1
+		start := end - len(val)
+		b.WriteString(query[last:start])
+		b.WriteString(decimalLiteralFunction + "('" + string(val) + "')")
+		last = end
+	}
+	if last == 0 {
+		return query, false
+	}
+	b.WriteString(query[last:])
+	return b.String(), true
+}
+
+// decimalLiteral is a literal DECIMAL value, typed with the digits it's written
+// with.
+type decimalLiteral struct {
+	expression.UnaryExpression
+	typ sql.DecimalType
+}
+
+var _ sql.Expression = (*decimalLiteral)(nil)
+
+func newDecimalLiteral(e sql.Expression) sql.Expression {
+	typ := anyDecimal
+	if l, ok := e.(*expression.Literal); ok {
+		if d, err := decimal.NewFromString(fmt.Sprint(l.Value())); err == nil {
+			typ = exactDecimalType(d)
+		}
+	}
+	return &decimalLiteral{expression.UnaryExpression{Child: e}, typ}
+}
+
+func (l *decimalLiteral) Type() sql.Type { return l.typ }
+
+// String is the literal as it was written.
+func (l *decimalLiteral) String() string {
+	if c, ok := l.Child.(*expression.Literal); ok {
+		return fmt.Sprint(c.Value())
+	}
+	return fmt.Sprintf("%s(%s)", decimalLiteralFunction, l.Child)
+}
+
+func (l *decimalLiteral) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 1 {
+		return nil, sql.ErrInvalidChildrenNumber.New(l, len(children), 1)
+	}
+	return newDecimalLiteral(children[0]), nil
+}
+
+func (l *decimalLiteral) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
+	v, err := l.Child.Eval(ctx, row)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	return l.typ.Convert(v)
+}
@@ -0,0 +1,63 @@
+package sqlite
+
+import "testing"
+
+func TestExactDecimalLiterals(t *testing.T) {
+	tests := []struct {
+		query     string
+		rewritten string
+		ok        bool
+	}{
+		{
+			"SELECT 1.5 + 0.1",
+			"SELECT 1.5 + 0.1",
+			false,
+		},
+		{
+			"SELECT 12345678901234.5678",
+			"SELECT mysqlite_decimal('12345678901234.5678')",
+			true,
+		},
+		{
+			"SELECT 12345678901234.5678 FROM t",
+			"SELECT mysqlite_decimal('12345678901234.5678') FROM t",
+			true,
+		},
+		{
+			"SELECT -12345678901234.5678, 0.5",
+			"SELECT -mysqlite_decimal('12345678901234.5678'), 0.5",
+			true,
+		},
+		{
+			"INSERT INTO t VALUES (1.00000000000000001,2.00000000000000002)",
+			"INSERT INTO t VALUES (mysqlite_decimal('1.00000000000000001'),mysqlite_decimal('2.00000000000000002'))",
+			true,
+		},
+		{
+			"SELECT 'héllo wörld', 12345678901234.5678",
+			"SELECT 'héllo wörld', mysqlite_decimal('12345678901234.5678')",
+			true,
+		},
+		{
+			"SELECT * FROM `tâble` WHERE `prîce` = 12345678901234.5678 AND name = '日本語'",
+			"SELECT * FROM `tâble` WHERE `prîce` = mysqlite_decimal('12345678901234.5678') AND name = '日本語'",
+			true,
+		},
+		{
+			"SELECT '12345678901234.5678', 1.2345678901234567e3",
+			"SELECT '12345678901234.5678', 1.2345678901234567e3",
+			false,
+		},
+		{
+			"SELECT /*!80000 12345678901234.5678 */ 1",
+			"SELECT /*!80000 12345678901234.5678 */ 1",
+			false,
+		},
+	}
+	for _, tt := range tests {
+		rewritten, ok := ExactDecimalLiterals(tt.query)
+		if rewritten != tt.rewritten || ok != tt.ok {
+			t.Errorf("ExactDecimalLiterals(%q) = %q, %v, want %q, %v", tt.query, rewritten, ok, tt.rewritten, tt.ok)
+		}
+	}
+}
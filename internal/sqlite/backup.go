@@ -0,0 +1,97 @@
+package sqlite
+
+import (
+	"context"
+	stdsql "database/sql"
+	"fmt"
+	"os"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+)
+
+// backupStepPages is how many pages a backup copies at a time. Progress is
+// reported, and cancellation checked, between steps.
+const backupStepPages = 1024
+
+// BackupProgress reports how far a backup has got, in database pages.
+type BackupProgress struct {
+	Remaining int
+	Total     int
+}
+
+// Backup copies the database, including its mysqlite metadata but not
+// temporary tables, to a new SQLite database at path using SQLite's online
+// backup API. The copy is of a single snapshot of the database, which the
+// backup's connection holds a read transaction on throughout, so writes on
+// other connections don't restart it. In WAL mode they carry on while the
+// backup runs; otherwise they wait for it. progress, if not nil, is called
+// after each step. If ctx is cancelled or the backup fails, the partial copy
+// is removed.
+func (db *Database) Backup(ctx context.Context, path string, progress func(BackupProgress)) (err error) {
+	if _, err := os.Stat(path); err == nil {
+		return errors.Errorf("File '%s' already exists", path)
+	}
+
+	dest, err := stdsql.Open(db.driver, path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := dest.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(path)
+		}
+	}()
+
+	dconn, err := dest.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer dconn.Close()
+	sconn, err := db.r.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer sconn.Close()
+
+	// A backup step outside a transaction reads the database as it is then,
+	// and starts over if it has changed since the last step. Reading within
+	// one keeps every step on the snapshot the first read sees.
+	if _, err := sconn.ExecContext(ctx, `BEGIN`); err != nil {
+		return err
+	}
+	defer sconn.ExecContext(context.Background(), `ROLLBACK`)
+	var n int
+	if err := sconn.QueryRowContext(ctx, `SELECT count(*) FROM main.sqlite_master`).Scan(&n); err != nil {
+		return err
+	}
+
+	return dconn.Raw(func(d interface{}) error {
+		return sconn.Raw(func(s interface{}) error {
+			bk, err := d.(*sqlite3.SQLiteConn).Backup("main", s.(*sqlite3.SQLiteConn), "main")
+			if err != nil {
+				return err
+			}
+			for {
+				done, err := bk.Step(backupStepPages)
+				if err != nil {
+					bk.Close()
+					return fmt.Errorf("backup to %s: %s", path, err)
+				}
+				if progress != nil {
+					progress(BackupProgress{Remaining: bk.Remaining(), Total: bk.PageCount()})
+				}
+				if done {
+					return bk.Close()
+				}
+				if err := ctx.Err(); err != nil {
+					bk.Close()
+					return err
+				}
+			}
+		})
+	})
+}
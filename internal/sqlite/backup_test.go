@@ -0,0 +1,130 @@
+package sqlite
+
+import (
+	"context"
+	stdsql "database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBackupWhileWriting(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mysqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	opts := DefaultOptions()
+	opts.JournalMode = "WAL"
+	opts.BusyTimeout = 5 * time.Second
+	db, err := NewDatabaseWithOptions("mydb", filepath.Join(dir, "mydb.db"), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// Enough rows for the backup to take several steps
+	const rows = 4000
+	if _, err := db.w.Exec(`CREATE TABLE events (rowtime INTEGER PRIMARY KEY, msg TEXT)`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.w.Exec(`INSERT INTO main.mysqlite_table_schema (source, cid, name, type, pk) VALUES ('events', 0, 'rowtime', 'INT64', true), ('events', 1, 'msg', 'TEXT', false)`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.w.Exec(`WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < ?) INSERT INTO events (msg) SELECT ? FROM n`, rows, strings.Repeat("x", 4096)); err != nil {
+		t.Fatal(err)
+	}
+
+	insert := func() error {
+		_, err := db.w.Exec(`INSERT INTO events (msg) VALUES ('during')`)
+		return err
+	}
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if err := insert(); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	// A backup that restarts never finishes, so give up on it
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var steps []BackupProgress
+	path := filepath.Join(dir, "copy.db")
+	err = db.Backup(ctx, path, func(p BackupProgress) {
+		if steps = append(steps, p); len(steps) > 100 {
+			cancel()
+		}
+		// Another connection writes between every step
+		if err := insert(); err != nil {
+			t.Error(err)
+		}
+	})
+	close(stop)
+	wg.Wait()
+	if err != nil {
+		t.Fatalf("backup after %d steps: %v", len(steps), err)
+	}
+
+	if len(steps) < 2 {
+		t.Fatalf("backup took %d steps, want several", len(steps))
+	}
+	for i := 1; i < len(steps); i++ {
+		if steps[i].Remaining >= steps[i-1].Remaining {
+			t.Fatalf("backup restarted: %+v", steps)
+		}
+	}
+
+	copied, err := stdsql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer copied.Close()
+	var columns, n int
+	if err := copied.QueryRow(`SELECT count(*) FROM mysqlite_table_schema WHERE source = 'events'`).Scan(&columns); err != nil {
+		t.Fatal(err)
+	}
+	if columns != 2 {
+		t.Errorf("copy has %d mysqlite_table_schema rows for events, want 2", columns)
+	}
+	// The copy is of the database as the backup began
+	if err := copied.QueryRow(`SELECT count(*) FROM events`).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n < rows {
+		t.Errorf("copy has %d rows, want at least %d", n, rows)
+	}
+}
+
+func TestBackupRefusesExistingFile(t *testing.T) {
+	db, ctx := testDatabase(t)
+	f, err := ioutil.TempFile("", "mysqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	if err := db.Backup(ctx, f.Name(), nil); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("Backup over an existing file: %v", err)
+	}
+	if _, err := os.Stat(f.Name()); err != nil {
+		t.Errorf("existing file removed: %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package sqlite
+
+import (
+	"unicode/utf8"
+
+	"github.com/liquidata-inc/go-mysql-server/sql"
+	"github.com/liquidata-inc/go-mysql-server/sql/analyzer"
+	"github.com/liquidata-inc/go-mysql-server/sql/plan"
+	"github.com/liquidata-inc/vitess/go/sqltypes"
+)
+
+// charType is a CHAR or VARCHAR type that counts its length in characters, as
+// MySQL does. The engine's string types count bytes, so they refuse 'héllo'
+// for a VARCHAR(5). Columns keep the engine's types, so that expressions over
+// them work as the engine expects, and charType only stands in where values
+// are converted to a column's type: when they're written, and when they're
+// sent to the client.
+type charType struct {
+	sql.StringType
+}
+
+// charTyped returns t as a charType if it's a CHAR or VARCHAR type, and t
+// otherwise.
+func charTyped(t sql.Type) sql.Type {
+	if _, ok := t.(charType); ok {
+		return t
+	}
+	if st, ok := t.(sql.StringType); ok && (t.Type() == sqltypes.Char || t.Type() == sqltypes.VarChar) {
+		return charType{st}
+	}
+	return t
+}
+
+func (t charType) Convert(v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	s, err := sql.LongText.Convert(v)
+	if err != nil {
+		return nil, err
+	}
+	if int64(utf8.RuneCountInString(s.(string))) > t.MaxCharacterLength() {
+		return nil, sql.ErrLengthBeyondLimit.New()
+	}
+	return s, nil
+}
+
+func (t charType) MustConvert(v interface{}) interface{} {
+	value, err := t.Convert(v)
+	if err != nil {
+		panic(err)
+	}
+	return value
+}
+
+func (t charType) SQL(v interface{}) (sqltypes.Value, error) {
+	if v == nil {
+		return sqltypes.NULL, nil
+	}
+	s, err := t.Convert(v)
+	if err != nil {
+		return sqltypes.Value{}, err
+	}
+	return sqltypes.MakeTrusted(t.Type(), []byte(s.(string))), nil
+}
+
+// charTypedSchema returns schema with its CHAR and VARCHAR columns' charTypes.
+func charTypedSchema(schema sql.Schema) sql.Schema {
+	typed := make(sql.Schema, len(schema))
+	for i, col := range schema {
+		c := *col
+		c.Type = charTyped(c.Type)
+		typed[i] = &c
+	}
+	return typed
+}
+
+// CountCharacters is an analyzer rule that has the engine convert CHAR and
+// VARCHAR values to their charType where the engine's types would refuse those
+// longer in bytes than in characters: when an INSERT writes them to a table,
+// and when they're sent to the client. Add it with
+// analyzer.Builder.AddPostAnalyzeRule.
+func CountCharacters(ctx *sql.Context, a *analyzer.Analyzer, n sql.Node, scope *analyzer.Scope) (sql.Node, error) {
+	n, err := plan.TransformUp(n, func(n sql.Node) (sql.Node, error) {
+		insert, ok := n.(*plan.InsertInto)
+		if !ok {
+			return n, nil
+		}
+		rt, ok := insert.Left.(*plan.ResolvedTable)
+		if !ok {
+			return n, nil
+		}
+		t, ok := rt.Table.(*Table)
+		if !ok || t.charTyped {
+			return n, nil
+		}
+		typed := *t
+		typed.schema = charTypedSchema(t.schema)
+		typed.charTyped = true
+		return insert.WithChildren(plan.NewResolvedTable(&typed), insert.Right)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := n.(*charLengths); ok {
+		return n, nil
+	}
+	for _, col := range n.Schema() {
+		if _, ok := charTyped(col.Type).(charType); ok {
+			return &charLengths{plan.UnaryNode{Child: n}}, nil
+		}
+	}
+	return n, nil
+}
+
+// charLengths is a query whose CHAR and VARCHAR results have their charType.
+type charLengths struct {
+	plan.UnaryNode
+}
+
+var _ sql.Node = (*charLengths)(nil)
+
+func (n *charLengths) Schema() sql.Schema {
+	return charTypedSchema(n.Child.Schema())
+}
+
+func (n *charLengths) RowIter(ctx *sql.Context, row sql.Row) (sql.RowIter, error) {
+	return n.Child.RowIter(ctx, row)
+}
+
+func (n *charLengths) String() string { return n.Child.String() }
+
+func (n *charLengths) WithChildren(children ...sql.Node) (sql.Node, error) {
+	if len(children) != 1 {
+		return nil, sql.ErrInvalidChildrenNumber.New(n, len(children), 1)
+	}
+	return &charLengths{plan.UnaryNode{Child: children[0]}}, nil
+}
@@ -0,0 +1,122 @@
+package sqlite
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// collations are the MySQL collations registered as SQLite collating functions
+// on every connection, so that text columns declared with them compare and sort
+// the same in SQLite as in MySQL. Columns with other collations use SQLite's
+// BINARY.
+var collations = []string{
+	"utf8mb4_0900_ai_ci",
+	"utf8mb4_0900_as_ci",
+	"utf8mb4_0900_as_cs",
+	"utf8mb4_0900_bin",
+	"utf8mb4_general_ci",
+	"utf8mb4_unicode_ci",
+	"utf8mb4_unicode_520_ci",
+	"utf8mb4_bin",
+	"utf8mb3_general_ci",
+	"utf8mb3_unicode_ci",
+	"utf8mb3_unicode_520_ci",
+	"utf8mb3_bin",
+	"latin1_general_ci",
+	"latin1_general_cs",
+	"latin1_swedish_ci",
+	"latin1_bin",
+	"ascii_general_ci",
+	"ascii_bin",
+}
+
+// sqliteCollation returns the COLLATE clause for a text column with the given
+// MySQL collation, or an empty string if SQLite's BINARY is used.
+func sqliteCollation(collation string) string {
+	for _, c := range collations {
+		if c == collation {
+			return ` COLLATE "` + c + `"`
+		}
+	}
+	return ""
+}
+
+func registerCollations(conn *sqlite3.SQLiteConn) error {
+	for _, c := range collations {
+		if err := conn.RegisterCollation(c, collationFunc(c)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collationFunc returns the comparison for a MySQL collation, derived from its
+// name: _ci collations ignore case and, unless _as_, accents; _cs collations
+// order case variants of a letter next to each other; and all but the 0900
+// collations ignore trailing spaces.
+func collationFunc(collation string) func(a, b string) int {
+	var (
+		bin     = strings.HasSuffix(collation, "_bin")
+		ci      = strings.HasSuffix(collation, "_ci")
+		accents = ci && !strings.Contains(collation, "_as_")
+		pad     = !strings.Contains(collation, "_0900_")
+	)
+	return func(a, b string) int {
+		if pad {
+			a, b = strings.TrimRight(a, " "), strings.TrimRight(b, " ")
+		}
+		if bin {
+			return strings.Compare(a, b)
+		}
+		if c := strings.Compare(foldString(a, accents), foldString(b, accents)); c != 0 || ci {
+			return c
+		}
+		return strings.Compare(a, b)
+	}
+}
+
+// foldString maps s to lower case, and its accented Latin letters to the
+// unaccented letter if accents is set.
+func foldString(s string, accents bool) string {
+	return strings.Map(func(r rune) rune {
+		r = unicode.ToLower(r)
+		if accents {
+			if f, ok := accentFolds[r]; ok {
+				return f
+			}
+		}
+		return r
+	}, s)
+}
+
+var accentFolds = func() map[rune]rune {
+	folds := map[rune]rune{}
+	for base, accented := range map[rune]string{
+		'a': "àáâãäåāăą",
+		'c': "çćĉċč",
+		'd': "ďđ",
+		'e': "èéêëēĕėęě",
+		'g': "ĝğġģ",
+		'h': "ĥħ",
+		'i': "ìíîïĩīĭįı",
+		'j': "ĵ",
+		'k': "ķ",
+		'l': "ĺļľŀł",
+		'n': "ñńņňŉ",
+		'o': "òóôõöøōŏő",
+		'r': "ŕŗř",
+		's': "śŝşš",
+		't': "ţťŧ",
+		'u': "ùúûüũūŭůűų",
+		'w': "ŵ",
+		'y': "ýÿŷ",
+		'z': "źżž",
+	} {
+		for _, r := range accented {
+			folds[r] = base
+		}
+	}
+	return folds
+}()
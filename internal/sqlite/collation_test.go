@@ -0,0 +1,95 @@
+package sqlite
+
+import (
+	"reflect"
+	"testing"
+
+	sqle "github.com/liquidata-inc/go-mysql-server"
+	"github.com/liquidata-inc/go-mysql-server/sql"
+)
+
+func TestCollationFunc(t *testing.T) {
+	tests := []struct {
+		collation string
+		a, b      string
+		want      int
+	}{
+		{"utf8mb4_general_ci", "abc", "ABC", 0},
+		{"utf8mb4_general_ci", "abc", "Abd", -1},
+		{"utf8mb4_general_ci", "résumé", "RESUME", 0},
+		{"utf8mb4_general_ci", "abc  ", "ABC", 0},
+		{"utf8mb4_0900_ai_ci", "abc  ", "abc", 1},
+		{"utf8mb4_0900_as_ci", "résumé", "RÉSUMÉ", 0},
+		{"utf8mb4_0900_as_ci", "résumé", "resume", 1},
+		{"utf8mb4_0900_as_cs", "abc", "ABC", 1},
+		{"utf8mb4_0900_as_cs", "ABD", "abc", 1},
+		{"latin1_general_cs", "a", "B", -1},
+		{"utf8mb4_bin", "B", "a", -1},
+		{"utf8mb4_bin", "a ", "a", 0},
+		{"utf8mb4_0900_bin", "a ", "a", 1},
+	}
+	for _, tt := range tests {
+		if got := collationFunc(tt.collation)(tt.a, tt.b); got != tt.want {
+			t.Errorf("%s: compare(%q, %q) = %d, want %d", tt.collation, tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestCollations(t *testing.T) {
+	db, ctx := testDatabase(t,
+		`CREATE TABLE words (id INT, ci VARCHAR(10) COLLATE utf8mb4_general_ci, bin VARCHAR(10) COLLATE utf8mb4_bin)`,
+		`INSERT INTO words (id, ci, bin) VALUES (1, 'abc', 'abc'), (2, 'Abb', 'Abb'), (3, 'ABD', 'ABD')`,
+	)
+
+	// SQLite compares and sorts the columns by their collations
+	for _, tt := range []struct {
+		query string
+		want  []string
+	}{
+		{`SELECT ci FROM words ORDER BY ci`, []string{"Abb", "abc", "ABD"}},
+		{`SELECT bin FROM words ORDER BY bin`, []string{"ABD", "Abb", "abc"}},
+		{`SELECT ci FROM words WHERE ci = 'ABC'`, []string{"abc"}},
+		{`SELECT bin FROM words WHERE bin = 'ABC'`, nil},
+	} {
+		var got []string
+		rows, err := db.r.Query(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for rows.Next() {
+			var s string
+			if err := rows.Scan(&s); err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, s)
+		}
+		rows.Close()
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.query, got, tt.want)
+		}
+	}
+
+	// A unique index treats values the collation finds equal as duplicates
+	e := sqle.NewDefault()
+	e.AddDatabase(db)
+	for _, stmt := range []string{
+		`CREATE UNIQUE INDEX ci_unique ON words (ci)`,
+		`CREATE UNIQUE INDEX bin_unique ON words (bin)`,
+		`INSERT INTO words (id, ci, bin) VALUES (4, 'a', 'a')`,
+		`INSERT INTO words (id, ci, bin) VALUES (5, 'b', 'A')`,
+	} {
+		if _, iter, err := e.Query(ctx, stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		} else if _, err := sql.RowIterToRows(iter); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	stmt := `INSERT INTO words (id, ci, bin) VALUES (6, 'A', 'c')`
+	_, iter, err := e.Query(ctx, stmt)
+	if err == nil {
+		_, err = sql.RowIterToRows(iter)
+	}
+	if err == nil {
+		t.Errorf("%s: inserted a duplicate of 'a'", stmt)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/liquidata-inc/go-mysql-server/sql"
 	"github.com/liquidata-inc/vitess/go/sqltypes"
@@ -15,11 +16,17 @@ import (
 )
 
 type Database struct {
-	name string
-	w    *stdsql.DB
-	r    *stdsql.DB
+	name   string
+	dsn    string
+	driver string
+	w      *stdsql.DB
+	r      *stdsql.DB
 
-	schemas map[string]sql.Schema
+	schemas   map[string]sql.Schema
+	generated map[string][]*generatedColumn // keyed like schemas, aligned with the schema
+
+	mu       sync.Mutex
+	sessions map[uint32]*session // keyed by session id, holds temporary tables
 }
 
 var (
@@ -31,8 +38,114 @@ var (
 	// _ sql.TableRenamer = (*Database)(nil)
 )
 
+// tableSchemaDDL creates the table tracking mysql-specific column metadata in the
+// given SQLite schema, which is "main" for permanent tables and "temp" for
+// temporary ones.
+const tableSchemaDDL = `CREATE TABLE IF NOT EXISTS %s.mysqlite_table_schema (
+	source TEXT, -- table name
+	cid INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	type TEXT NOT NULL,
+	pk INTEGER NOT NULL DEFAULT false, -- boolean
+	nullable INTEGER NOT NULL DEFAULT true, -- boolean
+	dflt_value BLOB,
+	comment TEXT,
+	num_unsigned INTEGER,  -- boolean
+	num_length INTEGER,
+	num_scale INTEGER,
+	txt_charset TEXT,
+	txt_collate TEXT,
+	enum_vals TEXT, -- json array of strings
+	gen_expr TEXT, -- generation expression, in mysql syntax
+	gen_stored INTEGER, -- boolean
+	gen_native INTEGER, -- boolean, whether sqlite maintains the generated column
+	num_offset INTEGER, -- boolean, whether values are stored offset by -2^63 to fit a signed integer
+	num_text INTEGER -- boolean, whether decimals are stored as text that sorts like their numbers
+)`
+
+// tableSchemaMigrations are the columns added to mysqlite_table_schema since it
+// was first released, which are missing from older database files.
+var tableSchemaMigrations = []string{
+	"gen_expr TEXT",
+	"gen_stored INTEGER",
+	"gen_native INTEGER",
+	"num_offset INTEGER",
+	"num_text INTEGER",
+}
+
+// dataMigrations convert the rows of older database files to a new storage
+// format, in the same transaction that adds the mysqlite_table_schema column
+// recording it.
+var dataMigrations = map[string]func(tx *stdsql.Tx) error{
+	"num_offset": offsetUint64Columns,
+	"num_text":   encodeDecimalColumns,
+}
+
+// migrateTableSchema adds the columns of tableSchemaMigrations to an existing
+// main.mysqlite_table_schema that lacks them.
+func migrateTableSchema(db *stdsql.DB) error {
+	rows, err := db.Query(`SELECT name FROM pragma_table_info('mysqlite_table_schema', 'main')`)
+	if err != nil {
+		return err
+	}
+	have := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		have[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, col := range tableSchemaMigrations {
+		name := strings.Fields(col)[0]
+		if have[name] {
+			continue
+		}
+		err := inTx(context.Background(), db, func(tx *stdsql.Tx) error {
+			if _, err := tx.Exec(`ALTER TABLE main.mysqlite_table_schema ADD COLUMN ` + col); err != nil {
+				return err
+			}
+			if migrate, ok := dataMigrations[name]; ok {
+				return migrate(tx)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// conn is the subset of *stdsql.DB used to run statements. It is also satisfied
+// by *stdsql.Conn, which lets temporary tables be served from the one connection
+// their session is pinned to.
+type conn interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (stdsql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*stdsql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *stdsql.Row
+	BeginTx(ctx context.Context, opts *stdsql.TxOptions) (*stdsql.Tx, error)
+}
+
+// NewDatabase opens the SQLite database at dsn with DefaultOptions.
 func NewDatabase(name, dsn string) (*Database, error) {
-	w, err := stdsql.Open("sqlite3", dsn)
+	return NewDatabaseWithOptions(name, dsn, DefaultOptions())
+}
+
+// NewDatabaseWithOptions opens the SQLite database at dsn, applying opts to every
+// connection it opens.
+func NewDatabaseWithOptions(name, dsn string, opts Options) (*Database, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	driver := registerDriver(opts)
+
+	w, err := stdsql.Open(driver, dsn)
 	if err != nil {
 		return nil, err
 	}
@@ -41,43 +154,69 @@ func NewDatabase(name, dsn string) (*Database, error) {
 	w.SetMaxIdleConns(1)
 	w.SetConnMaxLifetime(-1)
 
-	if _, err := w.Exec(
-		`CREATE TABLE IF NOT EXISTS mysqlite_table_schema (
-			source TEXT, -- table name
-			cid INTEGER NOT NULL,
-			name TEXT NOT NULL,
-			type TEXT NOT NULL,
-			pk INTEGER NOT NULL DEFAULT false, -- boolean
-			nullable INTEGER NOT NULL DEFAULT true, -- boolean
-			dflt_value BLOB,
-			comment TEXT,
-			num_unsigned INTEGER,  -- boolean
-			num_length INTEGER,
-			num_scale INTEGER,
-			txt_charset TEXT,
-			txt_collate TEXT,
-			enum_vals TEXT -- json array of strings
-		)`,
-	); err != nil {
+	if _, err := w.Exec(fmt.Sprintf(tableSchemaDDL, "main")); err != nil {
+		return nil, err
+	}
+	if err := migrateTableSchema(w); err != nil {
+		return nil, err
+	}
+	if _, err := w.Exec(fmt.Sprintf(tableOptionsDDL, "main")); err != nil {
+		return nil, err
+	}
+	if _, err := w.Exec(fmt.Sprintf(tableIndexesDDL, "main")); err != nil {
 		return nil, err
 	}
 
-	r, err := stdsql.Open("sqlite3", dsn)
+	r, err := stdsql.Open(driver, dsn)
 	if err != nil {
 		return nil, err
 	}
 	// sqlite3 allows concurrent readers
-	r.SetMaxOpenConns(50)
-	r.SetMaxIdleConns(10)
+	r.SetMaxOpenConns(opts.MaxReaders)
+	r.SetMaxIdleConns(opts.MaxIdleReaders)
 	r.SetConnMaxLifetime(-1)
 	return &Database{
-		name:    name,
-		w:       w,
-		r:       r,
-		schemas: map[string]sql.Schema{},
+		name:      name,
+		dsn:       dsn,
+		driver:    driver,
+		w:         w,
+		r:         r,
+		schemas:   map[string]sql.Schema{},
+		generated: map[string][]*generatedColumn{},
+		sessions:  map[uint32]*session{},
 	}, nil
 }
 
+// Close drops the temporary tables of every session, checkpoints the
+// write-ahead log into the database file and closes both connection pools.
+// Statements still running finish first. The database can't be used after.
+func (db *Database) Close() error {
+	db.mu.Lock()
+	ids := make([]uint32, 0, len(db.sessions))
+	for id := range db.sessions {
+		ids = append(ids, id)
+	}
+	db.mu.Unlock()
+
+	var firstErr error
+	for _, id := range ids {
+		if err := db.EndSession(id); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if err := db.r.Close(); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	// Outside WAL mode there's no log, and this does nothing
+	if _, err := db.w.Exec(`PRAGMA main.wal_checkpoint(TRUNCATE)`); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	if err := db.w.Close(); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	return firstErr
+}
+
 func (db *Database) Name() string {
 	return db.name
 }
@@ -85,29 +224,72 @@ func (db *Database) Name() string {
 func (db *Database) GetTableInsensitive(ctx *sql.Context, tblName string) (table sql.Table, ok bool, err error) {
 	tblName = strings.ToLower(tblName)
 
+	// Temporary tables shadow permanent tables of the same name
+	if s := db.session(ctx); s != nil {
+		if ss, ok := s.schemas[tblName]; ok {
+			return &Table{
+				name:      tblName,
+				schema:    ss,
+				generated: s.generated[tblName],
+				dbw:       s.conn,
+				dbr:       s.conn,
+				temporary: true,
+			}, true, nil
+		}
+	}
+
 	ss, ok := db.schemas[tblName]
 	if ok {
 		return &Table{
-			name:   tblName,
-			schema: ss,
-			dbw:    db.w,
-			dbr:    db.r,
+			name:      tblName,
+			schema:    ss,
+			generated: db.generated[tblName],
+			dbw:       db.w,
+			dbr:       db.r,
 		}, true, nil
 	}
 
-	rows, err := db.r.QueryContext(ctx,
+	schema, generated, err := loadSchema(ctx, db.r, "main", tblName)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(schema) == 0 {
+		return nil, false, nil
+	}
+
+	db.schemas[tblName] = schema
+	db.generated[tblName] = generated
+
+	return &Table{
+		name:      tblName,
+		schema:    schema,
+		generated: generated,
+		dbw:       db.w,
+		dbr:       db.r,
+	}, true, nil
+}
+
+// loadSchema reads a table's schema back out of the mysqlite_table_schema table
+// in the given SQLite schema, along with its generated columns. An unknown table
+// has an empty schema.
+func loadSchema(ctx *sql.Context, c conn, schemaName, tblName string) (sql.Schema, []*generatedColumn, error) {
+	rows, err := c.QueryContext(ctx,
 		`SELECT 
-			name, type, pk, nullable, dflt_value, comment, num_unsigned, num_length, num_scale, txt_charset, txt_collate, enum_vals 
+			name, type, pk, nullable, dflt_value, comment, num_unsigned, num_length, num_scale, txt_charset, txt_collate, enum_vals, gen_expr, gen_stored, gen_native 
 		FROM
-			mysqlite_table_schema WHERE source = "`+tblName+`"
+			`+schemaName+`.mysqlite_table_schema WHERE source = "`+tblName+`"
 		ORDER BY
 			cid`,
 	)
 	if err != nil {
-		return nil, false, err
+		return nil, nil, err
 	}
 
-	var schema sql.Schema
+	var (
+		schema    sql.Schema
+		generated []*generatedColumn
+	)
 	for rows.Next() {
 		var (
 			name      string
@@ -122,9 +304,12 @@ func (db *Database) GetTableInsensitive(ctx *sql.Context, tblName string) (table
 			charset   stdsql.NullString
 			collate   stdsql.NullString
 			enum      string // json array
+			genExpr   stdsql.NullString
+			genStored stdsql.NullBool
+			genNative stdsql.NullBool
 		)
-		if err := rows.Scan(&name, &typ, &pk, &nullable, &dfltValue, &comment, &unsigned, &length, &scale, &charset, &collate, &enum); err != nil {
-			return nil, false, err
+		if err := rows.Scan(&name, &typ, &pk, &nullable, &dfltValue, &comment, &unsigned, &length, &scale, &charset, &collate, &enum, &genExpr, &genStored, &genNative); err != nil {
+			return nil, nil, err
 		}
 
 		ct := sqlparser.ColumnType{
@@ -149,13 +334,13 @@ func (db *Database) GetTableInsensitive(ctx *sql.Context, tblName string) (table
 		if len(enum) > 0 {
 			var enumVals []string
 			if err := json.Unmarshal([]byte(enum), &enumVals); err != nil {
-				return nil, false, err
+				return nil, nil, err
 			}
 			ct.EnumValues = enumVals
 		}
 		colType, err := ColumnTypeToType(&ct)
 		if err != nil {
-			return nil, false, err
+			return nil, nil, err
 		}
 		col := sql.Column{
 			Name:       name,
@@ -168,25 +353,34 @@ func (db *Database) GetTableInsensitive(ctx *sql.Context, tblName string) (table
 		if dfltValue.Valid {
 			d, err := colType.Convert(dfltValue.String)
 			if err != nil {
-				return nil, false, err
+				return nil, nil, err
 			}
 			col.Default = d
 		}
 		schema = append(schema, &col)
-	}
 
-	if len(schema) == 0 {
-		return nil, false, nil
+		if genExpr.Valid {
+			if generated == nil {
+				generated = make([]*generatedColumn, len(schema)-1, len(schema))
+			}
+			generated = append(generated, &generatedColumn{
+				expr:   genExpr.String,
+				stored: genStored.Bool,
+				native: genNative.Bool,
+			})
+		} else if generated != nil {
+			generated = append(generated, nil)
+		}
 	}
 
-	db.schemas[tblName] = schema
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	if err := resolveGenerated(ctx, schema, generated); err != nil {
+		return nil, nil, err
+	}
 
-	return &Table{
-		name:   tblName,
-		schema: schema,
-		dbw:    db.w,
-		dbr:    db.r,
-	}, true, nil
+	return schema, generated, nil
 }
 
 func (db *Database) GetTableNames(ctx *sql.Context) ([]string, error) {
@@ -209,6 +403,40 @@ func (db *Database) GetTableNames(ctx *sql.Context) ([]string, error) {
 }
 
 func (db *Database) CreateTable(ctx *sql.Context, name string, schema sql.Schema) error {
+	schema, err := withRowtime(name, schema)
+	if err != nil {
+		return err
+	}
+	// The engine creates the table before its indexes, so refuse a SPATIAL one
+	// up front rather than leave the table behind
+	ext := columnExtensionsFromQuery(ctx.Query())
+	if ext.spatialIndex {
+		return errSpatialIndex
+	}
+	schema = withSpatial(schema, ext.spatial)
+	generated, err := withGenerated(ctx, schema, ext.generated)
+	if err != nil {
+		return err
+	}
+
+	return inTx(ctx, db.w, func(tx *stdsql.Tx) error {
+		if err := createTable(tx, "main", name, schema, generated); err != nil {
+			return err
+		}
+		if err := saveTableOptions(tx, "main", name, tableOptionsFromQuery(ctx.Query())); err != nil {
+			return err
+		}
+
+		db.schemas[name] = schema
+		db.generated[name] = generated
+
+		return nil
+	})
+}
+
+// withRowtime validates the rowtime column every mysqlite table carries, adding it
+// to the front of the schema if it is missing.
+func withRowtime(name string, schema sql.Schema) (sql.Schema, error) {
 	rowtimeIndex := schema.IndexOf("rowtime", name)
 	if rowtimeIndex < 0 {
 		schema = append([]*sql.Column{
@@ -224,306 +452,376 @@ func (db *Database) CreateTable(ctx *sql.Context, name string, schema sql.Schema
 	}
 	rowtimeCol := schema[rowtimeIndex]
 	if rowtimeCol.Type.Type() != sqltypes.Int64 {
-		return errors.Errorf("rowtime col must be of type BIGINT")
+		return nil, errors.Errorf("rowtime col must be of type BIGINT")
 	}
 	if !rowtimeCol.Nullable {
-		return errors.Errorf("rowtime col must be nullable ")
+		return nil, errors.Errorf("rowtime col must be nullable ")
 	}
 
 	if !rowtimeCol.PrimaryKey {
-		return errors.Errorf("rowtime col must be a primary key")
+		return nil, errors.Errorf("rowtime col must be a primary key")
 	}
 
-	return inTx(ctx, db.w, func(tx *stdsql.Tx) error {
-		var (
-			defs []string
-			pks  []string
-		)
+	return schema, nil
+}
 
-		type columnDefinition struct {
-			Name         string
-			Type         string
-			Affinity     string
-			PK           bool
-			Nullable     bool
-			Comment      string
-			DefaultValue *string // formatted for CREATE TABLE syntax
-			NumUnsigned  *bool
-			NumLength    *int64
-			NumScale     *int64
-			TxtCharset   *string
-			TxtCollate   *string
-			EnumVals     string // json array of strings
-		}
+// createTable creates the table in the given SQLite schema and records its
+// mysql-specific column metadata alongside it. generated is either nil or holds
+// the generated column, if any, at each index of schema.
+func createTable(tx *stdsql.Tx, schemaName, name string, schema sql.Schema, generated []*generatedColumn) error {
+	var (
+		defs []string
+		pks  []string
+	)
 
-		for cid, col := range schema {
+	type columnDefinition struct {
+		Name         string
+		Type         string
+		Affinity     string
+		PK           bool
+		Nullable     bool
+		Comment      string
+		DefaultValue *string // formatted for CREATE TABLE syntax
+		NumUnsigned  *bool
+		NumLength    *int64
+		NumScale     *int64
+		TxtCharset   *string
+		TxtCollate   *string
+		EnumVals     string // json array of strings
+		GenExpr      *string
+		GenStored    *bool
+		GenNative    *bool
+		NumOffset    *bool
+		NumText      *bool
+	}
 
-			def := columnDefinition{
-				Name:     col.Name,
-				Type:     col.Type.Type().String(),
-				PK:       col.PrimaryKey,
-				Nullable: col.Nullable,
-				Comment:  col.Comment,
-			}
+	for cid, col := range schema {
 
-			switch t := col.Type.Type(); t {
-			case sqltypes.Int8, sqltypes.Int16, sqltypes.Int24, sqltypes.Int32, sqltypes.Int64,
-				sqltypes.Uint8, sqltypes.Uint16, sqltypes.Uint24, sqltypes.Uint32, sqltypes.Uint64,
-				sqltypes.Float32, sqltypes.Float64:
+		def := columnDefinition{
+			Name:     col.Name,
+			Type:     col.Type.Type().String(),
+			PK:       col.PrimaryKey,
+			Nullable: col.Nullable,
+			Comment:  col.Comment,
+		}
 
-				castedType := col.Type.(sql.NumberType)
-				if castedType.IsFloat() {
-					def.Affinity = "REAL"
-				} else {
-					def.Affinity = "INTEGER"
-				}
-				unsigned := !castedType.IsSigned()
-				def.NumUnsigned = &unsigned
-				if col.Default != nil {
-					d, err := castedType.Convert(col.Default)
-					if err != nil {
-						return err
-					}
-					val := fmt.Sprintf("%v", d)
-					def.DefaultValue = &val
-				}
-			case sqltypes.Char, sqltypes.VarChar,
-				sqltypes.Binary, sqltypes.VarBinary,
-				sqltypes.Blob, sqltypes.Text:
-
-				def.Affinity = "TEXT"
-				castedType := col.Type.(sql.StringType)
-				charset := string(castedType.CharacterSet())
-				if len(charset) > 0 {
-					def.TxtCharset = &charset
-				}
-				length := castedType.MaxCharacterLength()
-				def.NumLength = &length
-				collate := string(castedType.Collation())
-				if len(collate) > 0 {
-					def.TxtCollate = &collate
-				}
-				if col.Default != nil {
-					d, err := castedType.Convert(col.Default)
-					if err != nil {
-						return err
-					}
-					val := fmt.Sprintf("%s", d)
-					def.DefaultValue = &val
-				}
-			case sqltypes.Decimal:
-
-				def.Affinity = "NUMERIC"
-				castedType := col.Type.(sql.DecimalType)
-				length := int64(castedType.Precision())
-				scale := int64(castedType.Scale())
-				def.NumLength = &length
-				def.NumScale = &scale
-				if col.Default != nil {
-					d, err := castedType.Convert(col.Default)
-					if err != nil {
-						return err
-					}
-					val := fmt.Sprintf("%v", d)
-					def.DefaultValue = &val
-				}
-			case sqltypes.Enum:
+		switch t := col.Type.Type(); t {
+		case sqltypes.Int8, sqltypes.Int16, sqltypes.Int24, sqltypes.Int32, sqltypes.Int64,
+			sqltypes.Uint8, sqltypes.Uint16, sqltypes.Uint24, sqltypes.Uint32, sqltypes.Uint64,
+			sqltypes.Float32, sqltypes.Float64:
 
-				def.Affinity = "TEXT"
-				castedType := col.Type.(sql.EnumType)
-				charset := string(castedType.CharacterSet())
-				if len(charset) > 0 {
-					def.TxtCharset = &charset
-				}
-				collate := string(castedType.Collation())
-				if len(collate) > 0 {
-					def.TxtCollate = &collate
-				}
-				b, err := json.Marshal(castedType.Values())
+			castedType := col.Type.(sql.NumberType)
+			if castedType.IsFloat() {
+				def.Affinity = "REAL"
+			} else {
+				def.Affinity = "INTEGER"
+			}
+			unsigned := !castedType.IsSigned()
+			def.NumUnsigned = &unsigned
+			if isOffset(castedType) {
+				offset := true
+				def.NumOffset = &offset
+			}
+			if col.Default != nil {
+				d, err := castedType.Convert(col.Default)
 				if err != nil {
 					return err
 				}
-				def.EnumVals = string(b)
-
-				if col.Default != nil {
-					d, err := castedType.Convert(col.Default)
-					if err != nil {
-						return err
-					}
-					val := fmt.Sprintf("%s", d)
-					def.DefaultValue = &val
+				val := fmt.Sprintf("%v", d)
+				def.DefaultValue = &val
+			}
+		case sqltypes.Char, sqltypes.VarChar,
+			sqltypes.Binary, sqltypes.VarBinary,
+			sqltypes.Blob, sqltypes.Text:
+
+			def.Affinity = "TEXT"
+			castedType := col.Type.(sql.StringType)
+			charset := string(castedType.CharacterSet())
+			if len(charset) > 0 {
+				def.TxtCharset = &charset
+			}
+			length := castedType.MaxCharacterLength()
+			def.NumLength = &length
+			collate := string(castedType.Collation())
+			if len(collate) > 0 {
+				def.TxtCollate = &collate
+			}
+			if col.Default != nil {
+				d, err := castedType.Convert(col.Default)
+				if err != nil {
+					return err
 				}
-			case sqltypes.Date, sqltypes.Datetime, sqltypes.Timestamp:
-
-				def.Affinity = "TEXT" // Best known way to allow for fractional seconds in SQLite
-				castedType := col.Type.(sql.DatetimeType)
-				if col.Default != nil {
-					d, err := castedType.ConvertWithoutRangeCheck(col.Default)
-					if err != nil {
-						return err
-					}
-					val := d.Format("2006-01-02 15:04:05.999")
-					def.DefaultValue = &val
+				val := fmt.Sprintf("%s", d)
+				def.DefaultValue = &val
+			}
+		case sqltypes.Decimal:
+
+			// Text, since REAL values aren't exact
+			def.Affinity = "TEXT"
+			text := true
+			def.NumText = &text
+			castedType := col.Type.(sql.DecimalType)
+			length := int64(castedType.Precision())
+			scale := int64(castedType.Scale())
+			def.NumLength = &length
+			def.NumScale = &scale
+			if col.Default != nil {
+				d, err := castedType.Convert(col.Default)
+				if err != nil {
+					return err
 				}
-			case sqltypes.Time:
+				val := fmt.Sprintf("%v", d)
+				def.DefaultValue = &val
+			}
+		case sqltypes.Enum:
 
-				def.Affinity = "INTEGER"
-				castedType := col.Type.(sql.TimeType)
-				if col.Default != nil {
-					d, err := castedType.Marshal(col.Default)
-					if err != nil {
-						return err
-					}
-					val := fmt.Sprintf("%d", d)
-					def.DefaultValue = &val
-				}
-			case sqltypes.Year:
+			def.Affinity = "TEXT"
+			castedType := col.Type.(sql.EnumType)
+			charset := string(castedType.CharacterSet())
+			if len(charset) > 0 {
+				def.TxtCharset = &charset
+			}
+			collate := string(castedType.Collation())
+			if len(collate) > 0 {
+				def.TxtCollate = &collate
+			}
+			b, err := json.Marshal(castedType.Values())
+			if err != nil {
+				return err
+			}
+			def.EnumVals = string(b)
 
-				def.Affinity = "INTEGER"
-				castedType := col.Type.(sql.YearType)
-				if col.Default != nil {
-					d, err := castedType.Convert(col.Default)
-					if err != nil {
-						return err
-					}
-					val := fmt.Sprintf("%v", d)
-					def.DefaultValue = &val
+			if col.Default != nil {
+				d, err := castedType.Convert(col.Default)
+				if err != nil {
+					return err
 				}
-			case sqltypes.Set:
+				val := fmt.Sprintf("%s", d)
+				def.DefaultValue = &val
+			}
+		case sqltypes.Date, sqltypes.Datetime, sqltypes.Timestamp:
 
-				def.Affinity = "TEXT"
-				castedType := col.Type.(sql.SetType)
-				charset := string(castedType.CharacterSet())
-				if len(charset) > 0 {
-					def.TxtCharset = &charset
-				}
-				collate := string(castedType.Collation())
-				if len(collate) > 0 {
-					def.TxtCollate = &collate
-				}
-				b, err := json.Marshal(castedType.Values())
+			def.Affinity = "TEXT" // Best known way to allow for fractional seconds in SQLite
+			castedType := col.Type.(sql.DatetimeType)
+			if col.Default != nil {
+				d, err := castedType.ConvertWithoutRangeCheck(col.Default)
 				if err != nil {
 					return err
 				}
-				def.EnumVals = string(b)
-
-				if col.Default != nil {
-					d, err := castedType.Convert(col.Default)
-					if err != nil {
-						return err
-					}
-					val := fmt.Sprintf("%s", d)
-					def.DefaultValue = &val
-				}
-			case sqltypes.Bit:
+				val := d.Format("2006-01-02 15:04:05.999")
+				def.DefaultValue = &val
+			}
+		case sqltypes.Time:
 
-				def.Affinity = "INTEGER"
-				castedType := col.Type.(sql.BitType)
-				if col.Default != nil {
-					d, err := castedType.Convert(col.Default)
-					if err != nil {
-						return err
-					}
-					val := fmt.Sprintf("%d", d)
-					def.DefaultValue = &val
-				}
-			case sqltypes.TypeJSON:
-
-				def.Affinity = "TEXT"
-				castedType := col.Type.(sql.JsonType)
-				if col.Default != nil {
-					d, err := castedType.Convert(col.Default)
-					if err != nil {
-						return err
-					}
-					val := fmt.Sprintf("%v", d)
-					def.DefaultValue = &val
+			def.Affinity = "INTEGER"
+			castedType := col.Type.(sql.TimeType)
+			if col.Default != nil {
+				d, err := castedType.Marshal(col.Default)
+				if err != nil {
+					return err
 				}
-			case sqltypes.Null:
+				val := fmt.Sprintf("%d", d)
+				def.DefaultValue = &val
+			}
+		case sqltypes.Year:
 
-				def.Affinity = "TEXT"
-			case sqltypes.Expression, sqltypes.Geometry:
+			def.Affinity = "INTEGER"
+			castedType := col.Type.(sql.YearType)
+			if col.Default != nil {
+				d, err := castedType.Convert(col.Default)
+				if err != nil {
+					return err
+				}
+				val := fmt.Sprintf("%v", d)
+				def.DefaultValue = &val
+			}
+		case sqltypes.Set:
 
-				def.Affinity = "TEXT"
-			default:
+			def.Affinity = "TEXT"
+			castedType := col.Type.(sql.SetType)
+			charset := string(castedType.CharacterSet())
+			if len(charset) > 0 {
+				def.TxtCharset = &charset
+			}
+			collate := string(castedType.Collation())
+			if len(collate) > 0 {
+				def.TxtCollate = &collate
+			}
+			b, err := json.Marshal(castedType.Values())
+			if err != nil {
+				return err
+			}
+			def.EnumVals = string(b)
 
-				panic("unknown sqltype: " + t.String())
+			if col.Default != nil {
+				d, err := castedType.Convert(col.Default)
+				if err != nil {
+					return err
+				}
+				val := fmt.Sprintf("%s", d)
+				def.DefaultValue = &val
 			}
+		case sqltypes.Bit:
 
-			// These strings are added to the CREATE TABLE statement
-			colDefClause := fmt.Sprintf("%s %s", def.Name, def.Affinity)
-			if def.DefaultValue != nil {
-				colDefClause += fmt.Sprintf(" DEFAULT %q", *def.DefaultValue)
+			def.Affinity = "INTEGER"
+			castedType := col.Type.(sql.BitType)
+			if col.Default != nil {
+				d, err := castedType.Convert(col.Default)
+				if err != nil {
+					return err
+				}
+				val := fmt.Sprintf("%d", d)
+				def.DefaultValue = &val
 			}
-			defs = append(defs, colDefClause)
-			if def.PK {
-				pks = append(pks, def.Name)
+		case sqltypes.TypeJSON:
+
+			def.Affinity = "TEXT"
+			castedType := col.Type.(sql.JsonType)
+			if col.Default != nil {
+				d, err := castedType.Convert(col.Default)
+				if err != nil {
+					return err
+				}
+				val := fmt.Sprintf("%v", d)
+				def.DefaultValue = &val
 			}
+		case sqltypes.Null:
 
-			// Track mysql-specific metadata for each column definition
-			tx.Exec(
-				`INSERT INTO mysqlite_table_schema (
-					source,
-					cid,
-					name,
-					type,
-					pk,
-					nullable,
-					dflt_value,
-					comment,
-					num_unsigned,
-					num_length,
-					num_scale,
-					txt_charset,
-					txt_collate,
-					enum_vals
-				) VALUES (
-					?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?
-				)`,
-				name,
-				cid,
-				def.Name,
-				def.Type,
-				def.PK,
-				def.Nullable,
-				def.DefaultValue,
-				def.Comment,
-				def.NumUnsigned,
-				def.NumLength,
-				def.NumScale,
-				def.TxtCharset,
-				def.TxtCollate,
-				def.EnumVals,
-			)
+			def.Affinity = "TEXT"
+		case sqltypes.Geometry:
+
+			// The MySQL type name distinguishes points, polygons etc.
+			def.Type = col.Type.String()
+			def.Affinity = "BLOB"
+		case sqltypes.Expression:
+
+			def.Affinity = "TEXT"
+		default:
+
+			panic("unknown sqltype: " + t.String())
 		}
 
-		if len(pks) > 0 {
-			defs = append(defs, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(pks, ", ")))
+		// These strings are added to the CREATE TABLE statement
+		colDefClause := fmt.Sprintf("%s %s", def.Name, def.Affinity)
+		if def.TxtCollate != nil {
+			colDefClause += sqliteCollation(*def.TxtCollate)
 		}
-		if _, err := tx.Exec(fmt.Sprintf(`CREATE TABLE "%s" (%s)`, name, strings.Join(defs, ", "))); err != nil {
-			return err
+		if def.DefaultValue != nil {
+			dflt := *def.DefaultValue
+			if isEncoded(col.Type) {
+				d, err := encodeValue(col.Type, dflt)
+				if err != nil {
+					return err
+				}
+				dflt = fmt.Sprintf("%v", d)
+			}
+			colDefClause += fmt.Sprintf(" DEFAULT %q", dflt)
+		}
+		if check := checkConstraint(col); check != "" && (generated == nil || generated[cid] == nil) {
+			colDefClause += " CHECK (" + check + ")"
+		}
+		if generated != nil && generated[cid] != nil {
+			g := generated[cid]
+			def.GenExpr, def.GenStored, def.GenNative = &g.expr, &g.stored, &g.native
+			if g.native {
+				expr, _ := translateExpr(g.expr, schema)
+				colDefClause += fmt.Sprintf(" GENERATED ALWAYS AS (%s)", expr)
+				if g.stored {
+					colDefClause += " STORED"
+				} else {
+					colDefClause += " VIRTUAL"
+				}
+			}
+		}
+		defs = append(defs, colDefClause)
+		if def.PK {
+			pks = append(pks, def.Name)
 		}
 
-		db.schemas[name] = schema
+		// Track mysql-specific metadata for each column definition
+		tx.Exec(
+			`INSERT INTO `+schemaName+`.mysqlite_table_schema (
+				source,
+				cid,
+				name,
+				type,
+				pk,
+				nullable,
+				dflt_value,
+				comment,
+				num_unsigned,
+				num_length,
+				num_scale,
+				txt_charset,
+				txt_collate,
+				enum_vals,
+				gen_expr,
+				gen_stored,
+				gen_native,
+				num_offset,
+				num_text
+			) VALUES (
+				?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?
+			)`,
+			name,
+			cid,
+			def.Name,
+			def.Type,
+			def.PK,
+			def.Nullable,
+			def.DefaultValue,
+			def.Comment,
+			def.NumUnsigned,
+			def.NumLength,
+			def.NumScale,
+			def.TxtCharset,
+			def.TxtCollate,
+			def.EnumVals,
+			def.GenExpr,
+			def.GenStored,
+			def.GenNative,
+			def.NumOffset,
+			def.NumText,
+		)
+	}
 
-		return nil
-	})
+	if len(pks) > 0 {
+		defs = append(defs, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(pks, ", ")))
+	}
+	if _, err := tx.Exec(fmt.Sprintf(`CREATE TABLE %s."%s" (%s)`, schemaName, name, strings.Join(defs, ", "))); err != nil {
+		return err
+	}
+
+	return nil
 }
 
 func (db *Database) DropTable(ctx *sql.Context, name string) error {
+	if s := db.session(ctx); s != nil {
+		if _, ok := s.schemas[strings.ToLower(name)]; ok {
+			return db.DropTemporaryTable(ctx, name)
+		}
+	}
+
 	return inTx(ctx, db.w, func(tx *stdsql.Tx) error {
+		if err := dropTableIndexes(tx, "main", name); err != nil {
+			return err
+		}
 		if _, err := tx.Exec(`DROP TABLE "` + name + `"`); err != nil {
 			return err
 		}
 		if _, err := tx.Exec(`DELETE FROM mysqlite_table_schema WHERE source = "` + name + `"`); err != nil {
 			return err
 		}
+		if _, err := tx.Exec(`DELETE FROM main.mysqlite_table_options WHERE source = ? COLLATE NOCASE`, name); err != nil {
+			return err
+		}
 		delete(db.schemas, name)
+		delete(db.generated, name)
 		return nil
 	})
 }
 
-func inTx(ctx context.Context, db *stdsql.DB, f func(tx *stdsql.Tx) error) error {
+func inTx(ctx context.Context, db conn, f func(tx *stdsql.Tx) error) error {
 	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
@@ -0,0 +1,232 @@
+package sqlite
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/liquidata-inc/go-mysql-server/sql"
+)
+
+// columnExtensions are the parts of column definitions in a CREATE TABLE
+// statement that vitess can't parse, keyed by lower case column name.
+type columnExtensions struct {
+	generated map[string]*generatedColumn
+	spatial   map[string]sql.Type
+	// fulltext holds the FULLTEXT indexes the statement defines, keyed by
+	// lower case index name, or first column if unnamed
+	fulltext map[string]bool
+	// spatialIndex is whether the statement defines a SPATIAL index, which
+	// mysqlite tables don't support
+	spatialIndex bool
+}
+
+// RewriteCreateTable rewrites the column definitions of a CREATE TABLE statement
+// that vitess can't parse into ones it can: generation clauses are removed,
+// spatial types become LONGBLOB and FULLTEXT indexes plain ones. The
+// definitions are recovered from the original statement when the table and its
+// indexes are created.
+func RewriteCreateTable(query string) string {
+	rewritten, _ := scanCreateTable(query)
+	return rewritten
+}
+
+// columnExtensionsFromQuery returns the column definitions of a CREATE TABLE
+// statement that RewriteCreateTable rewrites.
+func columnExtensionsFromQuery(query string) columnExtensions {
+	_, ext := scanCreateTable(query)
+	return ext
+}
+
+func scanCreateTable(query string) (string, columnExtensions) {
+	ext := columnExtensions{
+		generated: map[string]*generatedColumn{},
+		spatial:   map[string]sql.Type{},
+		fulltext:  map[string]bool{},
+	}
+	head, spec, tail, ok := splitTableSpec(query)
+	if !ok {
+		return query, ext
+	}
+
+	defs := splitDefinitions(spec)
+	for i, def := range defs {
+		name := strings.ToLower(columnName(def))
+		def, g := stripGenerated(def)
+		if g != nil {
+			ext.generated[name] = g
+		}
+		def, t := stripSpatial(def)
+		if t != nil {
+			ext.spatial[name] = t
+		}
+		if spatialIndexRegex.MatchString(def) {
+			ext.spatialIndex = true
+		}
+		def, key := stripFulltext(def)
+		if key != "" {
+			ext.fulltext[key] = true
+		}
+		defs[i] = def
+	}
+	return head + strings.Join(defs, ",") + tail, ext
+}
+
+var foreignKeyRegex = regexp.MustCompile(`(?is)^\s*(?:constraint(?:\s+\S+)?\s+)?foreign\s+key\b`)
+
+// StripForeignKeys removes the FOREIGN KEY constraints from a CREATE TABLE
+// statement, which mysqlite tables don't support, returning the statement
+// without them and the constraints it removed.
+func StripForeignKeys(query string) (string, []string) {
+	head, spec, tail, ok := splitTableSpec(query)
+	if !ok {
+		return query, nil
+	}
+	var (
+		defs    []string
+		removed []string
+	)
+	for _, def := range splitDefinitions(spec) {
+		if foreignKeyRegex.MatchString(def) {
+			removed = append(removed, strings.TrimSpace(def))
+			continue
+		}
+		defs = append(defs, def)
+	}
+	if len(removed) == 0 {
+		return query, nil
+	}
+	return head + strings.Join(defs, ",") + tail, removed
+}
+
+var (
+	rowtimeColumnRegex = regexp.MustCompile("(?is)^\\s*`?rowtime`?\\s+bigint\\b")
+	primaryKeyRegex    = regexp.MustCompile(`(?is)^(\s*primary\s+key\s*\()(.*)(\)\s*)$`)
+)
+
+// StripRowtime removes the rowtime column from a CREATE TABLE statement and
+// its PRIMARY KEY, as SHOW CREATE TABLE writes them, so that the table is
+// created with the rowtime column mysqlite adds itself. The engine parses
+// primary key columns as NOT NULL, while rowtime must be nullable to be filled
+// in by inserts that leave it out. Statements declaring rowtime other than as
+// the first column are left alone.
+func StripRowtime(query string) string {
+	head, spec, tail, ok := splitTableSpec(query)
+	if !ok {
+		return query
+	}
+	defs := splitDefinitions(spec)
+	if len(defs) < 2 || !rowtimeColumnRegex.MatchString(defs[0]) {
+		return query
+	}
+	var kept []string
+	for _, def := range defs[1:] {
+		if m := primaryKeyRegex.FindStringSubmatch(def); m != nil {
+			var cols []string
+			for _, col := range strings.Split(m[2], ",") {
+				if !strings.EqualFold(strings.Trim(strings.TrimSpace(col), "`"), "rowtime") {
+					cols = append(cols, col)
+				}
+			}
+			if len(cols) == 0 {
+				continue
+			}
+			def = m[1] + strings.Join(cols, ",") + m[3]
+		}
+		kept = append(kept, def)
+	}
+	return head + strings.Join(kept, ",") + tail
+}
+
+// splitDefinitions splits the parenthesized definitions of a CREATE TABLE
+// statement at the commas between them.
+func splitDefinitions(spec string) []string {
+	var defs []string
+	start := 0
+	scanSQL(spec, func(i, depth int) bool {
+		if spec[i] == ',' && depth == 0 {
+			defs = append(defs, spec[start:i])
+			start = i + 1
+		}
+		return true
+	})
+	return append(defs, spec[start:])
+}
+
+// columnName returns the name of the column a column definition declares.
+func columnName(def string) string {
+	def = strings.TrimSpace(def)
+	if strings.HasPrefix(def, "`") {
+		if i := strings.Index(def[1:], "`"); i >= 0 {
+			return def[1 : i+1]
+		}
+	}
+	if i := strings.IndexFunc(def, func(r rune) bool { return r == ' ' || r == '\t' || r == '\n' || r == '\r' }); i >= 0 {
+		return def[:i]
+	}
+	return def
+}
+
+// splitTableSpec splits a CREATE TABLE statement around its parenthesized column
+// definitions, returning the statement up to and including the opening
+// parenthesis, the definitions, and the rest from the closing parenthesis on.
+func splitTableSpec(query string) (head, spec, tail string, ok bool) {
+	open := -1
+	scanSQL(query, func(i, depth int) bool {
+		switch {
+		case query[i] == '(' && depth == 1 && open < 0:
+			open = i
+		case query[i] == ')' && depth == 0 && open >= 0:
+			head, spec, tail, ok = query[:open+1], query[open+1:i], query[i:], true
+			return false
+		}
+		return true
+	})
+	return head, spec, tail, ok
+}
+
+// scanSQL calls f with the index of every byte of s outside quoted strings and
+// identifiers, along with the parenthesis depth at that byte, counting the
+// parenthesis itself. Scanning stops once f returns false.
+func scanSQL(s string, f func(i, depth int) bool) {
+	var (
+		depth int
+		quote byte
+	)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if quote != 0 {
+			if c == '\\' && quote != '`' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch c {
+		case '\'', '"', '`':
+			quote = c
+			continue
+		case '(':
+			depth++
+		case ')':
+			depth--
+		}
+		if !f(i, depth) {
+			return
+		}
+	}
+}
+
+// closingParen returns the index of the parenthesis closing the one at open,
+// or -1 if there is none.
+func closingParen(s string, open int) int {
+	end := -1
+	scanSQL(s[open:], func(i, depth int) bool {
+		if depth == 0 {
+			end = open + i
+			return false
+		}
+		return true
+	})
+	return end
+}
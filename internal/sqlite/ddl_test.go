@@ -0,0 +1,31 @@
+package sqlite
+
+import "testing"
+
+func TestStripRowtime(t *testing.T) {
+	tests := []struct {
+		query, stripped string
+	}{
+		{
+			"CREATE TABLE `t` (\n  `rowtime` bigint,\n  `id` int NOT NULL,\n  PRIMARY KEY (`rowtime`,`id`)\n) ENGINE=InnoDB",
+			"CREATE TABLE `t` (\n  `id` int NOT NULL,\n  PRIMARY KEY (`id`)\n) ENGINE=InnoDB",
+		},
+		{
+			"CREATE TABLE `t` (\n  `rowtime` bigint,\n  `id` int,\n  PRIMARY KEY (`rowtime`)\n)",
+			"CREATE TABLE `t` (\n  `id` int)",
+		},
+		{
+			"CREATE TABLE t (id INT, rowtime BIGINT)",
+			"CREATE TABLE t (id INT, rowtime BIGINT)",
+		},
+		{
+			"CREATE TABLE t (id INT PRIMARY KEY)",
+			"CREATE TABLE t (id INT PRIMARY KEY)",
+		},
+	}
+	for _, tt := range tests {
+		if stripped := StripRowtime(tt.query); stripped != tt.stripped {
+			t.Errorf("StripRowtime(%q) = %q, want %q", tt.query, stripped, tt.stripped)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package sqlite
+
+import (
+	stdsql "database/sql"
+	"strings"
+
+	"github.com/liquidata-inc/go-mysql-server/sql"
+	"github.com/liquidata-inc/vitess/go/sqltypes"
+)
+
+// DECIMAL values are stored as text that sorts like their numbers: p for a
+// positive value or n for a negative one, then the value with its integer part
+// padded to the column's width and, if negative, each digit replaced by its
+// nine's complement. 12.5 in a DECIMAL(5,2) column is p012.50 and -12.5 is
+// n987.49.
+
+// encodeDecimal returns the value stored for a value of a DECIMAL type.
+func encodeDecimal(t sql.DecimalType, v interface{}) (interface{}, error) {
+	d, err := t.ConvertToDecimal(v)
+	if err != nil || !d.Valid {
+		return nil, err
+	}
+	s := d.Decimal.Abs().StringFixed(int32(t.Scale()))
+	intDigits := strings.IndexByte(s, '.')
+	if intDigits < 0 {
+		intDigits = len(s)
+	}
+	if width := int(t.Precision() - t.Scale()); intDigits < width {
+		s = strings.Repeat("0", width-intDigits) + s
+	}
+	if d.Decimal.Sign() >= 0 {
+		return "p" + s, nil
+	}
+	return "n" + complementDigits(s), nil
+}
+
+// decodeDecimal returns the value of a DECIMAL type stored as v, which for
+// databases older than the encoding may also be a number.
+func decodeDecimal(t sql.DecimalType, v interface{}) (interface{}, error) {
+	if s, ok := v.(string); ok && s != "" {
+		switch s[0] {
+		case 'p':
+			v = s[1:]
+		case 'n':
+			v = "-" + complementDigits(s[1:])
+		}
+	}
+	return t.Convert(v)
+}
+
+func complementDigits(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if c >= '0' && c <= '9' {
+			b[i] = '9' - c + '0'
+		}
+	}
+	return string(b)
+}
+
+// encodeDecimalColumns encodes the values of the DECIMAL columns of a database
+// created before they were stored encoded, when they were stored as numbers.
+func encodeDecimalColumns(tx *stdsql.Tx) error {
+	rows, err := tx.Query(`SELECT source, name, num_length, num_scale FROM main.mysqlite_table_schema WHERE type = ?`, sqltypes.Decimal.String())
+	if err != nil {
+		return err
+	}
+	type column struct {
+		source, name string
+		t            sql.DecimalType
+	}
+	var cols []column
+	for rows.Next() {
+		var (
+			col              column
+			precision, scale uint8
+		)
+		if err := rows.Scan(&col.source, &col.name, &precision, &scale); err != nil {
+			rows.Close()
+			return err
+		}
+		if col.t, err = sql.CreateDecimalType(precision, scale); err != nil {
+			rows.Close()
+			return err
+		}
+		cols = append(cols, col)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, col := range cols {
+		rows, err := tx.Query(`SELECT rowid, "` + col.name + `" FROM main."` + col.source + `" WHERE "` + col.name + `" IS NOT NULL`)
+		if err != nil {
+			return err
+		}
+		values := map[int64]interface{}{}
+		for rows.Next() {
+			var (
+				rowid int64
+				v     interface{}
+			)
+			if err := rows.Scan(&rowid, &v); err != nil {
+				rows.Close()
+				return err
+			}
+			if values[rowid], err = encodeDecimal(col.t, v); err != nil {
+				rows.Close()
+				return err
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		for rowid, v := range values {
+			if _, err := tx.Exec(`UPDATE main."`+col.source+`" SET "`+col.name+`" = ? WHERE rowid = ?`, v, rowid); err != nil {
+				return err
+			}
+		}
+	}
+	_, err = tx.Exec(`UPDATE main.mysqlite_table_schema SET num_text = true WHERE type = ?`, sqltypes.Decimal.String())
+	return err
+}
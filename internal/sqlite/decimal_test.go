@@ -0,0 +1,136 @@
+package sqlite
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/liquidata-inc/go-mysql-server/sql"
+)
+
+func TestDecimalEncoding(t *testing.T) {
+	maxDigits := strings.Repeat("9", 35) + "." + strings.Repeat("9", 30)
+	tests := []struct {
+		precision, scale uint8
+		value            interface{}
+		stored           interface{}
+		decoded          interface{}
+	}{
+		{5, 2, nil, nil, nil},
+		{5, 2, "12.5", "p012.50", "12.50"},
+		{5, 2, "-12.5", "n987.49", "-12.50"},
+		{5, 2, 12.5, "p012.50", "12.50"},
+		{5, 2, int64(-7), "n992.99", "-7.00"},
+		{5, 2, "0", "p000.00", "0.00"},
+		{5, 2, "-0.001", "p000.00", "0.00"},
+		{5, 2, "-0.01", "n999.98", "-0.01"},
+		{5, 2, "999.99", "p999.99", "999.99"},
+		{5, 2, "-999.99", "n000.00", "-999.99"},
+		{5, 2, "1.005", "p001.01", "1.01"},
+		{5, 0, "12", "p00012", "12"},
+		{5, 0, "-12", "n99987", "-12"},
+		{5, 0, "99999", "p99999", "99999"},
+		{65, 30, maxDigits, "p" + maxDigits, maxDigits},
+		{65, 30, "-" + maxDigits, "n" + strings.Repeat("0", 35) + "." + strings.Repeat("0", 30), "-" + maxDigits},
+		{65, 30, "0.000000000000000000000000000001", "p" + strings.Repeat("0", 35) + "." + strings.Repeat("0", 29) + "1", "0.000000000000000000000000000001"},
+	}
+	for _, tt := range tests {
+		typ := sql.MustCreateDecimalType(tt.precision, tt.scale)
+		stored, err := encodeValue(typ, tt.value)
+		if err != nil {
+			t.Errorf("%s: encodeValue(%v): %v", typ, tt.value, err)
+			continue
+		}
+		if stored != tt.stored {
+			t.Errorf("%s: encodeValue(%v) = %v, want %v", typ, tt.value, stored, tt.stored)
+		}
+		if got, err := decodeValue(typ, stored); err != nil || got != tt.decoded {
+			t.Errorf("%s: decodeValue(%v) = %v, %v, want %v", typ, stored, got, err, tt.decoded)
+		}
+	}
+}
+
+func TestDecimalEncodingOutOfRange(t *testing.T) {
+	typ := sql.MustCreateDecimalType(5, 2)
+	for _, v := range []interface{}{"1000", "-1000", "999.995"} {
+		if stored, err := encodeValue(typ, v); err == nil {
+			t.Errorf("encodeValue(%v) = %v, want an error", v, stored)
+		}
+	}
+}
+
+func TestDecimalEncodingOrder(t *testing.T) {
+	tests := []struct {
+		precision, scale uint8
+		values           []string
+	}{
+		{5, 2, []string{"-999.99", "-100", "-12.5", "-12.49", "-1", "-0.01", "0", "0.01", "1", "9.99", "10", "12.5", "999.99"}},
+		{5, 0, []string{"-99999", "-10000", "-9999", "-10", "-9", "-1", "0", "1", "9", "10", "99999"}},
+		{65, 30, []string{
+			"-" + strings.Repeat("9", 35) + "." + strings.Repeat("9", 30),
+			"-1",
+			"-0.000000000000000000000000000001",
+			"0",
+			"0.000000000000000000000000000001",
+			"1",
+			strings.Repeat("9", 35) + "." + strings.Repeat("9", 30),
+		}},
+	}
+	for _, tt := range tests {
+		typ := sql.MustCreateDecimalType(tt.precision, tt.scale)
+		var stored []string
+		for _, v := range tt.values {
+			s, err := encodeValue(typ, v)
+			if err != nil {
+				t.Fatalf("%s: encodeValue(%s): %v", typ, v, err)
+			}
+			stored = append(stored, s.(string))
+		}
+		if !sort.StringsAreSorted(stored) {
+			t.Errorf("%s: stored values %q don't sort like %q", typ, stored, tt.values)
+		}
+	}
+}
+
+func TestEncodeDecimalColumns(t *testing.T) {
+	path := legacyDatabase(t,
+		`CREATE TABLE prices (id INTEGER, amount DECIMAL(5,2), whole DECIMAL(3,0), ratio REAL)`,
+		`INSERT INTO main.mysqlite_table_schema (source, cid, name, type, num_length, num_scale) VALUES
+			('prices', 0, 'id', 'INT32', NULL, NULL),
+			('prices', 1, 'amount', 'DECIMAL', 5, 2),
+			('prices', 2, 'whole', 'DECIMAL', 3, 0),
+			('prices', 3, 'ratio', 'FLOAT64', NULL, NULL)`,
+		`INSERT INTO prices VALUES (1, 12.5, 7, 0.5), (2, -12.5, -7, 1.5), (3, NULL, NULL, NULL), (4, 0, 0, 0)`,
+	)
+
+	db, err := NewDatabase("legacy", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	tests := []struct {
+		column string
+		stored []interface{}
+	}{
+		{"amount", []interface{}{"p012.50", "n987.49", nil, "p000.00"}},
+		{"whole", []interface{}{"p007", "n992", nil, "p000"}},
+		{"ratio", []interface{}{0.5, 1.5, nil, 0.0}},
+	}
+	for _, tt := range tests {
+		stored := storedValues(t, db, "prices", tt.column)
+		for i, v := range stored {
+			if v != tt.stored[i] {
+				t.Errorf("%s, row %d: stored as %v, want %v", tt.column, i+1, v, tt.stored[i])
+			}
+		}
+	}
+
+	var text bool
+	if err := db.r.QueryRow(`SELECT num_text FROM main.mysqlite_table_schema WHERE name = 'amount'`).Scan(&text); err != nil {
+		t.Fatal(err)
+	}
+	if !text {
+		t.Error("num_text not set for amount")
+	}
+}
@@ -0,0 +1,143 @@
+package sqlite
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/liquidata-inc/go-mysql-server/sql"
+	"github.com/liquidata-inc/vitess/go/sqltypes"
+)
+
+// dumpInsertSize is roughly the largest INSERT statement Dump writes, in bytes.
+// Like mysqldump's default it stays well under MySQL's max_allowed_packet.
+const dumpInsertSize = 1 << 20
+
+// Dump writes the database's tables to w as MySQL statements that recreate
+// them on a MySQL server, in the style of mysqldump: each table's CREATE TABLE
+// statement as SHOW CREATE TABLE gives it, followed by its rows as multi-row
+// INSERT statements. Generated columns are left out of the INSERTs for MySQL
+// to compute.
+func (db *Database) Dump(ctx *sql.Context, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "-- mysqlite dump of database `%s`\n\n", db.name)
+	fmt.Fprintf(bw, "/*!40101 SET NAMES utf8mb4 */;\n")
+	fmt.Fprintf(bw, "/*!40014 SET @OLD_FOREIGN_KEY_CHECKS=@@FOREIGN_KEY_CHECKS, FOREIGN_KEY_CHECKS=0 */;\n")
+
+	names, err := db.GetTableNames(ctx)
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		table, ok, err := db.GetTableInsensitive(ctx, name)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if err := dumpTable(ctx, bw, table.(*Table)); err != nil {
+			return fmt.Errorf("dump of table %s: %s", name, err)
+		}
+	}
+
+	fmt.Fprintf(bw, "\n/*!40014 SET FOREIGN_KEY_CHECKS=@OLD_FOREIGN_KEY_CHECKS */;\n")
+	return bw.Flush()
+}
+
+func dumpTable(ctx *sql.Context, w *bufio.Writer, t *Table) error {
+	stmt, err := t.CreateTableStatement(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "\n--\n-- Table structure for table `%s`\n--\n\n", t.name)
+	fmt.Fprintf(w, "DROP TABLE IF EXISTS `%s`;\n%s;\n", t.name, stmt)
+
+	var (
+		cols  []int
+		names []string
+	)
+	for i, col := range t.schema {
+		if t.generated != nil && t.generated[i] != nil {
+			continue
+		}
+		cols = append(cols, i)
+		names = append(names, "`"+col.Name+"`")
+	}
+	columns := strings.Join(names, ",")
+
+	iter, err := t.PartitionRows(ctx, &partition{key: []byte("0")})
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+
+	fmt.Fprintf(w, "\n--\n-- Dumping data for table `%s`\n--\n\n", t.name)
+	var insert bytes.Buffer
+	flush := func() error {
+		if insert.Len() == 0 {
+			return nil
+		}
+		insert.WriteString(";\n")
+		_, err := insert.WriteTo(w)
+		return err
+	}
+	for {
+		row, err := iter.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if insert.Len() == 0 {
+			fmt.Fprintf(&insert, "INSERT INTO `%s` (%s) VALUES ", t.name, columns)
+		} else {
+			insert.WriteByte(',')
+		}
+		insert.WriteByte('(')
+		for j, i := range cols {
+			if j > 0 {
+				insert.WriteByte(',')
+			}
+			if err := encodeMySQLValue(&insert, charTyped(t.schema[i].Type), row[i]); err != nil {
+				return fmt.Errorf("column %s: %s", t.schema[i].Name, err)
+			}
+		}
+		insert.WriteByte(')')
+		if insert.Len() >= dumpInsertSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return flush()
+}
+
+// encodeMySQLValue writes v, a value of type t, as a MySQL literal. Binary
+// values, including geometries in MySQL's internal format, are written in hex
+// so that the dump is valid UTF-8.
+func encodeMySQLValue(b *bytes.Buffer, t sql.Type, v interface{}) error {
+	sv, err := t.SQL(v)
+	if err != nil {
+		return err
+	}
+	switch {
+	case sv.IsNull():
+		b.WriteString("NULL")
+	case sv.IsBinary() || sv.Type() == sqltypes.Geometry:
+		// Both MySQL and the engine read X'...' as a binary string, while
+		// the engine reads 0x... as a number
+		b.WriteString("X'")
+		b.WriteString(hex.EncodeToString(sv.ToBytes()))
+		b.WriteString("'")
+	default:
+		sv.EncodeSQL(b)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package sqlite
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDump(t *testing.T) {
+	db, ctx := testDatabase(t,
+		`CREATE TABLE people (id INT, name VARCHAR(20), photo BLOB, balance DECIMAL(10,2), born DATE)`,
+		`INSERT INTO people (rowtime, id, name, photo, balance, born) VALUES (1, 1, 'O''Brien', X'00ff', 12.50, '1990-01-02'), (2, 2, 'back\\slash', NULL, NULL, NULL)`,
+		`CREATE TABLE empty (id INT)`,
+	)
+
+	var b bytes.Buffer
+	if err := db.Dump(ctx, &b); err != nil {
+		t.Fatal(err)
+	}
+	dumped := b.String()
+	for _, want := range []string{
+		"DROP TABLE IF EXISTS `empty`;\nCREATE TABLE `empty` (",
+		"CREATE TABLE `people` (\n  `rowtime` bigint,\n  `id` int,\n  `name` varchar(20),\n",
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;\n",
+		"INSERT INTO `people` (`rowtime`,`id`,`name`,`photo`,`balance`,`born`) VALUES " +
+			"(1,1,'O\\'Brien',X'00ff',12.50,'1990-01-02'),(2,2,'back\\\\slash',NULL,NULL,NULL);\n",
+	} {
+		if !strings.Contains(dumped, want) {
+			t.Errorf("dump has no %q:\n%s", want, dumped)
+		}
+	}
+	if strings.Contains(dumped, "INSERT INTO `empty`") {
+		t.Errorf("dump inserts into an empty table:\n%s", dumped)
+	}
+	// Tables are dumped in order of name
+	if strings.Index(dumped, "`empty`") > strings.Index(dumped, "`people`") {
+		t.Errorf("people dumped before empty:\n%s", dumped)
+	}
+}
@@ -0,0 +1,38 @@
+package sqlite
+
+import (
+	"github.com/liquidata-inc/go-mysql-server/sql"
+)
+
+// Values of some types are stored in an encoding of their own, where SQLite's
+// native values would lose them: BIGINT UNSIGNED values are offset to fit a
+// signed integer, and DECIMAL values are text rather than approximate REALs.
+// Both encodings keep values in order, so SQLite compares, sorts and indexes
+// them as MySQL would, but can't compute with them.
+
+// isEncoded reports whether values of type t are stored encoded.
+func isEncoded(t sql.Type) bool {
+	return isOffset(t) || sql.IsDecimal(t)
+}
+
+// encodeValue returns the value stored for v, a value of type t.
+func encodeValue(t sql.Type, v interface{}) (interface{}, error) {
+	switch {
+	case isOffset(t):
+		return encodeUint64(v)
+	case sql.IsDecimal(t):
+		return encodeDecimal(t.(sql.DecimalType), v)
+	}
+	return v, nil
+}
+
+// decodeValue returns the value of type t stored as v.
+func decodeValue(t sql.Type, v interface{}) (interface{}, error) {
+	switch {
+	case isOffset(t):
+		return decodeUint64(v), nil
+	case sql.IsDecimal(t):
+		return decodeDecimal(t.(sql.DecimalType), v)
+	}
+	return v, nil
+}
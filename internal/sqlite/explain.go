@@ -0,0 +1,60 @@
+package sqlite
+
+import (
+	"github.com/liquidata-inc/go-mysql-server/sql"
+)
+
+// Explain describes how the table is read as a tree: the SQL each scan runs and
+// the plan SQLite's EXPLAIN QUERY PLAN reports for it, which shows whether it
+// uses an index or scans the whole table.
+func (t *Table) Explain(ctx *sql.Context) (string, error) {
+	query := t.scanQuery()
+	rows, err := t.dbr.QueryContext(ctx, "EXPLAIN QUERY PLAN "+query)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	type step struct {
+		detail   string
+		children []int
+	}
+	steps := map[int]*step{0: {}}
+	for rows.Next() {
+		var (
+			id, parent, notused int
+			detail              string
+		)
+		if err := rows.Scan(&id, &parent, &notused, &detail); err != nil {
+			return "", err
+		}
+		steps[id] = &step{detail: detail}
+		if p, ok := steps[parent]; ok {
+			p.children = append(p.children, id)
+		} else {
+			steps[0].children = append(steps[0].children, id)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	var tree func(id int) string
+	tree = func(id int) string {
+		s := steps[id]
+		pr := sql.NewTreePrinter()
+		_ = pr.WriteNode("%s", s.detail)
+		children := make([]string, len(s.children))
+		for i, c := range s.children {
+			children[i] = tree(c)
+		}
+		_ = pr.WriteChildren(children...)
+		return pr.String()
+	}
+	steps[0].detail = "SQLite query plan"
+
+	pr := sql.NewTreePrinter()
+	_ = pr.WriteNode("%s", t.String())
+	_ = pr.WriteChildren("SQLite: "+query, tree(0))
+	return pr.String(), nil
+}
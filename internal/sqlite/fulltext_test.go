@@ -0,0 +1,162 @@
+//go:build sqlite_fts5
+// +build sqlite_fts5
+
+package sqlite
+
+import (
+	"reflect"
+	"testing"
+
+	sqle "github.com/liquidata-inc/go-mysql-server"
+	"github.com/liquidata-inc/go-mysql-server/sql"
+)
+
+// fulltextEngine returns an engine running full-text searches over db.
+func fulltextEngine(db *Database) *sqle.Engine {
+	e := sqle.NewDefault()
+	e.AddDatabase(db)
+	e.Catalog.MustRegister(MatchFunction(e.Catalog))
+	return e
+}
+
+// search runs a statement as the server does, with MATCH ... AGAINST and CREATE
+// TABLE rewritten, and returns its rows.
+func search(t *testing.T, e *sqle.Engine, ctx *sql.Context, stmt string) []sql.Row {
+	t.Helper()
+	rewritten := RewriteCreateTable(stmt)
+	if m, err := RewriteMatchAgainst(rewritten); err != nil {
+		t.Fatalf("%s: %v", stmt, err)
+	} else if m != "" {
+		rewritten = m
+	}
+	ctx = sql.NewContext(ctx, sql.WithSession(ctx.Session), sql.WithQuery(stmt))
+	_, iter, err := e.Query(ctx, rewritten)
+	if err != nil {
+		t.Fatalf("%s: %v", stmt, err)
+	}
+	rows, err := sql.RowIterToRows(iter)
+	if err != nil {
+		t.Fatalf("%s: %v", stmt, err)
+	}
+	return rows
+}
+
+// ids returns the first column of rows as integers.
+func ids(rows []sql.Row) []int64 {
+	var ids []int64
+	for _, row := range rows {
+		id, _ := sql.Int64.Convert(row[0])
+		ids = append(ids, id.(int64))
+	}
+	return ids
+}
+
+func TestFulltextIndex(t *testing.T) {
+	db, ctx := testDatabase(t,
+		`CREATE TABLE posts (id INT, msg TEXT)`,
+		`INSERT INTO posts (id, msg) VALUES (1, 'the quick brown fox'), (2, 'a lazy dog sleeps'), (3, 'fox meets fox and another fox')`,
+	)
+	e := fulltextEngine(db)
+
+	// Rows already in the table are indexed
+	search(t, e, ctx, `CREATE FULLTEXT INDEX ft ON posts (msg)`)
+	foxes := `SELECT id FROM posts WHERE MATCH (msg) AGAINST ('fox') ORDER BY id`
+	if got := ids(search(t, e, ctx, foxes)); !reflect.DeepEqual(got, []int64{1, 3}) {
+		t.Errorf("before writes, found %v, want [1 3]", got)
+	}
+
+	// Triggers keep the index in sync with writes, including those by
+	// truncating or by other SQLite clients
+	search(t, e, ctx, `INSERT INTO posts (id, msg) VALUES (4, 'a fox appears')`)
+	for _, stmt := range []string{
+		`DELETE FROM posts WHERE id = 1`,
+		`UPDATE posts SET msg = 'the dog chases a fox' WHERE id = 2`,
+	} {
+		if _, err := db.w.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	if got := ids(search(t, e, ctx, foxes)); !reflect.DeepEqual(got, []int64{2, 3, 4}) {
+		t.Errorf("after writes, found %v, want [2 3 4]", got)
+	}
+	search(t, e, ctx, `CREATE TABLE notes (id INT, msg TEXT, FULLTEXT (msg))`)
+	search(t, e, ctx, `INSERT INTO notes (id, msg) VALUES (1, 'fox')`)
+	notes, _, err := db.GetTableInsensitive(ctx, "notes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := notes.(*Table).Truncate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(search(t, e, ctx, `SELECT id FROM notes WHERE MATCH (msg) AGAINST ('fox')`)); got != nil {
+		t.Errorf("after truncating, found %v", got)
+	}
+
+	for _, tt := range []struct {
+		against string
+		ids     []int64
+	}{
+		{"'dog' IN BOOLEAN MODE", []int64{2}},
+		{"'+fox -dog' IN BOOLEAN MODE", []int64{3, 4}},
+		{"'+fox +appears' IN BOOLEAN MODE", []int64{4}},
+		{"'app*' IN BOOLEAN MODE", []int64{4}},
+		{`'"dog chases"' IN BOOLEAN MODE`, []int64{2}},
+		{`'"chases dog"' IN BOOLEAN MODE`, nil},
+		{"'sleeps'", nil},
+	} {
+		stmt := `SELECT id FROM posts WHERE MATCH (msg) AGAINST (` + tt.against + `) ORDER BY id`
+		if got := ids(search(t, e, ctx, stmt)); !reflect.DeepEqual(got, tt.ids) {
+			t.Errorf("AGAINST (%s) found %v, want %v", tt.against, got, tt.ids)
+		}
+	}
+
+	// More relevant rows score higher: those with more matches, then shorter
+	// ones. Rows that don't match score 0
+	rows := search(t, e, ctx, `SELECT id, MATCH (msg) AGAINST ('fox') AS score FROM posts ORDER BY score DESC, id`)
+	if got := ids(rows); !reflect.DeepEqual(got, []int64{3, 4, 2}) {
+		t.Errorf("ranked %v, want [3 4 2]", got)
+	}
+	for _, row := range rows {
+		if score := row[1].(float64); score <= 1 {
+			t.Errorf("row %v scores %v, want more than 1", row[0], score)
+		}
+	}
+	rows = search(t, e, ctx, `SELECT MATCH (msg) AGAINST ('cat') FROM posts`)
+	for _, row := range rows {
+		if row[0].(float64) != 0 {
+			t.Errorf("a row not matching scores %v", row[0])
+		}
+	}
+}
+
+func TestFulltextInCreateTable(t *testing.T) {
+	db, ctx := testDatabase(t)
+	e := fulltextEngine(db)
+
+	search(t, e, ctx, "CREATE TABLE posts (id INT, title TEXT, body TEXT, KEY (id), FULLTEXT KEY ft (title, body), FULLTEXT (body))")
+	search(t, e, ctx, `INSERT INTO posts (id, title, body) VALUES (1, 'foxes', 'about dogs'), (2, 'dogs', 'about foxes')`)
+
+	for _, tt := range []struct {
+		stmt string
+		ids  []int64
+	}{
+		{`SELECT id FROM posts WHERE MATCH (title, body) AGAINST ('foxes') ORDER BY id`, []int64{1, 2}},
+		{`SELECT id FROM posts WHERE MATCH (body) AGAINST ('foxes') ORDER BY id`, []int64{2}},
+	} {
+		if got := ids(search(t, e, ctx, tt.stmt)); !reflect.DeepEqual(got, tt.ids) {
+			t.Errorf("%s: found %v, want %v", tt.stmt, got, tt.ids)
+		}
+	}
+
+	indexes, err := loadIndexes(ctx, db.r, "posts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kinds := map[string]string{}
+	for _, idx := range indexes {
+		kinds[idx.name] = idx.kind
+	}
+	if want := map[string]string{"id": "", "ft": "FULLTEXT", "body": "FULLTEXT"}; !reflect.DeepEqual(kinds, want) {
+		t.Errorf("created indexes %v, want %v", kinds, want)
+	}
+}
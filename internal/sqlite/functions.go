@@ -0,0 +1,230 @@
+package sqlite
+
+import (
+	"math"
+	"time"
+
+	"github.com/liquidata-inc/go-mysql-server/sql"
+	"github.com/liquidata-inc/go-mysql-server/sql/expression"
+	"github.com/liquidata-inc/vitess/go/sqltypes"
+	"github.com/mattn/go-sqlite3"
+)
+
+// MySQLFunctions are MySQL functions go-mysql-server lacks, which mysqlite
+// provides to both the engine and SQLite.
+var MySQLFunctions = []sql.Function{
+	sql.FunctionN{Name: "from_unixtime", Fn: newFromUnixtime},
+	sql.Function1{Name: decimalLiteralFunction, Fn: newDecimalLiteral},
+}
+
+// pushdownFunctions are the MySQL functions registered as SQLite functions on
+// every connection, so that SQL run by SQLite can use them with MySQL
+// semantics. Functions SQLite has built in with the same semantics, like
+// LOWER, are left alone. Impure functions are those whose result doesn't only
+// depend on their arguments.
+var pushdownFunctions = []struct {
+	name string
+	pure bool
+}{
+	{"concat", true},
+	{"concat_ws", true},
+	{"if", true},
+	{"left", true},
+	{"lpad", true},
+	{"rpad", true},
+	{"mid", true},
+	{"repeat", true},
+	{"reverse", true},
+	{"substring_index", true},
+	{"char_length", true},
+	{"character_length", true},
+	{"to_base64", true},
+	{"from_base64", true},
+	{"soundex", true},
+	{"greatest", true},
+	{"least", true},
+	{"ceil", true},
+	{"ceiling", true},
+	{"floor", true},
+	{"pow", true},
+	{"power", true},
+	{"sqrt", true},
+	{"ln", true},
+	{"log", true},
+	{"log2", true},
+	{"log10", true},
+	{"date_format", true},
+	{"year", true},
+	{"month", true},
+	{"day", true},
+	{"dayofmonth", true},
+	{"dayofweek", true},
+	{"dayofyear", true},
+	{"weekday", true},
+	{"week", true},
+	{"yearweek", true},
+	{"hour", true},
+	{"minute", true},
+	{"second", true},
+	{"from_unixtime", true},
+	{"unix_timestamp", false},
+	{"now", false},
+}
+
+func registerFunctions(conn *sqlite3.SQLiteConn) error {
+	for _, f := range pushdownFunctions {
+		fn, err := functions.Function(f.name)
+		if err != nil {
+			return err
+		}
+		if err := conn.RegisterFunc(f.name, sqliteFunction(fn), f.pure); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sqliteFunction implements a SQLite function by calling the engine's
+// implementation of the function with literal arguments, so that it gives the
+// same results in SQLite as in the engine.
+func sqliteFunction(fn sql.Function) func(args ...interface{}) (interface{}, error) {
+	return func(args ...interface{}) (interface{}, error) {
+		exprs := make([]sql.Expression, len(args))
+		for i, arg := range args {
+			exprs[i] = sqliteArg(arg)
+		}
+		e, err := fn.Call(exprs...)
+		if err != nil {
+			return nil, err
+		}
+		v, err := e.Eval(sql.NewEmptyContext(), nil)
+		if err != nil || v == nil {
+			return nil, err
+		}
+		return sqliteResult(e.Type(), v)
+	}
+}
+
+// sqliteArg returns a SQLite value as a literal of the corresponding type.
+func sqliteArg(v interface{}) sql.Expression {
+	switch v := v.(type) {
+	case int64:
+		return expression.NewLiteral(v, sql.Int64)
+	case float64:
+		return expression.NewLiteral(v, sql.Float64)
+	case string:
+		// Times are stored as text
+		if t, ok := parseTimestamp(v); ok {
+			return expression.NewLiteral(t, sql.Datetime)
+		}
+		return expression.NewLiteral(v, sql.LongText)
+	case []byte:
+		// go-sqlite3 passes NULL as a nil slice
+		if v != nil {
+			return expression.NewLiteral(v, sql.LongBlob)
+		}
+	}
+	return expression.NewLiteral(nil, sql.Null)
+}
+
+// sqliteResult converts a value of type t to the SQLite value a client would
+// see, formatting dates and decimals as text.
+func sqliteResult(t sql.Type, v interface{}) (interface{}, error) {
+	sv, err := t.SQL(v)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case sv.IsNull():
+		return nil, nil
+	case sv.IsSigned():
+		return sqltypes.ToInt64(sv)
+	case sv.IsUnsigned():
+		u, err := sqltypes.ToUint64(sv)
+		if err != nil || u > math.MaxInt64 {
+			return sv.ToString(), err
+		}
+		return int64(u), nil
+	case sv.IsFloat():
+		return sqltypes.ToFloat64(sv)
+	case sv.IsBinary() || sv.Type() == sqltypes.Geometry:
+		return sv.ToBytes(), nil
+	}
+	return sv.ToString(), nil
+}
+
+// fromUnixtime is FROM_UNIXTIME(unix_timestamp[, format]). Times are UTC, as
+// UNIX_TIMESTAMP assumes.
+type fromUnixtime struct {
+	args []sql.Expression
+}
+
+var _ sql.Expression = (*fromUnixtime)(nil)
+
+func newFromUnixtime(args ...sql.Expression) (sql.Expression, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, sql.ErrInvalidArgumentNumber.New("FROM_UNIXTIME", "1 or 2", len(args))
+	}
+	return &fromUnixtime{args: args}, nil
+}
+
+func (f *fromUnixtime) Resolved() bool {
+	for _, arg := range f.args {
+		if !arg.Resolved() {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *fromUnixtime) String() string {
+	if len(f.args) == 2 {
+		return "FROM_UNIXTIME(" + f.args[0].String() + ", " + f.args[1].String() + ")"
+	}
+	return "FROM_UNIXTIME(" + f.args[0].String() + ")"
+}
+
+func (f *fromUnixtime) Type() sql.Type {
+	if len(f.args) == 2 {
+		return sql.LongText
+	}
+	return sql.Datetime
+}
+
+func (f *fromUnixtime) IsNullable() bool { return true }
+
+func (f *fromUnixtime) Children() []sql.Expression { return f.args }
+
+func (f *fromUnixtime) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	return newFromUnixtime(children...)
+}
+
+func (f *fromUnixtime) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
+	v, err := f.args[0].Eval(ctx, row)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	v, err = sql.Float64.Convert(v)
+	if err != nil {
+		return nil, err
+	}
+	secs := v.(float64)
+	if secs < 0 {
+		return nil, nil
+	}
+	whole, frac := math.Modf(secs)
+	t := time.Unix(int64(whole), int64(math.Round(frac*1e6))*1e3).UTC()
+	if len(f.args) == 1 {
+		return t, nil
+	}
+
+	dateFormat, err := functions.Function("date_format")
+	if err != nil {
+		return nil, err
+	}
+	e, err := dateFormat.Call(expression.NewLiteral(t, sql.Datetime), f.args[1])
+	if err != nil {
+		return nil, err
+	}
+	return e.Eval(ctx, row)
+}
@@ -0,0 +1,84 @@
+package sqlite
+
+import (
+	stdsql "database/sql"
+	"testing"
+	"time"
+
+	sqle "github.com/liquidata-inc/go-mysql-server"
+	"github.com/liquidata-inc/go-mysql-server/sql"
+)
+
+func TestPushdownFunctions(t *testing.T) {
+	db, _ := testDatabase(t)
+
+	tests := []struct {
+		expr string
+		want interface{}
+	}{
+		{"concat('a', 1, 2.5)", "a12.5"},
+		{"concat('a', NULL)", nil},
+		{"concat_ws('-', 'a', NULL, 'b')", "a-b"},
+		{"if(1 > 2, 'yes', 'no')", "no"},
+		{"lpad('5', 3, '0')", "005"},
+		{"substring_index('a.b.c', '.', 2)", "a.b"},
+		{"char_length('héllo')", int64(5)},
+		{"greatest(1, 3, 2)", int64(3)},
+		{"floor(2.5)", float64(2)},
+		{"date_format('2020-01-02 03:04:05', '%Y/%m/%d %H:%i')", "2020/01/02 03:04"},
+		{"year('2020-01-02')", int64(2020)},
+		{"dayofweek('2020-01-05 00:00:00')", int64(1)},
+		{"from_unixtime(86400)", "1970-01-02 00:00:00"},
+		{"from_unixtime(86400, '%Y-%m-%d')", "1970-01-02"},
+		{"from_unixtime(-1)", nil},
+	}
+	// Functions are registered on every connection, for reads and writes
+	for _, pool := range []*stdsql.DB{db.r, db.w} {
+		for _, tt := range tests {
+			var got interface{}
+			if err := pool.QueryRow("SELECT " + tt.expr).Scan(&got); err != nil {
+				t.Errorf("%s: %v", tt.expr, err)
+				continue
+			}
+			if b, ok := got.([]byte); ok {
+				got = string(b)
+			}
+			if got != tt.want {
+				t.Errorf("%s = %#v, want %#v", tt.expr, got, tt.want)
+			}
+		}
+	}
+
+	var ts float64
+	if err := db.r.QueryRow("SELECT unix_timestamp()").Scan(&ts); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(time.Unix(int64(ts), 0)); d < -time.Second || d > time.Minute {
+		t.Errorf("unix_timestamp() = %v, %v from now", ts, d)
+	}
+}
+
+func TestFromUnixtime(t *testing.T) {
+	db, ctx := testDatabase(t)
+	e := sqle.NewDefault()
+	e.AddDatabase(db)
+	e.Catalog.MustRegister(MySQLFunctions...)
+
+	_, iter, err := e.Query(ctx, "SELECT FROM_UNIXTIME(1.5), FROM_UNIXTIME(86400, '%Y-%m-%d'), FROM_UNIXTIME(NULL)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := sql.RowIterToRows(iter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := rows[0][0], time.Unix(1, 5e8).UTC(); got != want {
+		t.Errorf("FROM_UNIXTIME(1.5) = %v, want %v", got, want)
+	}
+	if got := rows[0][1]; got != "1970-01-02" {
+		t.Errorf("FROM_UNIXTIME(86400, '%%Y-%%m-%%d') = %v, want 1970-01-02", got)
+	}
+	if got := rows[0][2]; got != nil {
+		t.Errorf("FROM_UNIXTIME(NULL) = %v, want NULL", got)
+	}
+}
@@ -0,0 +1,363 @@
+package sqlite
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/liquidata-inc/go-mysql-server/sql"
+	"github.com/liquidata-inc/go-mysql-server/sql/expression"
+	"github.com/liquidata-inc/go-mysql-server/sql/expression/function"
+	"github.com/liquidata-inc/go-mysql-server/sql/parse"
+	"github.com/liquidata-inc/go-mysql-server/sql/plan"
+	"github.com/liquidata-inc/vitess/go/vt/sqlparser"
+)
+
+// generatedColumn is a column whose value is computed from the rest of its row,
+// declared with [GENERATED ALWAYS] AS (expr) [VIRTUAL|STORED].
+type generatedColumn struct {
+	expr   string // as declared, in MySQL syntax
+	stored bool
+	// native columns are SQLite generated columns, maintained by SQLite itself.
+	// Expressions that can't be translated to SQLite are computed by eval on
+	// insert and stored in an ordinary column instead.
+	native bool
+	eval   sql.Expression
+}
+
+// String formats the column's generation clause as it appears in a column
+// definition.
+func (g *generatedColumn) String() string {
+	if g.stored {
+		return fmt.Sprintf("GENERATED ALWAYS AS (%s) STORED", g.expr)
+	}
+	return fmt.Sprintf("GENERATED ALWAYS AS (%s) VIRTUAL", g.expr)
+}
+
+var (
+	generatedRegex        = regexp.MustCompile(`(?is)\s(?:generated\s+always\s+)?as\s*\(`)
+	generatedStorageRegex = regexp.MustCompile(`(?is)^\s*(virtual|stored)\b`)
+)
+
+// stripGenerated removes the generation clause from a column definition,
+// returning the definition without it and the generated column it declares,
+// or nil if it declares an ordinary column.
+func stripGenerated(def string) (string, *generatedColumn) {
+	top := make([]bool, len(def))
+	scanSQL(def, func(i, depth int) bool {
+		top[i] = depth == 0 && def[i] != '('
+		return true
+	})
+	for _, loc := range generatedRegex.FindAllStringIndex(def, -1) {
+		if !top[loc[0]] {
+			continue
+		}
+		open := loc[1] - 1
+		close := closingParen(def, open)
+		if close < 0 {
+			break
+		}
+		g := &generatedColumn{expr: strings.TrimSpace(def[open+1 : close])}
+		end := close + 1
+		if m := generatedStorageRegex.FindStringSubmatchIndex(def[end:]); m != nil {
+			g.stored = strings.EqualFold(def[end+m[2]:end+m[3]], "stored")
+			end += m[1]
+		}
+		return def[:loc[0]] + def[end:], g
+	}
+	return def, nil
+}
+
+// withGenerated lines the generated columns declared by a CREATE TABLE statement
+// up with the table schema, deciding which of them SQLite can maintain itself.
+func withGenerated(ctx *sql.Context, schema sql.Schema, cols map[string]*generatedColumn) ([]*generatedColumn, error) {
+	if len(cols) == 0 {
+		return nil, nil
+	}
+	generated := make([]*generatedColumn, len(schema))
+	for i, col := range schema {
+		g, ok := cols[strings.ToLower(col.Name)]
+		if !ok {
+			continue
+		}
+		if col.PrimaryKey {
+			return nil, fmt.Errorf("generated column %q can't be part of the primary key", col.Name)
+		}
+		// SQLite would store the value of a column it computes unencoded
+		_, g.native = translateExpr(g.expr, schema)
+		g.native = g.native && !isEncoded(col.Type)
+		generated[i] = g
+	}
+	if err := resolveGenerated(ctx, schema, generated); err != nil {
+		return nil, err
+	}
+	return generated, nil
+}
+
+// resolveGenerated prepares the expressions of generated columns for evaluation
+// in Go. Columns computed in Go must resolve; native ones are also evaluated
+// where possible so that columns computed in Go can depend on them.
+func resolveGenerated(ctx *sql.Context, schema sql.Schema, generated []*generatedColumn) error {
+	for i, g := range generated {
+		if g == nil {
+			continue
+		}
+		eval, err := resolveExpr(ctx, schema, g.expr)
+		if err != nil && !g.native {
+			return fmt.Errorf("generated column %q: %s", schema[i].Name, err)
+		}
+		g.eval = eval
+	}
+	return nil
+}
+
+var functions = func() sql.FunctionRegistry {
+	r := sql.NewFunctionRegistry()
+	r.MustRegister(function.Defaults...)
+	r.MustRegister(SpatialFunctions...)
+	r.MustRegister(MySQLFunctions...)
+	return r
+}()
+
+// resolveExpr parses a MySQL expression and resolves its columns against schema
+// and its functions against the go-mysql-server builtins.
+func resolveExpr(ctx *sql.Context, schema sql.Schema, expr string) (sql.Expression, error) {
+	node, err := parse.Parse(ctx, "SELECT "+expr)
+	if err != nil {
+		return nil, err
+	}
+	project, ok := node.(*plan.Project)
+	if !ok || len(project.Projections) != 1 {
+		return nil, fmt.Errorf("unsupported expression: %s", expr)
+	}
+	e := project.Projections[0]
+	if alias, ok := e.(*expression.Alias); ok {
+		e = alias.Child
+	}
+	return expression.TransformUp(e, func(e sql.Expression) (sql.Expression, error) {
+		switch e := e.(type) {
+		case *expression.UnresolvedColumn:
+			i := schema.IndexOf(e.Name(), schema[0].Source)
+			if i < 0 {
+				return nil, sql.ErrColumnNotFound.New(e.Name())
+			}
+			col := schema[i]
+			return expression.NewGetField(i, col.Type, col.Name, col.Nullable), nil
+		case *expression.UnresolvedFunction:
+			f, err := functions.Function(strings.ToLower(e.Name()))
+			if err != nil {
+				return nil, err
+			}
+			return f.Call(e.Arguments...)
+		}
+		return e, nil
+	})
+}
+
+// computeGenerated fills in the generated columns of row that are computed in
+// Go, in schema order so that a column can depend on the ones before it.
+func computeGenerated(ctx *sql.Context, schema sql.Schema, generated []*generatedColumn, row sql.Row) error {
+	for i, g := range generated {
+		if g == nil || g.eval == nil {
+			continue
+		}
+		v, err := g.eval.Eval(ctx, row)
+		if err != nil {
+			return err
+		}
+		if v != nil {
+			if v, err = charTyped(schema[i].Type).Convert(v); err != nil {
+				return err
+			}
+		}
+		row[i] = v
+	}
+	return nil
+}
+
+// sqliteFunctions are the MySQL functions that SQLite implements with the same
+// semantics, keyed by MySQL name. SUBSTRING isn't one, since SQLite counts a
+// start of 0 or a negative length differently, and neither is ROUND, which
+// returns a REAL in SQLite even for integers.
+var sqliteFunctions = map[string]string{
+	"abs":      "abs",
+	"coalesce": "coalesce",
+	"ifnull":   "ifnull",
+	"lower":    "lower",
+	"lcase":    "lower",
+	"upper":    "upper",
+	"ucase":    "upper",
+	"replace":  "replace",
+	"trim":     "trim",
+	"ltrim":    "ltrim",
+	"rtrim":    "rtrim",
+	"nullif":   "nullif",
+}
+
+// translateExpr translates a MySQL expression over the columns of schema to
+// SQLite, reporting false if it uses anything whose semantics differ between
+// the two.
+func translateExpr(expr string, schema sql.Schema) (string, bool) {
+	stmt, err := sqlparser.Parse("SELECT " + expr)
+	if err != nil {
+		return "", false
+	}
+	sel, ok := stmt.(*sqlparser.Select)
+	if !ok || len(sel.SelectExprs) != 1 {
+		return "", false
+	}
+	ae, ok := sel.SelectExprs[0].(*sqlparser.AliasedExpr)
+	if !ok {
+		return "", false
+	}
+	return sqliteExpr(ae.Expr, schema)
+}
+
+func sqliteExpr(e sqlparser.Expr, schema sql.Schema) (string, bool) {
+	switch e := e.(type) {
+	case *sqlparser.SQLVal:
+		switch e.Type {
+		case sqlparser.IntVal, sqlparser.FloatVal:
+			return string(e.Val), true
+		case sqlparser.StrVal:
+			return "'" + strings.ReplaceAll(string(e.Val), "'", "''") + "'", true
+		}
+	case *sqlparser.NullVal:
+		return "NULL", true
+	case sqlparser.BoolVal:
+		if e {
+			return "1", true
+		}
+		return "0", true
+	case *sqlparser.ColName:
+		i := schema.IndexOf(e.Name.String(), schema[0].Source)
+		if !e.Qualifier.IsEmpty() || i < 0 || isEncoded(schema[i].Type) {
+			// Encoded values can't be computed with
+			return "", false
+		}
+		return `"` + e.Name.String() + `"`, true
+	case *sqlparser.ParenExpr:
+		s, ok := sqliteExpr(e.Expr, schema)
+		return "(" + s + ")", ok
+	case *sqlparser.UnaryExpr:
+		if e.Operator != sqlparser.UMinusStr && e.Operator != sqlparser.UPlusStr {
+			return "", false
+		}
+		s, ok := sqliteExpr(e.Expr, schema)
+		return e.Operator + s, ok
+	case *sqlparser.BinaryExpr:
+		// Division differs: MySQL divides integers exactly, SQLite truncates
+		switch e.Operator {
+		case sqlparser.PlusStr, sqlparser.MinusStr, sqlparser.MultStr, sqlparser.ModStr:
+			return sqliteBinary(e.Left, e.Operator, e.Right, schema)
+		}
+	case *sqlparser.ComparisonExpr:
+		switch e.Operator {
+		case sqlparser.EqualStr, sqlparser.LessThanStr, sqlparser.GreaterThanStr,
+			sqlparser.LessEqualStr, sqlparser.GreaterEqualStr, sqlparser.NotEqualStr:
+			return sqliteBinary(e.Left, e.Operator, e.Right, schema)
+		}
+	case *sqlparser.AndExpr:
+		return sqliteBinary(e.Left, "AND", e.Right, schema)
+	case *sqlparser.OrExpr:
+		return sqliteBinary(e.Left, "OR", e.Right, schema)
+	case *sqlparser.NotExpr:
+		s, ok := sqliteExpr(e.Expr, schema)
+		return "NOT " + s, ok
+	case *sqlparser.IsExpr:
+		switch e.Operator {
+		case sqlparser.IsNullStr, sqlparser.IsNotNullStr:
+			s, ok := sqliteExpr(e.Expr, schema)
+			return s + " " + strings.ToUpper(e.Operator), ok
+		}
+	case *sqlparser.CaseExpr:
+		s := "CASE"
+		if e.Expr != nil {
+			v, ok := sqliteExpr(e.Expr, schema)
+			if !ok {
+				return "", false
+			}
+			s += " " + v
+		}
+		for _, when := range e.Whens {
+			cond, ok := sqliteExpr(when.Cond, schema)
+			if !ok {
+				return "", false
+			}
+			val, ok := sqliteExpr(when.Val, schema)
+			if !ok {
+				return "", false
+			}
+			s += " WHEN " + cond + " THEN " + val
+		}
+		if e.Else != nil {
+			v, ok := sqliteExpr(e.Else, schema)
+			if !ok {
+				return "", false
+			}
+			s += " ELSE " + v
+		}
+		return s + " END", true
+	case *sqlparser.FuncExpr:
+		if !e.Qualifier.IsEmpty() || e.Distinct {
+			return "", false
+		}
+		name := e.Name.Lowered()
+		var args []string
+		for _, se := range e.Exprs {
+			ae, ok := se.(*sqlparser.AliasedExpr)
+			if !ok {
+				return "", false
+			}
+			if name == "concat" && !isText(ae.Expr, schema) {
+				// SQLite formats numbers differently, e.g. 1.0 rather than 1
+				return "", false
+			}
+			arg, ok := sqliteExpr(ae.Expr, schema)
+			if !ok {
+				return "", false
+			}
+			args = append(args, arg)
+		}
+		if name == "concat" {
+			// Like CONCAT, || is NULL if any operand is
+			if len(args) == 0 {
+				return "", false
+			}
+			return "(" + strings.Join(args, " || ") + ")", true
+		}
+		if fn, ok := sqliteFunctions[name]; ok {
+			return fn + "(" + strings.Join(args, ", ") + ")", true
+		}
+	}
+	return "", false
+}
+
+// isText reports whether e is known to be a string without conversion.
+func isText(e sqlparser.Expr, schema sql.Schema) bool {
+	switch e := e.(type) {
+	case *sqlparser.SQLVal:
+		return e.Type == sqlparser.StrVal
+	case *sqlparser.ColName:
+		i := schema.IndexOf(e.Name.String(), schema[0].Source)
+		return i >= 0 && sql.IsText(schema[i].Type)
+	case *sqlparser.FuncExpr:
+		switch e.Name.Lowered() {
+		case "concat", "lower", "lcase", "upper", "ucase", "replace", "substr", "substring", "trim", "ltrim", "rtrim":
+			return true
+		}
+	}
+	return false
+}
+
+func sqliteBinary(left sqlparser.Expr, op string, right sqlparser.Expr, schema sql.Schema) (string, bool) {
+	l, ok := sqliteExpr(left, schema)
+	if !ok {
+		return "", false
+	}
+	r, ok := sqliteExpr(right, schema)
+	if !ok {
+		return "", false
+	}
+	return l + " " + op + " " + r, true
+}
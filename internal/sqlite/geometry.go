@@ -0,0 +1,484 @@
+package sqlite
+
+import (
+	"encoding/binary"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// WKB geometry types
+const (
+	wkbPoint uint32 = 1 + iota
+	wkbLineString
+	wkbPolygon
+	wkbMultiPoint
+	wkbMultiLineString
+	wkbMultiPolygon
+	wkbGeometryCollection
+)
+
+var wkbNames = map[uint32]string{
+	wkbPoint:              "POINT",
+	wkbLineString:         "LINESTRING",
+	wkbPolygon:            "POLYGON",
+	wkbMultiPoint:         "MULTIPOINT",
+	wkbMultiLineString:    "MULTILINESTRING",
+	wkbMultiPolygon:       "MULTIPOLYGON",
+	wkbGeometryCollection: "GEOMETRYCOLLECTION",
+}
+
+type point struct{ x, y float64 }
+
+// geometry is a decoded spatial value. Points and line strings keep their
+// points in points, polygons their rings in rings, and the multi types and
+// collections their members in geometries.
+type geometry struct {
+	srid       uint32
+	kind       uint32
+	points     []point
+	rings      [][]point
+	geometries []geometry
+}
+
+var errInvalidGeometry = errors.New("invalid geometry")
+
+// decodeGeometry decodes a value in MySQL's internal geometry format, which is
+// the little-endian SRID of the geometry followed by its WKB.
+func decodeGeometry(b []byte) (geometry, error) {
+	if len(b) < 4 {
+		return geometry{}, errInvalidGeometry
+	}
+	g, rest, err := decodeWKB(b[4:])
+	if err != nil {
+		return geometry{}, err
+	}
+	if len(rest) != 0 {
+		return geometry{}, errInvalidGeometry
+	}
+	g.srid = binary.LittleEndian.Uint32(b)
+	return g, nil
+}
+
+// wkbReader reads the fields of a WKB geometry, remembering the first error.
+type wkbReader struct {
+	b     []byte
+	order binary.ByteOrder
+	err   error
+}
+
+func (r *wkbReader) uint32() uint32 {
+	if r.err != nil || len(r.b) < 4 {
+		r.err = errInvalidGeometry
+		return 0
+	}
+	v := r.order.Uint32(r.b)
+	r.b = r.b[4:]
+	return v
+}
+
+func (r *wkbReader) point() point {
+	if r.err != nil || len(r.b) < 16 {
+		r.err = errInvalidGeometry
+		return point{}
+	}
+	p := point{
+		x: math.Float64frombits(r.order.Uint64(r.b)),
+		y: math.Float64frombits(r.order.Uint64(r.b[8:])),
+	}
+	r.b = r.b[16:]
+	return p
+}
+
+func (r *wkbReader) points() []point {
+	n := r.uint32()
+	if r.err != nil || uint64(n)*16 > uint64(len(r.b)) {
+		r.err = errInvalidGeometry
+		return nil
+	}
+	points := make([]point, n)
+	for i := range points {
+		points[i] = r.point()
+	}
+	return points
+}
+
+// decodeWKB decodes a WKB geometry from the start of b, returning the bytes
+// that follow it.
+func decodeWKB(b []byte) (geometry, []byte, error) {
+	if len(b) < 1 {
+		return geometry{}, nil, errInvalidGeometry
+	}
+	r := &wkbReader{b: b[1:]}
+	switch b[0] {
+	case 0:
+		r.order = binary.BigEndian
+	case 1:
+		r.order = binary.LittleEndian
+	default:
+		return geometry{}, nil, errInvalidGeometry
+	}
+
+	g := geometry{kind: r.uint32()}
+	switch g.kind {
+	case wkbPoint:
+		g.points = []point{r.point()}
+	case wkbLineString:
+		g.points = r.points()
+	case wkbPolygon:
+		n := r.uint32()
+		for i := uint32(0); i < n && r.err == nil; i++ {
+			g.rings = append(g.rings, r.points())
+		}
+	case wkbMultiPoint, wkbMultiLineString, wkbMultiPolygon, wkbGeometryCollection:
+		n := r.uint32()
+		for i := uint32(0); i < n && r.err == nil; i++ {
+			var m geometry
+			m, r.b, r.err = decodeWKB(r.b)
+			g.geometries = append(g.geometries, m)
+		}
+	default:
+		return geometry{}, nil, errInvalidGeometry
+	}
+	if r.err != nil {
+		return geometry{}, nil, r.err
+	}
+	if err := g.validate(); err != nil {
+		return geometry{}, nil, err
+	}
+	return g, r.b, nil
+}
+
+// validate checks that a geometry is well formed the way MySQL does: line
+// strings have at least two points, polygon rings are closed with at least four,
+// and the members of the multi types are of the right kind.
+func (g geometry) validate() error {
+	var member uint32
+	switch g.kind {
+	case wkbLineString:
+		if len(g.points) < 2 {
+			return errInvalidGeometry
+		}
+	case wkbPolygon:
+		if len(g.rings) == 0 {
+			return errInvalidGeometry
+		}
+		for _, ring := range g.rings {
+			if len(ring) < 4 || ring[0] != ring[len(ring)-1] {
+				return errInvalidGeometry
+			}
+		}
+	case wkbMultiPoint:
+		member = wkbPoint
+	case wkbMultiLineString:
+		member = wkbLineString
+	case wkbMultiPolygon:
+		member = wkbPolygon
+	}
+	for _, m := range g.geometries {
+		if member != 0 && m.kind != member {
+			return errInvalidGeometry
+		}
+	}
+	return nil
+}
+
+// value encodes a geometry in MySQL's internal format.
+func (g geometry) value() []byte {
+	b := make([]byte, 4, 64)
+	binary.LittleEndian.PutUint32(b, g.srid)
+	return g.appendWKB(b)
+}
+
+// wkb encodes a geometry as little-endian WKB.
+func (g geometry) wkb() []byte {
+	return g.appendWKB(nil)
+}
+
+func (g geometry) appendWKB(b []byte) []byte {
+	le := binary.LittleEndian
+	appendUint32 := func(v uint32) {
+		b = append(b, 0, 0, 0, 0)
+		le.PutUint32(b[len(b)-4:], v)
+	}
+	appendPoints := func(points []point) {
+		for _, p := range points {
+			b = append(b, make([]byte, 16)...)
+			le.PutUint64(b[len(b)-16:], math.Float64bits(p.x))
+			le.PutUint64(b[len(b)-8:], math.Float64bits(p.y))
+		}
+	}
+
+	b = append(b, 1)
+	appendUint32(g.kind)
+	switch g.kind {
+	case wkbPoint:
+		appendPoints(g.points)
+	case wkbLineString:
+		appendUint32(uint32(len(g.points)))
+		appendPoints(g.points)
+	case wkbPolygon:
+		appendUint32(uint32(len(g.rings)))
+		for _, ring := range g.rings {
+			appendUint32(uint32(len(ring)))
+			appendPoints(ring)
+		}
+	default:
+		appendUint32(uint32(len(g.geometries)))
+		for _, m := range g.geometries {
+			b = m.appendWKB(b)
+		}
+	}
+	return b
+}
+
+// wkt formats a geometry as WKT, the way MySQL's ST_AsText does.
+func (g geometry) wkt() string {
+	var b strings.Builder
+	g.writeWKT(&b)
+	return b.String()
+}
+
+func (g geometry) writeWKT(b *strings.Builder) {
+	writePoints := func(points []point) {
+		b.WriteByte('(')
+		for i, p := range points {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(formatCoordinate(p.x))
+			b.WriteByte(' ')
+			b.WriteString(formatCoordinate(p.y))
+		}
+		b.WriteByte(')')
+	}
+	writeRings := func(rings [][]point) {
+		b.WriteByte('(')
+		for i, ring := range rings {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			writePoints(ring)
+		}
+		b.WriteByte(')')
+	}
+
+	b.WriteString(wkbNames[g.kind])
+	switch g.kind {
+	case wkbPoint, wkbLineString:
+		writePoints(g.points)
+		return
+	case wkbPolygon:
+		writeRings(g.rings)
+		return
+	}
+	if len(g.geometries) == 0 {
+		b.WriteString(" EMPTY")
+		return
+	}
+	b.WriteByte('(')
+	for i, m := range g.geometries {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		switch g.kind {
+		case wkbMultiPoint, wkbMultiLineString:
+			writePoints(m.points)
+		case wkbMultiPolygon:
+			writeRings(m.rings)
+		default:
+			m.writeWKT(b)
+		}
+	}
+	b.WriteByte(')')
+}
+
+func formatCoordinate(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// parseWKT parses a geometry in WKT.
+func parseWKT(s string) (geometry, error) {
+	p := &wktParser{s: s}
+	g, err := p.geometry()
+	if err != nil {
+		return geometry{}, err
+	}
+	if p.skipSpace(); p.pos != len(p.s) {
+		return geometry{}, errInvalidGeometry
+	}
+	return g, nil
+}
+
+type wktParser struct {
+	s   string
+	pos int
+}
+
+func (p *wktParser) skipSpace() {
+	for p.pos < len(p.s) && strings.IndexByte(" \t\r\n", p.s[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+func (p *wktParser) peek() byte {
+	if p.skipSpace(); p.pos < len(p.s) {
+		return p.s[p.pos]
+	}
+	return 0
+}
+
+func (p *wktParser) expect(c byte) error {
+	if p.peek() != c {
+		return errInvalidGeometry
+	}
+	p.pos++
+	return nil
+}
+
+func (p *wktParser) word() string {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.s) && ('a' <= p.s[p.pos]|0x20 && p.s[p.pos]|0x20 <= 'z') {
+		p.pos++
+	}
+	return strings.ToUpper(p.s[start:p.pos])
+}
+
+func (p *wktParser) number() (float64, error) {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.s) && strings.IndexByte("+-.0123456789eE", p.s[p.pos]) >= 0 {
+		p.pos++
+	}
+	f, err := strconv.ParseFloat(p.s[start:p.pos], 64)
+	if err != nil {
+		return 0, errInvalidGeometry
+	}
+	return f, nil
+}
+
+func (p *wktParser) point() (point, error) {
+	x, err := p.number()
+	if err != nil {
+		return point{}, err
+	}
+	y, err := p.number()
+	if err != nil {
+		return point{}, err
+	}
+	return point{x, y}, nil
+}
+
+// list parses a parenthesized, comma separated list, calling item for each of
+// its elements.
+func (p *wktParser) list(item func() error) error {
+	if err := p.expect('('); err != nil {
+		return err
+	}
+	for {
+		if err := item(); err != nil {
+			return err
+		}
+		if p.peek() != ',' {
+			return p.expect(')')
+		}
+		p.pos++
+	}
+}
+
+func (p *wktParser) points() ([]point, error) {
+	var points []point
+	err := p.list(func() error {
+		pt, err := p.point()
+		points = append(points, pt)
+		return err
+	})
+	return points, err
+}
+
+func (p *wktParser) rings() ([][]point, error) {
+	var rings [][]point
+	err := p.list(func() error {
+		ring, err := p.points()
+		rings = append(rings, ring)
+		return err
+	})
+	return rings, err
+}
+
+func (p *wktParser) geometry() (geometry, error) {
+	name := p.word()
+	var g geometry
+	for kind, n := range wkbNames {
+		if n == name {
+			g.kind = kind
+		}
+	}
+	if g.kind == 0 {
+		return geometry{}, errInvalidGeometry
+	}
+
+	var err error
+	switch g.kind {
+	case wkbPoint:
+		var pt point
+		if err = p.expect('('); err == nil {
+			if pt, err = p.point(); err == nil {
+				err = p.expect(')')
+			}
+		}
+		g.points = []point{pt}
+	case wkbLineString:
+		g.points, err = p.points()
+	case wkbPolygon:
+		g.rings, err = p.rings()
+	default:
+		save := p.pos
+		if p.word() == "EMPTY" {
+			return g, nil
+		}
+		p.pos = save
+		err = p.list(func() error {
+			var m geometry
+			var err error
+			switch g.kind {
+			case wkbMultiPoint:
+				// Members may be parenthesized, as in MULTIPOINT((0 0),(1 1)), or not
+				m.kind = wkbPoint
+				var pt point
+				if p.peek() == '(' {
+					p.pos++
+					if pt, err = p.point(); err == nil {
+						err = p.expect(')')
+					}
+				} else {
+					pt, err = p.point()
+				}
+				m.points = []point{pt}
+			case wkbMultiLineString:
+				m.kind = wkbLineString
+				m.points, err = p.points()
+			case wkbMultiPolygon:
+				m.kind = wkbPolygon
+				m.rings, err = p.rings()
+			default:
+				m, err = p.geometry()
+			}
+			if err == nil {
+				err = m.validate()
+			}
+			g.geometries = append(g.geometries, m)
+			return err
+		})
+	}
+	if err != nil {
+		return geometry{}, err
+	}
+	if err := g.validate(); err != nil {
+		return geometry{}, err
+	}
+	return g, nil
+}
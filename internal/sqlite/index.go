@@ -0,0 +1,437 @@
+package sqlite
+
+import (
+	"context"
+	stdsql "database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/liquidata-inc/go-mysql-server/sql"
+	"github.com/pkg/errors"
+)
+
+// tableIndexesDDL creates the table recording the secondary indexes of each
+// table in the given SQLite schema.
+const tableIndexesDDL = `CREATE TABLE IF NOT EXISTS %s.mysqlite_table_indexes (
+	source TEXT NOT NULL, -- table name
+	name TEXT NOT NULL, -- index name
+	kind TEXT NOT NULL, -- FULLTEXT, UNIQUE or empty
+	columns TEXT NOT NULL, -- json array of column names
+	comment TEXT,
+	PRIMARY KEY (source, name)
+)`
+
+var _ sql.IndexAlterableTable = (*Table)(nil)
+
+// tableIndex is a secondary index. Ordinary and unique indexes are SQLite
+// indexes. Full-text indexes are FTS5 tables over the indexed columns, kept in
+// sync with the table by triggers so that every write path updates them.
+type tableIndex struct {
+	name    string
+	kind    string
+	columns []string
+	comment string
+}
+
+// errNoFulltextIndex mirrors MySQL's ER_FT_MATCHING_KEY_NOT_FOUND.
+var errNoFulltextIndex = errors.New("Can't find FULLTEXT index matching the column list")
+
+func (t *Table) schemaName() string {
+	if t.temporary {
+		return "temp"
+	}
+	return "main"
+}
+
+// sqliteIndexName is the name of the SQLite object backing an index: an index
+// for ordinary and unique indexes, an FTS5 table for full-text ones.
+func sqliteIndexName(table, index string) string {
+	return "mysqlite_index_" + table + "_" + index
+}
+
+// loadIndexes reads a table's indexes. Like loadTableOptions, the unqualified
+// lookup resolves to the temp schema on a session's pinned connection.
+func loadIndexes(ctx context.Context, c conn, tblName string) ([]tableIndex, error) {
+	rows, err := c.QueryContext(ctx, `SELECT name, kind, columns, comment FROM mysqlite_table_indexes WHERE source = ? COLLATE NOCASE ORDER BY rowid`, tblName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var indexes []tableIndex
+	for rows.Next() {
+		var (
+			idx     tableIndex
+			columns string
+			comment stdsql.NullString
+		)
+		if err := rows.Scan(&idx.name, &idx.kind, &columns, &comment); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(columns), &idx.columns); err != nil {
+			return nil, err
+		}
+		idx.comment = comment.String
+		indexes = append(indexes, idx)
+	}
+	return indexes, rows.Err()
+}
+
+func (t *Table) index(ctx context.Context, name string) (*tableIndex, error) {
+	indexes, err := loadIndexes(ctx, t.dbr, t.name)
+	if err != nil {
+		return nil, err
+	}
+	for _, idx := range indexes {
+		if strings.EqualFold(idx.name, name) {
+			return &idx, nil
+		}
+	}
+	return nil, nil
+}
+
+func (t *Table) CreateIndex(ctx *sql.Context, indexName string, using sql.IndexUsing, constraint sql.IndexConstraint, columns []sql.IndexColumn, comment string) error {
+	idx := tableIndex{name: indexName, comment: comment}
+	switch constraint {
+	case sql.IndexConstraint_None:
+	case sql.IndexConstraint_Unique:
+		idx.kind = "UNIQUE"
+	case sql.IndexConstraint_Fulltext:
+		idx.kind = "FULLTEXT"
+	default:
+		return errSpatialIndex
+	}
+	for _, col := range columns {
+		i := t.schema.IndexOf(col.Name, t.name)
+		if i < 0 {
+			return sql.ErrColumnNotFound.New(col.Name)
+		}
+		if idx.kind == "FULLTEXT" && !sql.IsText(t.schema[i].Type) {
+			return errors.Errorf("column '%s' cannot be part of FULLTEXT index", col.Name)
+		}
+		idx.columns = append(idx.columns, t.schema[i].Name)
+	}
+	if idx.name == "" {
+		idx.name = idx.columns[0]
+	}
+	// CREATE TABLE defines FULLTEXT indexes as plain ones, which vitess can
+	// parse
+	if idx.kind == "" && columnExtensionsFromQuery(ctx.Query()).fulltext[strings.ToLower(idx.name)] {
+		idx.kind = "FULLTEXT"
+	}
+
+	existing, err := t.index(ctx, idx.name)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return errors.Errorf("Duplicate key name '%s'", idx.name)
+	}
+
+	return inTx(ctx, t.dbw, func(tx *stdsql.Tx) error {
+		if err := t.createIndex(tx, idx); err != nil {
+			return err
+		}
+		b, err := json.Marshal(idx.columns)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(
+			`INSERT INTO `+t.schemaName()+`.mysqlite_table_indexes (source, name, kind, columns, comment) VALUES (?, ?, ?, ?, ?)`,
+			t.name, idx.name, idx.kind, string(b), idx.comment,
+		)
+		return err
+	})
+}
+
+// FULLTEXT {INDEX | KEY} [index_name] (key_part,...) in CREATE TABLE
+var fulltextDefinitionRegex = regexp.MustCompile("(?is)^(\\s*)fulltext(?:\\s+(?:index|key))?\\s*(`[^`]+`|\\w+)?\\s*\\(")
+
+// stripFulltext rewrites a FULLTEXT index definition in CREATE TABLE as a
+// plain index, returning the definition and the lower case name of the index,
+// or of its first column if it's unnamed, or an empty string if it defines
+// something else.
+func stripFulltext(def string) (string, string) {
+	m := fulltextDefinitionRegex.FindStringSubmatchIndex(def)
+	if m == nil {
+		return def, ""
+	}
+	var name string
+	rewritten := def[:m[3]] + "index "
+	if m[4] >= 0 {
+		name = strings.Trim(def[m[4]:m[5]], "`")
+		rewritten += def[m[4]:m[5]] + " "
+	}
+	rewritten += def[m[1]-1:]
+	if name == "" {
+		end := closingParen(def, m[1]-1)
+		if end < 0 {
+			return def, ""
+		}
+		name = columnName(strings.SplitN(def[m[1]:end], ",", 2)[0])
+	}
+	return rewritten, strings.ToLower(name)
+}
+
+// createIndex creates the SQLite objects backing an index.
+func (t *Table) createIndex(tx *stdsql.Tx, idx tableIndex) error {
+	var (
+		schemaName = t.schemaName()
+		name       = sqliteIndexName(t.name, idx.name)
+		cols       = make([]string, len(idx.columns))
+		newCols    = make([]string, len(idx.columns))
+		oldCols    = make([]string, len(idx.columns))
+	)
+	for i, col := range idx.columns {
+		cols[i] = `"` + col + `"`
+		newCols[i] = `new."` + col + `"`
+		oldCols[i] = `old."` + col + `"`
+	}
+
+	if idx.kind != "FULLTEXT" {
+		_, err := tx.Exec(fmt.Sprintf(`CREATE %s INDEX %s."%s" ON "%s" (%s)`, idx.kind, schemaName, name, t.name, strings.Join(cols, ", ")))
+		return err
+	}
+
+	if _, err := tx.Exec(fmt.Sprintf(
+		`CREATE VIRTUAL TABLE %s."%s" USING fts5(%s, content='%s', content_rowid='rowtime')`,
+		schemaName, name, strings.Join(cols, ", "), t.name,
+	)); err != nil {
+		if strings.Contains(err.Error(), "no such module") {
+			return errors.Errorf("FULLTEXT indexes require building with -tags sqlite_fts5: %s", err)
+		}
+		return err
+	}
+	var (
+		insert = fmt.Sprintf(`INSERT INTO "%s" (rowid, %s) VALUES (new.rowtime, %s);`, name, strings.Join(cols, ", "), strings.Join(newCols, ", "))
+		remove = fmt.Sprintf(`INSERT INTO "%s" ("%s", rowid, %s) VALUES ('delete', old.rowtime, %s);`, name, name, strings.Join(cols, ", "), strings.Join(oldCols, ", "))
+	)
+	for _, trigger := range []struct{ suffix, event, body string }{
+		{"ai", "INSERT", insert},
+		{"ad", "DELETE", remove},
+		{"au", "UPDATE", remove + " " + insert},
+	} {
+		if _, err := tx.Exec(fmt.Sprintf(
+			`CREATE TRIGGER %s."%s_%s" AFTER %s ON "%s" BEGIN %s END`,
+			schemaName, name, trigger.suffix, trigger.event, t.name, trigger.body,
+		)); err != nil {
+			return err
+		}
+	}
+	// Index the rows the table already holds
+	_, err := tx.Exec(fmt.Sprintf(`INSERT INTO %s."%s" ("%s") VALUES ('rebuild')`, schemaName, name, name))
+	return err
+}
+
+// dropIndex drops the SQLite objects backing an index, including the triggers
+// maintaining a full-text index.
+func dropIndex(tx *stdsql.Tx, schemaName, table string, idx tableIndex) error {
+	name := sqliteIndexName(table, idx.name)
+	if idx.kind != "FULLTEXT" {
+		_, err := tx.Exec(fmt.Sprintf(`DROP INDEX IF EXISTS %s."%s"`, schemaName, name))
+		return err
+	}
+	for _, suffix := range []string{"ai", "ad", "au"} {
+		if _, err := tx.Exec(fmt.Sprintf(`DROP TRIGGER IF EXISTS %s."%s_%s"`, schemaName, name, suffix)); err != nil {
+			return err
+		}
+	}
+	_, err := tx.Exec(fmt.Sprintf(`DROP TABLE IF EXISTS %s."%s"`, schemaName, name))
+	return err
+}
+
+// dropTableIndexes drops every index of a table that is itself being dropped.
+func dropTableIndexes(tx *stdsql.Tx, schemaName, table string) error {
+	rows, err := tx.Query(`SELECT name, kind FROM `+schemaName+`.mysqlite_table_indexes WHERE source = ? COLLATE NOCASE`, table)
+	if err != nil {
+		return err
+	}
+	var indexes []tableIndex
+	for rows.Next() {
+		var idx tableIndex
+		if err := rows.Scan(&idx.name, &idx.kind); err != nil {
+			rows.Close()
+			return err
+		}
+		indexes = append(indexes, idx)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, idx := range indexes {
+		if err := dropIndex(tx, schemaName, table, idx); err != nil {
+			return err
+		}
+	}
+	_, err = tx.Exec(`DELETE FROM `+schemaName+`.mysqlite_table_indexes WHERE source = ? COLLATE NOCASE`, table)
+	return err
+}
+
+func (t *Table) DropIndex(ctx *sql.Context, indexName string) error {
+	idx, err := t.index(ctx, indexName)
+	if err != nil {
+		return err
+	}
+	if idx == nil {
+		return sql.ErrIndexNotFound.New(indexName)
+	}
+	return inTx(ctx, t.dbw, func(tx *stdsql.Tx) error {
+		if err := dropIndex(tx, t.schemaName(), t.name, *idx); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`DELETE FROM `+t.schemaName()+`.mysqlite_table_indexes WHERE source = ? COLLATE NOCASE AND name = ?`, t.name, idx.name)
+		return err
+	})
+}
+
+// RenameIndex recreates the index under its new name, as SQLite can rename
+// neither indexes nor the triggers maintaining full-text indexes.
+func (t *Table) RenameIndex(ctx *sql.Context, fromIndexName string, toIndexName string) error {
+	idx, err := t.index(ctx, fromIndexName)
+	if err != nil {
+		return err
+	}
+	if idx == nil {
+		return sql.ErrIndexNotFound.New(fromIndexName)
+	}
+	existing, err := t.index(ctx, toIndexName)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return errors.Errorf("Duplicate key name '%s'", toIndexName)
+	}
+	return inTx(ctx, t.dbw, func(tx *stdsql.Tx) error {
+		if err := dropIndex(tx, t.schemaName(), t.name, *idx); err != nil {
+			return err
+		}
+		renamed := *idx
+		renamed.name = toIndexName
+		if err := t.createIndex(tx, renamed); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`UPDATE `+t.schemaName()+`.mysqlite_table_indexes SET name = ? WHERE source = ? COLLATE NOCASE AND name = ?`, toIndexName, t.name, idx.name)
+		return err
+	})
+}
+
+// Match runs a MATCH (columns) AGAINST (against) search over the full-text index
+// on exactly the given columns, returning the relevance of each matching row
+// keyed by rowtime. Rows that don't match are absent.
+func (t *Table) Match(ctx *sql.Context, columns []string, against string, booleanMode bool) (map[int64]float64, error) {
+	indexes, err := loadIndexes(ctx, t.dbr, t.name)
+	if err != nil {
+		return nil, err
+	}
+	var fts string
+	for _, idx := range indexes {
+		if idx.kind == "FULLTEXT" && sameColumns(idx.columns, columns) {
+			fts = sqliteIndexName(t.name, idx.name)
+			break
+		}
+	}
+	if fts == "" {
+		return nil, errNoFulltextIndex
+	}
+
+	scores := map[int64]float64{}
+	query := ftsQuery(against, booleanMode)
+	if query == "" {
+		return scores, nil
+	}
+	// FTS5 ranks with bm25, where more relevant rows score lower and below zero.
+	// Scores start at 1 because go-mysql-server rounds floats in conditions, and
+	// WHERE MATCH ... AGAINST must hold for every matching row.
+	rows, err := t.dbr.QueryContext(ctx, `SELECT rowid, 1 - rank FROM "`+fts+`" WHERE "`+fts+`" MATCH ?`, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			rowtime int64
+			score   float64
+		)
+		if err := rows.Scan(&rowtime, &score); err != nil {
+			return nil, err
+		}
+		scores[rowtime] = score
+	}
+	return scores, rows.Err()
+}
+
+func sameColumns(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	seen := map[string]bool{}
+	for _, col := range a {
+		seen[strings.ToLower(col)] = true
+	}
+	for _, col := range b {
+		if !seen[strings.ToLower(strings.TrimSpace(col))] {
+			return false
+		}
+	}
+	return true
+}
+
+var (
+	ftsWordRegex    = regexp.MustCompile(`[\pL\pN_]+`)
+	ftsBooleanRegex = regexp.MustCompile(`([+\-~<>]*)("[^"]*"|[\pL\pN_]+\*?)`)
+)
+
+// ftsQuery translates a MATCH ... AGAINST search string to an FTS5 query. In
+// natural language mode any of the words may match. In boolean mode words
+// marked + must match, words marked - must not, and the rest may match;
+// trailing * and "quoted phrases" carry over as prefix and phrase queries.
+// Operators that only adjust MySQL's ranking are ignored. An empty query
+// matches nothing.
+func ftsQuery(against string, booleanMode bool) string {
+	if !booleanMode {
+		words := ftsWordRegex.FindAllString(against, -1)
+		for i, word := range words {
+			words[i] = `"` + word + `"`
+		}
+		return strings.Join(words, " OR ")
+	}
+
+	var required, optional, excluded []string
+	for _, m := range ftsBooleanRegex.FindAllStringSubmatch(against, -1) {
+		ops, term := m[1], m[2]
+		switch {
+		case strings.HasPrefix(term, `"`):
+			term = `"` + strings.Join(ftsWordRegex.FindAllString(term, -1), " ") + `"`
+			if term == `""` {
+				continue
+			}
+		case strings.HasSuffix(term, "*"):
+			term = `"` + strings.TrimSuffix(term, "*") + `"*`
+		default:
+			term = `"` + term + `"`
+		}
+		switch {
+		case strings.Contains(ops, "-"):
+			excluded = append(excluded, term)
+		case strings.Contains(ops, "+"):
+			required = append(required, term)
+		default:
+			optional = append(optional, term)
+		}
+	}
+
+	// With required words present, optional ones only affect ranking
+	query := strings.Join(required, " AND ")
+	if query == "" {
+		query = strings.Join(optional, " OR ")
+	}
+	if query == "" {
+		return ""
+	}
+	for _, term := range excluded {
+		query = "(" + query + ") NOT " + term
+	}
+	return query
+}
@@ -0,0 +1,48 @@
+package sqlite
+
+import "testing"
+
+func TestFtsQuery(t *testing.T) {
+	tests := []struct {
+		against string
+		boolean bool
+		query   string
+	}{
+		{"quick brown fox", false, `"quick" OR "brown" OR "fox"`},
+		{"+quick, -fox!", false, `"quick" OR "fox"`},
+		{"", false, ""},
+		{"quick brown", true, `"quick" OR "brown"`},
+		{"+quick +fox", true, `"quick" AND "fox"`},
+		{"+quick brown", true, `"quick"`},
+		{"+quick -lazy -dog", true, `(("quick") NOT "lazy") NOT "dog"`},
+		{"quick -lazy", true, `("quick") NOT "lazy"`},
+		{"qui*", true, `"qui"*`},
+		{`+"brown fox" >quick <lazy ~dog`, true, `"brown fox"`},
+		{`"" quick`, true, `"quick"`},
+		{"-lazy", true, ""},
+	}
+	for _, tt := range tests {
+		if query := ftsQuery(tt.against, tt.boolean); query != tt.query {
+			t.Errorf("ftsQuery(%q, %v) = %q, want %q", tt.against, tt.boolean, query, tt.query)
+		}
+	}
+}
+
+func TestStripFulltext(t *testing.T) {
+	tests := []struct {
+		def, rewritten, name string
+	}{
+		{" FULLTEXT KEY ft (msg)", " index ft (msg)", "ft"},
+		{"\n  FULLTEXT INDEX `Full Text` (`title`,`body`)", "\n  index `Full Text` (`title`,`body`)", "full text"},
+		{" fulltext (Body, title)", " index (Body, title)", "body"},
+		{" FULLTEXT(`msg`)", " index (`msg`)", "msg"},
+		{" fulltext_col TEXT", " fulltext_col TEXT", ""},
+		{" KEY k (msg)", " KEY k (msg)", ""},
+	}
+	for _, tt := range tests {
+		rewritten, name := stripFulltext(tt.def)
+		if rewritten != tt.rewritten || name != tt.name {
+			t.Errorf("stripFulltext(%q) = %q, %q, want %q, %q", tt.def, rewritten, name, tt.rewritten, tt.name)
+		}
+	}
+}
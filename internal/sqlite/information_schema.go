@@ -0,0 +1,218 @@
+package sqlite
+
+import (
+	"strings"
+
+	"github.com/liquidata-inc/go-mysql-server/sql"
+)
+
+// Visible reports whether the session's user may see the table of db, or db
+// itself if table is empty, in INFORMATION_SCHEMA.
+type Visible func(ctx *sql.Context, db, table string) bool
+
+// informationSchemaDatabase wraps go-mysql-server's INFORMATION_SCHEMA so that
+// the rows it reports for mysqlite tables reflect what was actually stored for
+// them, rather than the hardcoded defaults it uses for every table, and only
+// describe tables the user may see.
+type informationSchemaDatabase struct {
+	sql.Database
+	catalog *sql.Catalog
+	visible Visible
+}
+
+// NewInformationSchemaDatabase creates an INFORMATION_SCHEMA database for cat
+// that knows about mysqlite tables. If visible isn't nil, rows about other
+// databases and tables are left out, as MySQL leaves out those the user has no
+// privileges on.
+func NewInformationSchemaDatabase(cat *sql.Catalog, visible Visible) sql.Database {
+	return &informationSchemaDatabase{
+		Database: sql.NewInformationSchemaDatabase(cat),
+		catalog:  cat,
+		visible:  visible,
+	}
+}
+
+func (db *informationSchemaDatabase) GetTableInsensitive(ctx *sql.Context, tblName string) (sql.Table, bool, error) {
+	table, ok, err := db.Database.GetTableInsensitive(ctx, tblName)
+	if err != nil || !ok {
+		return table, ok, err
+	}
+	if strings.EqualFold(tblName, sql.TablesTableName) {
+		table = &tablesTable{Table: table, catalog: db.catalog}
+	}
+	if db.visible != nil {
+		table = newVisibleTable(table, db.visible)
+	}
+	return table, true, nil
+}
+
+// visibleTable is an INFORMATION_SCHEMA table whose rows about databases and
+// tables the user can't see are left out.
+type visibleTable struct {
+	sql.Table
+	visible  Visible
+	dbIdx    int
+	tableIdx int
+}
+
+// newVisibleTable returns t with its rows filtered by visible, or t itself if
+// its rows aren't about databases.
+func newVisibleTable(t sql.Table, visible Visible) sql.Table {
+	vt := &visibleTable{Table: t, visible: visible, dbIdx: -1, tableIdx: -1}
+	for i, col := range t.Schema() {
+		switch strings.ToLower(col.Name) {
+		case "table_schema", "schema_name", "constraint_schema", "trigger_schema", "event_schema", "routine_schema":
+			if vt.dbIdx < 0 {
+				vt.dbIdx = i
+			}
+		case "table_name":
+			vt.tableIdx = i
+		}
+	}
+	if vt.dbIdx < 0 {
+		return t
+	}
+	return vt
+}
+
+func (t *visibleTable) PartitionRows(ctx *sql.Context, partition sql.Partition) (sql.RowIter, error) {
+	iter, err := t.Table.PartitionRows(ctx, partition)
+	if err != nil {
+		return nil, err
+	}
+	return &visibleRowIter{ctx: ctx, iter: iter, t: t}, nil
+}
+
+type visibleRowIter struct {
+	ctx  *sql.Context
+	iter sql.RowIter
+	t    *visibleTable
+}
+
+func (i *visibleRowIter) Next() (sql.Row, error) {
+	for {
+		row, err := i.iter.Next()
+		if err != nil {
+			return nil, err
+		}
+		db := rowString(row, i.t.dbIdx)
+		if strings.EqualFold(db, "information_schema") {
+			return row, nil
+		}
+		if i.t.visible(i.ctx, db, rowString(row, i.t.tableIdx)) {
+			return row, nil
+		}
+	}
+}
+
+func (i *visibleRowIter) Close() error {
+	return i.iter.Close()
+}
+
+// rowString returns the text of row[idx], or "" if idx is out of range.
+func rowString(row sql.Row, idx int) string {
+	if idx < 0 || idx >= len(row) {
+		return ""
+	}
+	switch v := row[idx].(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	return ""
+}
+
+// tablesTable is INFORMATION_SCHEMA.TABLES.
+type tablesTable struct {
+	sql.Table
+	catalog *sql.Catalog
+}
+
+func (t *tablesTable) PartitionRows(ctx *sql.Context, partition sql.Partition) (sql.RowIter, error) {
+	iter, err := t.Table.PartitionRows(ctx, partition)
+	if err != nil {
+		return nil, err
+	}
+	return &tablesRowIter{ctx: ctx, iter: iter, catalog: t.catalog}, nil
+}
+
+// Positions of the INFORMATION_SCHEMA.TABLES columns that are patched
+const (
+	tablesSchemaIdx     = 1
+	tablesNameIdx       = 2
+	tablesEngineIdx     = 4
+	tablesRowFormatIdx  = 6
+	tablesRowsIdx       = 7
+	tablesAvgRowIdx     = 8
+	tablesDataIdx       = 9
+	tablesIndexIdx      = 11
+	tablesCollationIdx  = 17
+	tablesCommentIdx    = 20
+	tablesColumnsLength = 21
+)
+
+type tablesRowIter struct {
+	ctx     *sql.Context
+	iter    sql.RowIter
+	catalog *sql.Catalog
+}
+
+func (i *tablesRowIter) Next() (sql.Row, error) {
+	row, err := i.iter.Next()
+	if err != nil {
+		return nil, err
+	}
+	if len(row) != tablesColumnsLength {
+		return row, nil
+	}
+	dbName, _ := row[tablesSchemaIdx].(string)
+	tblName, _ := row[tablesNameIdx].(string)
+	table, err := i.catalog.Table(i.ctx, dbName, tblName)
+	if err != nil {
+		// views and tables of other databases are left as they are
+		return row, nil
+	}
+	t, ok := table.(*Table)
+	if !ok {
+		return row, nil
+	}
+
+	opts, err := t.TableOptions(i.ctx)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Engine != "" {
+		row[tablesEngineIdx] = strings.ToUpper(opts.Engine)
+	}
+	if opts.RowFormat != "" {
+		row[tablesRowFormatIdx] = strings.Title(strings.ToLower(opts.RowFormat))
+	}
+	row[tablesCollationIdx] = opts.Collation()
+	row[tablesCommentIdx] = opts.Comment
+
+	rows, err := t.NumRows(i.ctx)
+	if err != nil {
+		return nil, err
+	}
+	data, err := t.DataLength(i.ctx)
+	if err != nil {
+		return nil, err
+	}
+	index, err := t.IndexLength(i.ctx)
+	if err != nil {
+		return nil, err
+	}
+	row[tablesRowsIdx] = rows
+	row[tablesDataIdx] = data
+	row[tablesIndexIdx] = index
+	row[tablesAvgRowIdx] = uint64(0)
+	if rows > 0 {
+		row[tablesAvgRowIdx] = data / rows
+	}
+	return row, nil
+}
+
+func (i *tablesRowIter) Close() error {
+	return i.iter.Close()
+}
@@ -0,0 +1,74 @@
+package sqlite
+
+import (
+	stdsql "database/sql"
+	"io"
+
+	"github.com/liquidata-inc/go-mysql-server/sql"
+)
+
+// How BulkLoad treats rows that duplicate a unique key, as chosen by the
+// REPLACE and IGNORE keywords of LOAD DATA.
+const (
+	OnDuplicateError   = ""
+	OnDuplicateIgnore  = "IGNORE"
+	OnDuplicateReplace = "REPLACE"
+)
+
+// BulkLoadableTable is a table that can load many rows at once, as LOAD DATA
+// does.
+type BulkLoadableTable interface {
+	sql.Table
+	// BulkLoad inserts the rows next returns until it returns io.EOF.
+	BulkLoad(ctx *sql.Context, onDuplicate string, next func() (sql.Row, error)) (inserted, skipped int, err error)
+}
+
+// BulkLoad inserts the rows next returns until it returns io.EOF, executing a
+// single prepared statement in a single transaction. Rows are in schema order
+// and, as when the engine inserts them, those without a rowtime are given the
+// current time and values are checked against the session's sql_mode. It
+// returns how many rows were inserted, and how many were skipped as duplicates
+// with OnDuplicateIgnore. Nothing is inserted if any row fails.
+func (t *Table) BulkLoad(ctx *sql.Context, onDuplicate string, next func() (sql.Row, error)) (inserted, skipped int, err error) {
+	tx, err := t.dbw.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var stmt *stdsql.Stmt
+	for n := 1; ; n++ {
+		row, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return inserted, skipped, err
+		}
+		cols, args, err := t.insertValues(ctx, row, n)
+		if err != nil {
+			return inserted, skipped, err
+		}
+		if stmt == nil {
+			// Every row writes the same columns
+			if stmt, err = tx.PrepareContext(ctx, t.insertStatement(onDuplicate, cols)); err != nil {
+				return inserted, skipped, err
+			}
+			defer stmt.Close()
+		}
+		res, err := stmt.ExecContext(ctx, args...)
+		if err != nil {
+			return inserted, skipped, err
+		}
+		if n, err := res.RowsAffected(); err == nil && n == 0 {
+			skipped++
+		} else {
+			inserted++
+		}
+	}
+	return inserted, skipped, tx.Commit()
+}
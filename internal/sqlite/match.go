@@ -0,0 +1,260 @@
+package sqlite
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/liquidata-inc/go-mysql-server/sql"
+	"github.com/liquidata-inc/vitess/go/vt/sqlparser"
+	"github.com/pkg/errors"
+)
+
+// MatchFunctionName is the function the mysqlite server rewrites
+// MATCH (columns) AGAINST (expr [modifier]) to, which go-mysql-server can't
+// parse. It is called as
+//
+//	mysqlite_match('db', 'table', 'col1,col2', expr, 'natural'|'boolean', tbl.rowtime)
+//
+// and returns the relevance of the row, which is 0 for rows that don't match.
+const MatchFunctionName = "mysqlite_match"
+
+// MatchFunction returns the MatchFunctionName function, which searches the
+// full-text indexes of tables found in cat.
+func MatchFunction(cat *sql.Catalog) sql.Function {
+	return sql.FunctionN{
+		Name: MatchFunctionName,
+		Fn: func(args ...sql.Expression) (sql.Expression, error) {
+			if len(args) != 6 {
+				return nil, sql.ErrInvalidArgumentNumber.New(MatchFunctionName, 6, len(args))
+			}
+			return &match{cat: cat, args: args, scores: &matchScores{}}, nil
+		},
+	}
+}
+
+// match is a MATCH ... AGAINST search. The search runs once per query, the
+// first time the expression is evaluated, and each row then looks up its score.
+type match struct {
+	cat    *sql.Catalog
+	args   []sql.Expression
+	scores *matchScores
+}
+
+type matchScores struct {
+	once   sync.Once
+	err    error
+	scores map[int64]float64
+}
+
+var _ sql.Expression = (*match)(nil)
+
+func (m *match) Resolved() bool {
+	for _, arg := range m.args {
+		if !arg.Resolved() {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *match) String() string {
+	args := make([]string, len(m.args))
+	for i, arg := range m.args {
+		args[i] = arg.String()
+	}
+	return fmt.Sprintf("%s(%s)", MatchFunctionName, strings.Join(args, ", "))
+}
+
+func (m *match) Type() sql.Type { return sql.Float64 }
+
+func (m *match) IsNullable() bool { return false }
+
+func (m *match) Children() []sql.Expression { return m.args }
+
+func (m *match) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != len(m.args) {
+		return nil, sql.ErrInvalidChildrenNumber.New(m, len(children), len(m.args))
+	}
+	return &match{cat: m.cat, args: children, scores: m.scores}, nil
+}
+
+func (m *match) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
+	m.scores.once.Do(func() {
+		m.scores.scores, m.scores.err = m.search(ctx)
+	})
+	if m.scores.err != nil {
+		return nil, m.scores.err
+	}
+
+	rowtime, err := m.args[5].Eval(ctx, row)
+	if err != nil {
+		return nil, err
+	}
+	rowtime, err = sql.Int64.Convert(rowtime)
+	if err != nil || rowtime == nil {
+		return float64(0), err
+	}
+	return m.scores.scores[rowtime.(int64)], nil
+}
+
+func (m *match) search(ctx *sql.Context) (map[int64]float64, error) {
+	var params [5]string
+	for i := range params {
+		v, err := m.args[i].Eval(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		if v == nil {
+			return map[int64]float64{}, nil
+		}
+		params[i] = fmt.Sprint(v)
+	}
+	db, table, columns, against, mode := params[0], params[1], params[2], params[3], params[4]
+	if db == "" {
+		db = ctx.GetCurrentDatabase()
+	}
+
+	t, err := m.cat.Table(ctx, db, table)
+	if err != nil {
+		return nil, err
+	}
+	st, ok := t.(*Table)
+	if !ok {
+		return nil, errors.Errorf("MATCH ... AGAINST is not supported on table %s", t.Name())
+	}
+	return st.Match(ctx, strings.Split(columns, ","), against, mode == "boolean")
+}
+
+var (
+	matchStartRegex   = regexp.MustCompile(`(?i)^match\s*\(`)
+	againstStartRegex = regexp.MustCompile(`(?i)^\s*against\s*\(`)
+)
+
+// RewriteMatchAgainst rewrites each MATCH ... AGAINST in a statement to a call
+// to MatchFunctionName, returning an empty string if there are none. vitess
+// parses MATCH but go-mysql-server can't convert it, so the statement is parsed
+// only to find the table each search runs against, and the searches are then
+// replaced in the statement text.
+func RewriteMatchAgainst(query string) (string, error) {
+	stmt, err := sqlparser.Parse(query)
+	if err != nil {
+		// leave it to the engine to report
+		return "", nil
+	}
+
+	var (
+		tables  = map[string]sqlparser.TableName{} // by alias
+		matches []*sqlparser.MatchExpr
+	)
+	_ = sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		switch node := node.(type) {
+		case *sqlparser.AliasedTableExpr:
+			if name, ok := node.Expr.(sqlparser.TableName); ok {
+				alias := node.As.String()
+				if alias == "" {
+					alias = name.Name.String()
+				}
+				tables[strings.ToLower(alias)] = name
+			}
+		case *sqlparser.MatchExpr:
+			matches = append(matches, node)
+		}
+		return true, nil
+	}, stmt)
+	if len(matches) == 0 {
+		return "", nil
+	}
+
+	calls := make([]string, len(matches))
+	for i, m := range matches {
+		var (
+			alias   string
+			columns []string
+		)
+		for _, se := range m.Columns {
+			ae, ok := se.(*sqlparser.AliasedExpr)
+			if !ok {
+				return "", errors.Errorf("unsupported MATCH column: %s", sqlparser.String(se))
+			}
+			col, ok := ae.Expr.(*sqlparser.ColName)
+			if !ok {
+				return "", errors.Errorf("unsupported MATCH column: %s", sqlparser.String(ae.Expr))
+			}
+			if q := col.Qualifier.Name.String(); q != "" {
+				alias = q
+			}
+			columns = append(columns, col.Name.String())
+		}
+		if alias == "" {
+			if len(tables) != 1 {
+				return "", errors.Errorf("ambiguous MATCH columns: %s", sqlparser.String(m))
+			}
+			for a := range tables {
+				alias = a
+			}
+		}
+		table, ok := tables[strings.ToLower(alias)]
+		if !ok {
+			return "", sql.ErrTableNotFound.New(alias)
+		}
+		mode := "natural"
+		if m.Option == sqlparser.BooleanModeStr {
+			mode = "boolean"
+		}
+		calls[i] = fmt.Sprintf("%s('%s', '%s', '%s', %s, '%s', `%s`.`rowtime`)",
+			MatchFunctionName,
+			table.Qualifier.String(),
+			table.Name.String(),
+			strings.Join(columns, ","),
+			sqlparser.String(m.Expr),
+			mode,
+			alias,
+		)
+	}
+
+	// Replace the searches in the order vitess found them, which is the order
+	// they appear in the statement
+	var (
+		b    strings.Builder
+		last int
+		n    int
+		skip = -1
+	)
+	scanSQL(query, func(i, depth int) bool {
+		if i < skip || n == len(matches) {
+			return true
+		}
+		if loc := matchStartRegex.FindStringIndex(query[i:]); loc == nil || (i > 0 && isIdentByte(query[i-1])) {
+			return true
+		}
+		end := closingParen(query, i+strings.Index(query[i:], "("))
+		if end < 0 {
+			return true
+		}
+		loc := againstStartRegex.FindStringIndex(query[end+1:])
+		if loc == nil {
+			return true
+		}
+		end = closingParen(query, end+loc[1])
+		if end < 0 {
+			return true
+		}
+		b.WriteString(query[last:i])
+		b.WriteString(calls[n])
+		last, skip = end+1, end+1
+		n++
+		return true
+	})
+	if n != len(matches) {
+		return "", errors.Errorf("unable to rewrite MATCH ... AGAINST in: %s", query)
+	}
+	b.WriteString(query[last:])
+	return b.String(), nil
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '$' || c == '.' || c == '`' ||
+		('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
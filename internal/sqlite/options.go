@@ -0,0 +1,142 @@
+package sqlite
+
+import (
+	stdsql "database/sql"
+	"flag"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Options configures the SQLite connections a Database opens. Pragmas left at
+// their zero value are not set, so SQLite's (or the DSN's) defaults apply.
+type Options struct {
+	// JournalMode sets PRAGMA journal_mode, e.g. "WAL" to let readers proceed
+	// while a write is in progress.
+	JournalMode string `yaml:"journal_mode"`
+	// Synchronous sets PRAGMA synchronous, e.g. "NORMAL" or "FULL".
+	Synchronous string `yaml:"synchronous"`
+	// BusyTimeout sets PRAGMA busy_timeout, how long a connection waits on a
+	// locked database before failing with "database is locked".
+	BusyTimeout time.Duration `yaml:"busy_timeout"`
+	// CacheSize sets PRAGMA cache_size. Positive values are pages, negative
+	// values are KiB.
+	CacheSize int `yaml:"cache_size"`
+	// MmapSize sets PRAGMA mmap_size in bytes.
+	MmapSize int64 `yaml:"mmap_size"`
+
+	// MaxReaders and MaxIdleReaders size the read pool. The write pool always
+	// has a single connection since SQLite allows only one writer at a time.
+	MaxReaders     int `yaml:"max_readers"`
+	MaxIdleReaders int `yaml:"max_idle_readers"`
+}
+
+// DefaultOptions are the options used by NewDatabase.
+func DefaultOptions() Options {
+	return Options{
+		MaxReaders:     50,
+		MaxIdleReaders: 10,
+	}
+}
+
+// AddFlags registers command line flags for the options on fs, using the current
+// values of o as defaults.
+func (o *Options) AddFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.JournalMode, "journal-mode", o.JournalMode, "SQLite journal mode (e.g. WAL, DELETE).")
+	fs.StringVar(&o.Synchronous, "synchronous", o.Synchronous, "SQLite synchronous level (OFF, NORMAL, FULL, EXTRA).")
+	fs.DurationVar(&o.BusyTimeout, "busy-timeout", o.BusyTimeout, "How long to wait on a locked SQLite database.")
+	fs.IntVar(&o.CacheSize, "cache-size", o.CacheSize, "SQLite cache size in pages, or KiB if negative.")
+	fs.Int64Var(&o.MmapSize, "mmap-size", o.MmapSize, "SQLite memory-mapped I/O size in bytes.")
+	fs.IntVar(&o.MaxReaders, "max-readers", o.MaxReaders, "Maximum number of open SQLite read connections.")
+	fs.IntVar(&o.MaxIdleReaders, "max-idle-readers", o.MaxIdleReaders, "Maximum number of idle SQLite read connections.")
+}
+
+// Validate reports an error if o has values SQLite doesn't accept.
+func (o Options) Validate() error {
+	switch strings.ToUpper(o.JournalMode) {
+	case "", "DELETE", "TRUNCATE", "PERSIST", "MEMORY", "WAL", "OFF":
+	default:
+		return fmt.Errorf("unknown journal mode %q", o.JournalMode)
+	}
+	switch strings.ToUpper(o.Synchronous) {
+	case "", "OFF", "NORMAL", "FULL", "EXTRA", "0", "1", "2", "3":
+	default:
+		return fmt.Errorf("unknown synchronous level %q", o.Synchronous)
+	}
+	if o.BusyTimeout < 0 {
+		return fmt.Errorf("negative busy timeout %s", o.BusyTimeout)
+	}
+	if o.MmapSize < 0 {
+		return fmt.Errorf("negative mmap size %d", o.MmapSize)
+	}
+	if o.MaxReaders < 1 {
+		return fmt.Errorf("max readers must be at least 1, not %d", o.MaxReaders)
+	}
+	if o.MaxIdleReaders < 0 {
+		return fmt.Errorf("negative max idle readers %d", o.MaxIdleReaders)
+	}
+	return nil
+}
+
+// pragmas returns the statements that apply o to a new connection.
+func (o Options) pragmas() []string {
+	var pragmas []string
+	if o.JournalMode != "" {
+		pragmas = append(pragmas, "PRAGMA journal_mode = "+o.JournalMode)
+	}
+	if o.Synchronous != "" {
+		pragmas = append(pragmas, "PRAGMA synchronous = "+o.Synchronous)
+	}
+	if o.BusyTimeout > 0 {
+		pragmas = append(pragmas, fmt.Sprintf("PRAGMA busy_timeout = %d", o.BusyTimeout.Milliseconds()))
+	}
+	if o.CacheSize != 0 {
+		pragmas = append(pragmas, fmt.Sprintf("PRAGMA cache_size = %d", o.CacheSize))
+	}
+	if o.MmapSize != 0 {
+		pragmas = append(pragmas, fmt.Sprintf("PRAGMA mmap_size = %d", o.MmapSize))
+	}
+	return pragmas
+}
+
+var (
+	driversMu sync.Mutex
+	drivers   = map[Options]string{}
+)
+
+// registerDriver returns the name of a sqlite3 driver that prepares every
+// connection it opens according to o, with the MySQL collations and functions
+// registered. database/sql only lets a driver be registered once and never
+// forgets one, so each distinct Options is registered the first time it's seen
+// and its driver shared by the databases opened with it after that.
+func registerDriver(o Options) string {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+	if name, ok := drivers[o]; ok {
+		return name
+	}
+
+	pragmas := o.pragmas()
+	name := fmt.Sprintf("mysqlite_sqlite3_%d", len(drivers)+1)
+	stdsql.Register(name, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			if err := registerCollations(conn); err != nil {
+				return err
+			}
+			if err := registerFunctions(conn); err != nil {
+				return err
+			}
+			for _, pragma := range pragmas {
+				if _, err := conn.Exec(pragma, nil); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+	drivers[o] = name
+	return name
+}
@@ -0,0 +1,796 @@
+package sqlite
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/liquidata-inc/go-mysql-server/sql"
+	"github.com/liquidata-inc/vitess/go/vt/sqlparser"
+	"github.com/shopspring/decimal"
+)
+
+// TranslateSelect translates a SELECT whose tables all belong to the database
+// into a single SQLite statement, so that its joins, aggregates, sorting and
+// limit run in SQLite rather than in the engine. It reports false if the
+// statement uses anything that can't be translated with the same semantics, in
+// which case the engine has to run it.
+func (db *Database) TranslateSelect(ctx *sql.Context, sel *sqlparser.Select) (string, bool) {
+	tr := &selectTranslator{ctx: ctx, db: db}
+	return tr.selectStatement(sel)
+}
+
+// Passthrough runs a statement produced by TranslateSelect, returning its rows
+// as values of schema, the result schema the engine gives the same SELECT.
+func (db *Database) Passthrough(ctx *sql.Context, query string, schema sql.Schema) (sql.RowIter, error) {
+	var c conn = db.r
+	// The session's connection sees its temporary tables as well as the rest
+	// of the database
+	if s := db.session(ctx); s != nil {
+		c = s.conn
+	}
+	rows, err := c.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	cols, err := rows.Columns()
+	if err != nil {
+		rows.Close()
+		return nil, err
+	}
+	if len(cols) != len(schema) {
+		rows.Close()
+		return nil, fmt.Errorf("passthrough query returned %d columns, expected %d: %s", len(cols), len(schema), query)
+	}
+	return &rowIter{schema: schema, rows: rows}, nil
+}
+
+// selectTranslator translates a SELECT statement to SQLite, resolving its
+// tables and columns against the database.
+type selectTranslator struct {
+	ctx    *sql.Context
+	db     *Database
+	tables []scopeTable
+	// aliases are the lowercased aliases of the select list, which GROUP BY,
+	// HAVING and ORDER BY can refer to, mapped to the type of the encoded value
+	// they alias, or nil
+	aliases map[string]sql.Type
+}
+
+// scopeTable is a table in the FROM clause, named by its alias if it has one.
+type scopeTable struct {
+	name  string
+	table *Table
+}
+
+func (tr *selectTranslator) selectStatement(sel *sqlparser.Select) (string, bool) {
+	// Locking reads and hints like SQL_CALC_FOUND_ROWS are left to the engine
+	if sel.Lock != "" || (sel.Hints != "" && sel.Hints != sqlparser.StraightJoinHint) {
+		return "", false
+	}
+
+	from := make([]string, len(sel.From))
+	for i, te := range sel.From {
+		s, ok := tr.tableExpr(te)
+		if !ok {
+			return "", false
+		}
+		from[i] = s
+	}
+
+	tr.aliases = make(map[string]sql.Type)
+	for _, se := range sel.SelectExprs {
+		if ae, ok := se.(*sqlparser.AliasedExpr); ok && !ae.As.IsEmpty() {
+			_, t, _ := tr.encodedExpr(ae.Expr, false)
+			tr.aliases[ae.As.Lowered()] = t
+		}
+	}
+	var exprs []string
+	for _, se := range sel.SelectExprs {
+		switch se := se.(type) {
+		case *sqlparser.StarExpr:
+			cols, ok := tr.star(se)
+			if !ok {
+				return "", false
+			}
+			exprs = append(exprs, cols...)
+		case *sqlparser.AliasedExpr:
+			s, _, ok := tr.encodedExpr(se.Expr, false)
+			if !ok {
+				s, ok = tr.expr(se.Expr, false)
+			}
+			if !ok {
+				return "", false
+			}
+			if !se.As.IsEmpty() {
+				s += " AS " + quoteIdent(se.As.String())
+			}
+			exprs = append(exprs, s)
+		default:
+			return "", false
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("SELECT ")
+	if sel.Distinct == sqlparser.DistinctStr {
+		b.WriteString("DISTINCT ")
+	}
+	b.WriteString(strings.Join(exprs, ", "))
+	b.WriteString(" FROM " + strings.Join(from, ", "))
+
+	if sel.Where != nil {
+		s, ok := tr.expr(sel.Where.Expr, false)
+		if !ok {
+			return "", false
+		}
+		b.WriteString(" WHERE " + s)
+	}
+	if len(sel.GroupBy) > 0 {
+		groups := make([]string, len(sel.GroupBy))
+		for i, e := range sel.GroupBy {
+			s, _, ok := tr.encodedExpr(e, true)
+			if !ok {
+				s, ok = tr.expr(e, true)
+			}
+			if !ok {
+				return "", false
+			}
+			groups[i] = s
+		}
+		b.WriteString(" GROUP BY " + strings.Join(groups, ", "))
+	}
+	if sel.Having != nil {
+		s, ok := tr.expr(sel.Having.Expr, true)
+		if !ok {
+			return "", false
+		}
+		b.WriteString(" HAVING " + s)
+	}
+	if len(sel.OrderBy) > 0 {
+		orders := make([]string, len(sel.OrderBy))
+		for i, o := range sel.OrderBy {
+			s, _, ok := tr.encodedExpr(o.Expr, true)
+			if !ok {
+				s, ok = tr.expr(o.Expr, true)
+			}
+			if !ok {
+				return "", false
+			}
+			orders[i] = s + " " + strings.ToUpper(o.Direction)
+		}
+		b.WriteString(" ORDER BY " + strings.Join(orders, ", "))
+	}
+	if sel.Limit != nil {
+		// Placeholders and expressions are left to the engine
+		count, ok := intLiteral(sel.Limit.Rowcount)
+		if !ok {
+			return "", false
+		}
+		b.WriteString(" LIMIT " + count)
+		if sel.Limit.Offset != nil {
+			offset, ok := intLiteral(sel.Limit.Offset)
+			if !ok {
+				return "", false
+			}
+			b.WriteString(" OFFSET " + offset)
+		}
+	}
+	return b.String(), true
+}
+
+func (tr *selectTranslator) tableExpr(te sqlparser.TableExpr) (string, bool) {
+	switch te := te.(type) {
+	case *sqlparser.AliasedTableExpr:
+		name, ok := te.Expr.(sqlparser.TableName)
+		if !ok || te.Partitions != nil || te.AsOf != nil {
+			return "", false
+		}
+		if !name.Qualifier.IsEmpty() && !strings.EqualFold(name.Qualifier.String(), tr.db.name) {
+			return "", false
+		}
+		table, ok, err := tr.db.GetTableInsensitive(tr.ctx, name.Name.String())
+		if err != nil || !ok {
+			return "", false
+		}
+		st := scopeTable{name: strings.ToLower(name.Name.String()), table: table.(*Table)}
+		s := quoteIdent(st.table.name)
+		if !te.As.IsEmpty() {
+			st.name = strings.ToLower(te.As.String())
+			s += " AS " + quoteIdent(st.name)
+		}
+		for _, t := range tr.tables {
+			if t.name == st.name {
+				// Not unique: let the engine report it
+				return "", false
+			}
+		}
+		tr.tables = append(tr.tables, st)
+		return s, true
+	case *sqlparser.ParenTableExpr:
+		exprs := make([]string, len(te.Exprs))
+		for i, e := range te.Exprs {
+			s, ok := tr.tableExpr(e)
+			if !ok {
+				return "", false
+			}
+			exprs[i] = s
+		}
+		return "(" + strings.Join(exprs, ", ") + ")", true
+	case *sqlparser.JoinTableExpr:
+		var join string
+		switch te.Join {
+		case sqlparser.JoinStr, sqlparser.StraightJoinStr:
+			join = "JOIN"
+		case sqlparser.LeftJoinStr:
+			join = "LEFT JOIN"
+		case sqlparser.RightJoinStr:
+			join = "RIGHT JOIN"
+		default:
+			// NATURAL joins and USING merge columns, which changes what * is
+			return "", false
+		}
+		if te.Condition.Using != nil {
+			return "", false
+		}
+		left, ok := tr.tableExpr(te.LeftExpr)
+		if !ok {
+			return "", false
+		}
+		right, ok := tr.tableExpr(te.RightExpr)
+		if !ok {
+			return "", false
+		}
+		s := left + " " + join + " " + right
+		if te.Condition.On != nil {
+			on, ok := tr.expr(te.Condition.On, false)
+			if !ok {
+				return "", false
+			}
+			s += " ON " + on
+		}
+		return s, true
+	}
+	return "", false
+}
+
+// star expands * or tbl.* to the columns it selects, in the order the engine
+// selects them.
+func (tr *selectTranslator) star(se *sqlparser.StarExpr) ([]string, bool) {
+	var cols []string
+	for _, t := range tr.tables {
+		if !se.TableName.IsEmpty() && t.name != strings.ToLower(se.TableName.Name.String()) {
+			continue
+		}
+		for _, col := range t.table.schema {
+			cols = append(cols, quoteIdent(t.name)+"."+quoteIdent(col.Name)+binaryCollation(col))
+		}
+	}
+	return cols, len(cols) > 0
+}
+
+// column resolves a column reference to the table and column it names. Column
+// names that are ambiguous, or unknown, are left to the engine to report.
+func (tr *selectTranslator) column(c *sqlparser.ColName) (*scopeTable, *sql.Column, bool) {
+	if !c.Qualifier.Qualifier.IsEmpty() && !strings.EqualFold(c.Qualifier.Qualifier.String(), tr.db.name) {
+		return nil, nil, false
+	}
+	var (
+		found *scopeTable
+		col   *sql.Column
+	)
+	for i, t := range tr.tables {
+		if !c.Qualifier.IsEmpty() && t.name != strings.ToLower(c.Qualifier.Name.String()) {
+			continue
+		}
+		for _, tc := range t.table.schema {
+			if strings.EqualFold(tc.Name, c.Name.String()) {
+				if found != nil {
+					return nil, nil, false
+				}
+				found, col = &tr.tables[i], tc
+			}
+		}
+	}
+	return found, col, found != nil
+}
+
+// expr translates an expression. References to the select list's aliases are
+// only allowed where MySQL allows them, in GROUP BY, HAVING and ORDER BY.
+func (tr *selectTranslator) expr(e sqlparser.Expr, aliases bool) (string, bool) {
+	switch e := e.(type) {
+	case *sqlparser.SQLVal:
+		switch e.Type {
+		case sqlparser.IntVal:
+			// SQLite reads larger integers as REAL
+			if _, err := strconv.ParseInt(string(e.Val), 10, 64); err != nil {
+				return "", false
+			}
+			return string(e.Val), true
+		case sqlparser.FloatVal:
+			return string(e.Val), true
+		case sqlparser.StrVal:
+			return quoteString(string(e.Val)), true
+		}
+	case *sqlparser.NullVal:
+		return "NULL", true
+	case sqlparser.BoolVal:
+		if e {
+			return "1", true
+		}
+		return "0", true
+	case *sqlparser.ColName:
+		if encoded, alias := tr.aliases[e.Name.Lowered()]; aliases && e.Qualifier.IsEmpty() && alias {
+			if _, _, ok := tr.column(e); ok || encoded != nil {
+				// Both an alias and a column
+				return "", false
+			}
+			return quoteIdent(e.Name.String()), true
+		}
+		t, col, ok := tr.column(e)
+		if !ok || isEncoded(col.Type) {
+			return "", false
+		}
+		return quoteIdent(t.name) + "." + quoteIdent(col.Name) + binaryCollation(col), true
+	case *sqlparser.ParenExpr:
+		s, ok := tr.expr(e.Expr, aliases)
+		return "(" + s + ")", ok
+	case *sqlparser.UnaryExpr:
+		switch e.Operator {
+		case sqlparser.UMinusStr, sqlparser.UPlusStr:
+			s, ok := tr.expr(e.Expr, aliases)
+			return e.Operator + s, ok
+		case sqlparser.BangStr:
+			s, ok := tr.expr(e.Expr, aliases)
+			return "NOT " + s, ok
+		}
+	case *sqlparser.BinaryExpr:
+		switch e.Operator {
+		case sqlparser.PlusStr, sqlparser.MinusStr, sqlparser.MultStr:
+			return tr.binary(e.Left, e.Operator, e.Right, aliases)
+		case sqlparser.ModStr:
+			// SQLite's % truncates its operands to integers
+			if tr.isInteger(e.Left) && tr.isInteger(e.Right) {
+				return tr.binary(e.Left, e.Operator, e.Right, aliases)
+			}
+		}
+		// Division differs: MySQL divides integers exactly, SQLite truncates.
+		// Bitwise operators are unsigned in MySQL and signed in SQLite.
+	case *sqlparser.ComparisonExpr:
+		switch e.Operator {
+		case sqlparser.EqualStr, sqlparser.LessThanStr, sqlparser.GreaterThanStr,
+			sqlparser.LessEqualStr, sqlparser.GreaterEqualStr, sqlparser.NotEqualStr:
+			return tr.compare(e.Left, e.Operator, e.Right, aliases)
+		case sqlparser.NullSafeEqualStr:
+			return tr.compare(e.Left, "IS", e.Right, aliases)
+		case sqlparser.InStr, sqlparser.NotInStr:
+			if _, ok := e.Right.(sqlparser.ValTuple); !ok {
+				return "", false
+			}
+			return tr.compare(e.Left, strings.ToUpper(e.Operator), e.Right, aliases)
+		}
+		// SQLite's LIKE ignores the case of ASCII letters, where the engine's
+		// doesn't
+	case sqlparser.ValTuple:
+		vals := make([]string, len(e))
+		for i, v := range e {
+			s, ok := tr.expr(v, aliases)
+			if !ok {
+				return "", false
+			}
+			vals[i] = s
+		}
+		return "(" + strings.Join(vals, ", ") + ")", true
+	case *sqlparser.AndExpr:
+		return tr.binary(e.Left, "AND", e.Right, aliases)
+	case *sqlparser.OrExpr:
+		return tr.binary(e.Left, "OR", e.Right, aliases)
+	case *sqlparser.NotExpr:
+		s, ok := tr.expr(e.Expr, aliases)
+		return "NOT " + s, ok
+	case *sqlparser.IsExpr:
+		s, _, ok := tr.encodedExpr(e.Expr, aliases)
+		if !ok {
+			s, ok = tr.expr(e.Expr, aliases)
+		}
+		return s + " " + strings.ToUpper(e.Operator), ok
+	case *sqlparser.RangeCond:
+		s, ok := tr.compare(e.Left, strings.ToUpper(e.Operator), e.From, aliases)
+		if !ok {
+			return "", false
+		}
+		if _, t, ok := tr.encodedExpr(e.Left, aliases); ok {
+			to, ok := encodedOperand(t, e.To)
+			return s + " AND " + to, ok
+		}
+		if !tr.sameClass(e.Left, e.To) {
+			return "", false
+		}
+		to, ok := tr.expr(e.To, aliases)
+		return s + " AND " + to, ok
+	case *sqlparser.CaseExpr:
+		s := "CASE"
+		if e.Expr != nil {
+			v, ok := tr.expr(e.Expr, aliases)
+			if !ok {
+				return "", false
+			}
+			s += " " + v
+		}
+		for _, when := range e.Whens {
+			cond, ok := tr.expr(when.Cond, aliases)
+			if !ok {
+				return "", false
+			}
+			val, ok := tr.expr(when.Val, aliases)
+			if !ok {
+				return "", false
+			}
+			s += " WHEN " + cond + " THEN " + val
+		}
+		if e.Else != nil {
+			v, ok := tr.expr(e.Else, aliases)
+			if !ok {
+				return "", false
+			}
+			s += " ELSE " + v
+		}
+		return s + " END", true
+	case *sqlparser.FuncExpr:
+		return tr.function(e, aliases)
+	case *sqlparser.GroupConcatExpr:
+		// SQLite's GROUP_CONCAT has no ORDER BY, and can't combine DISTINCT
+		// with a separator; both default to a comma
+		if len(e.OrderBy) > 0 || e.Separator != "" || len(e.Exprs) != 1 {
+			return "", false
+		}
+		ae, ok := e.Exprs[0].(*sqlparser.AliasedExpr)
+		if !ok {
+			return "", false
+		}
+		arg, ok := tr.expr(ae.Expr, aliases)
+		if !ok {
+			return "", false
+		}
+		return "group_concat(" + strings.ToUpper(e.Distinct) + arg + ")", true
+	}
+	return "", false
+}
+
+// aggregateFunctions are the aggregate functions SQLite implements with the
+// same semantics as MySQL.
+var aggregateFunctions = map[string]bool{
+	"count": true,
+	"sum":   true,
+	"avg":   true,
+	"min":   true,
+	"max":   true,
+}
+
+func (tr *selectTranslator) function(e *sqlparser.FuncExpr, aliases bool) (string, bool) {
+	if !e.Qualifier.IsEmpty() {
+		return "", false
+	}
+	name := e.Name.Lowered()
+	if aggregateFunctions[name] {
+		if len(e.Exprs) != 1 {
+			return "", false
+		}
+		if _, ok := e.Exprs[0].(*sqlparser.StarExpr); ok && name == "count" && !e.Distinct {
+			return "count(*)", true
+		}
+		if name == "count" {
+			if ae, ok := e.Exprs[0].(*sqlparser.AliasedExpr); ok {
+				if arg, _, ok := tr.encodedExpr(ae.Expr, aliases); ok {
+					return name + "(" + distinct(e) + arg + ")", true
+				}
+			}
+		}
+	} else if e.Distinct {
+		return "", false
+	} else if fn, ok := sqliteFunctions[name]; ok {
+		name = fn
+	} else if !isPushdownFunction(name) {
+		return "", false
+	}
+
+	args := make([]string, len(e.Exprs))
+	for i, se := range e.Exprs {
+		ae, ok := se.(*sqlparser.AliasedExpr)
+		if !ok {
+			return "", false
+		}
+		if args[i], ok = tr.expr(ae.Expr, aliases); !ok {
+			return "", false
+		}
+	}
+	if e.Distinct {
+		return name + "(DISTINCT " + strings.Join(args, ", ") + ")", true
+	}
+	return name + "(" + strings.Join(args, ", ") + ")", true
+}
+
+func isPushdownFunction(name string) bool {
+	for _, f := range pushdownFunctions {
+		if f.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (tr *selectTranslator) binary(left sqlparser.Expr, op string, right sqlparser.Expr, aliases bool) (string, bool) {
+	l, ok := tr.expr(left, aliases)
+	if !ok {
+		return "", false
+	}
+	r, ok := tr.expr(right, aliases)
+	if !ok {
+		return "", false
+	}
+	return l + " " + op + " " + r, true
+}
+
+func distinct(e *sqlparser.FuncExpr) string {
+	if e.Distinct {
+		return "DISTINCT "
+	}
+	return ""
+}
+
+// compare translates a comparison. Encoded values are compared with values
+// of the same type, or with literals encoded the same way.
+func (tr *selectTranslator) compare(left sqlparser.Expr, op string, right sqlparser.Expr, aliases bool) (string, bool) {
+	l, lt, lok := tr.encodedExpr(left, aliases)
+	r, rt, rok := tr.encodedExpr(right, aliases)
+	switch {
+	case lok && rok:
+		// DECIMAL values of different widths are encoded differently
+		if lt.String() != rt.String() {
+			return "", false
+		}
+	case lok:
+		r, rok = encodedOperand(lt, right)
+	case rok:
+		l, lok = encodedOperand(rt, left)
+	default:
+		// SQLite compares values of different types by their storage class,
+		// and DATETIME values as the text go-sqlite3 stores, where MySQL
+		// converts them; only values of the same kind compare the same way
+		if !tr.sameClass(left, right) {
+			return "", false
+		}
+		return tr.binary(left, op, right, aliases)
+	}
+	if !lok || !rok {
+		return "", false
+	}
+	return l + " " + op + " " + r, true
+}
+
+// encodedExpr translates an expression whose value SQLite has encoded, as
+// BIGINT UNSIGNED and DECIMAL values are stored: a column, an alias of one, or
+// the MIN or MAX of one, along with its type. Since the encodings keep values
+// in order these can be compared, sorted and grouped, but nothing else.
+func (tr *selectTranslator) encodedExpr(e sqlparser.Expr, aliases bool) (string, sql.Type, bool) {
+	switch e := e.(type) {
+	case *sqlparser.ColName:
+		if encoded, alias := tr.aliases[e.Name.Lowered()]; aliases && e.Qualifier.IsEmpty() && alias {
+			if _, _, ok := tr.column(e); ok || encoded == nil {
+				return "", nil, false
+			}
+			return quoteIdent(e.Name.String()), encoded, true
+		}
+		t, col, ok := tr.column(e)
+		if !ok || !isEncoded(col.Type) {
+			return "", nil, false
+		}
+		return quoteIdent(t.name) + "." + quoteIdent(col.Name), col.Type, true
+	case *sqlparser.ParenExpr:
+		s, t, ok := tr.encodedExpr(e.Expr, aliases)
+		return "(" + s + ")", t, ok
+	case *sqlparser.FuncExpr:
+		name := e.Name.Lowered()
+		if !e.Qualifier.IsEmpty() || (name != "min" && name != "max") || len(e.Exprs) != 1 {
+			return "", nil, false
+		}
+		ae, ok := e.Exprs[0].(*sqlparser.AliasedExpr)
+		if !ok {
+			return "", nil, false
+		}
+		arg, t, ok := tr.encodedExpr(ae.Expr, aliases)
+		if !ok {
+			return "", nil, false
+		}
+		return name + "(" + distinct(e) + arg + ")", t, true
+	}
+	return "", nil, false
+}
+
+// encodedOperand translates a literal compared with an encoded value of type
+// t, encoding it the same way. Literals the type can't hold exactly, and other
+// operands, can't be compared in SQLite.
+func encodedOperand(t sql.Type, e sqlparser.Expr) (string, bool) {
+	switch e := e.(type) {
+	case *sqlparser.NullVal:
+		return "NULL", true
+	case *sqlparser.SQLVal:
+		return encodedLiteral(t, string(e.Val), e.Type)
+	case *sqlparser.UnaryExpr:
+		if v, ok := e.Expr.(*sqlparser.SQLVal); ok && e.Operator == sqlparser.UMinusStr {
+			return encodedLiteral(t, "-"+string(v.Val), v.Type)
+		}
+	case sqlparser.ValTuple:
+		vals := make([]string, len(e))
+		for i, v := range e {
+			s, ok := encodedOperand(t, v)
+			if !ok {
+				return "", false
+			}
+			vals[i] = s
+		}
+		return "(" + strings.Join(vals, ", ") + ")", true
+	}
+	return "", false
+}
+
+func encodedLiteral(t sql.Type, val string, typ sqlparser.ValType) (string, bool) {
+	if isOffset(t) {
+		if typ != sqlparser.IntVal {
+			return "", false
+		}
+		u, err := strconv.ParseUint(val, 10, 64)
+		if err != nil {
+			return "", false
+		}
+		v, err := encodeUint64(u)
+		if err != nil {
+			return "", false
+		}
+		return fmt.Sprintf("%d", v), true
+	}
+
+	if typ != sqlparser.IntVal && typ != sqlparser.FloatVal {
+		return "", false
+	}
+	d, err := decimal.NewFromString(val)
+	if err != nil {
+		return "", false
+	}
+	dt := t.(sql.DecimalType)
+	// A literal with more digits than the column keeps would be rounded
+	if !d.Equal(d.Truncate(int32(dt.Scale()))) {
+		return "", false
+	}
+	v, err := encodeDecimal(dt, d)
+	if err != nil {
+		return "", false
+	}
+	return quoteString(v.(string)), true
+}
+
+// operandClass is the kind of value an operand has, as far as comparisons
+// are concerned.
+type operandClass int
+
+const (
+	unknownClass operandClass = iota
+	nullClass
+	numberClass
+	stringClass
+)
+
+// sameClass reports whether left and right are both numbers or both strings,
+// or one of them is NULL, which SQLite and MySQL compare the same way. A tuple
+// on the right, as IN has, must hold values of left's class.
+func (tr *selectTranslator) sameClass(left, right sqlparser.Expr) bool {
+	l := tr.class(left)
+	if l == unknownClass {
+		return false
+	}
+	rights := []sqlparser.Expr{right}
+	if tuple, ok := right.(sqlparser.ValTuple); ok {
+		rights = tuple
+	}
+	for _, e := range rights {
+		r := tr.class(e)
+		if r == unknownClass || r != l && r != nullClass && l != nullClass {
+			return false
+		}
+	}
+	return true
+}
+
+// class returns the kind of value e has. Dates and times, blobs and other
+// types SQLite stores differently from their MySQL text, and expressions
+// whose type isn't evident, are unknown.
+func (tr *selectTranslator) class(e sqlparser.Expr) operandClass {
+	switch e := e.(type) {
+	case *sqlparser.NullVal:
+		return nullClass
+	case sqlparser.BoolVal:
+		return numberClass
+	case *sqlparser.SQLVal:
+		switch e.Type {
+		case sqlparser.IntVal, sqlparser.FloatVal:
+			return numberClass
+		case sqlparser.StrVal:
+			return stringClass
+		}
+	case *sqlparser.ColName:
+		if _, alias := tr.aliases[e.Name.Lowered()]; alias && e.Qualifier.IsEmpty() {
+			return unknownClass
+		}
+		_, col, ok := tr.column(e)
+		switch {
+		case !ok:
+		case sql.IsInteger(col.Type) || sql.IsFloat(col.Type):
+			return numberClass
+		case sql.IsTextOnly(col.Type):
+			return stringClass
+		}
+	case *sqlparser.ParenExpr:
+		return tr.class(e.Expr)
+	case *sqlparser.UnaryExpr:
+		if e.Operator == sqlparser.UMinusStr || e.Operator == sqlparser.UPlusStr {
+			if tr.class(e.Expr) == numberClass {
+				return numberClass
+			}
+		}
+	case *sqlparser.BinaryExpr:
+		if tr.class(e.Left) == numberClass && tr.class(e.Right) == numberClass {
+			return numberClass
+		}
+	case *sqlparser.FuncExpr:
+		switch e.Name.Lowered() {
+		case "count", "sum", "avg":
+			return numberClass
+		case "min", "max":
+			if len(e.Exprs) == 1 {
+				if ae, ok := e.Exprs[0].(*sqlparser.AliasedExpr); ok {
+					return tr.class(ae.Expr)
+				}
+			}
+		}
+	}
+	return unknownClass
+}
+
+// isInteger reports whether e is an integer literal or integer column.
+func (tr *selectTranslator) isInteger(e sqlparser.Expr) bool {
+	switch e := e.(type) {
+	case *sqlparser.SQLVal:
+		return e.Type == sqlparser.IntVal
+	case *sqlparser.ColName:
+		_, col, ok := tr.column(e)
+		return ok && sql.IsInteger(col.Type)
+	}
+	return false
+}
+
+// binaryCollation returns a COLLATE clause making SQLite compare, sort and
+// group a text column's values byte by byte, as the engine does, if the column
+// was declared with one of the collations SQLite has registered.
+func binaryCollation(col *sql.Column) string {
+	st, ok := col.Type.(sql.StringType)
+	if !ok || sqliteCollation(st.Collation().String()) == "" {
+		return ""
+	}
+	return " COLLATE BINARY"
+}
+
+func intLiteral(e sqlparser.Expr) (string, bool) {
+	v, ok := e.(*sqlparser.SQLVal)
+	if !ok || v.Type != sqlparser.IntVal {
+		return "", false
+	}
+	return string(v.Val), true
+}
+
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func quoteString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,198 @@
+package sqlite
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	sqle "github.com/liquidata-inc/go-mysql-server"
+	"github.com/liquidata-inc/go-mysql-server/sql"
+	"github.com/liquidata-inc/vitess/go/vt/sqlparser"
+)
+
+// testDatabase opens a new database named mydb, running stmts on it through
+// the engine, and returns it with a context using it.
+func testDatabase(t *testing.T, stmts ...string) (*Database, *sql.Context) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "mysqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	db, err := NewDatabase("mydb", filepath.Join(dir, "mydb.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	e := sqle.NewDefault()
+	e.AddDatabase(db)
+	ctx := sql.NewContext(context.Background(), sql.WithSession(sql.NewBaseSession()))
+	ctx.SetCurrentDatabase("mydb")
+	for _, stmt := range stmts {
+		_, iter, err := e.Query(ctx, stmt)
+		if err == nil {
+			_, err = sql.RowIterToRows(iter)
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	return db, ctx
+}
+
+func TestTranslateSelect(t *testing.T) {
+	db, ctx := testDatabase(t,
+		`CREATE TABLE users (id INT, name VARCHAR(20), born DATE, balance DECIMAL(10,2), visits BIGINT UNSIGNED)`,
+		`CREATE TABLE orders (id INT, user_id INT, total DOUBLE, note VARCHAR(20) COLLATE utf8mb4_bin)`,
+	)
+
+	tests := []struct {
+		query      string
+		translated string // empty if the query is left to the engine
+	}{
+		{
+			"SELECT * FROM users",
+			`SELECT "users"."rowtime", "users"."id", "users"."name" COLLATE BINARY, "users"."born", "users"."balance", "users"."visits" FROM "users"`,
+		},
+		{
+			"SELECT name FROM users WHERE id = 1 AND name <> 'bob'",
+			`SELECT "users"."name" COLLATE BINARY FROM "users" WHERE "users"."id" = 1 AND "users"."name" COLLATE BINARY != 'bob'`,
+		},
+		{
+			"SELECT u.name, count(*) AS n FROM users u JOIN orders o ON o.user_id = u.id GROUP BY u.name HAVING count(*) > 1 ORDER BY n DESC, u.name LIMIT 10 OFFSET 5",
+			`SELECT "u"."name" COLLATE BINARY, count(*) AS "n" FROM "users" AS "u" JOIN "orders" AS "o" ON "o"."user_id" = "u"."id" GROUP BY "u"."name" COLLATE BINARY HAVING count(*) > 1 ORDER BY "n" DESC, "u"."name" COLLATE BINARY ASC LIMIT 10 OFFSET 5`,
+		},
+		{
+			"SELECT users.name, orders.total FROM users LEFT JOIN orders ON orders.user_id = users.id WHERE orders.total >= 9.5",
+			`SELECT "users"."name" COLLATE BINARY, "orders"."total" FROM "users" LEFT JOIN "orders" ON "orders"."user_id" = "users"."id" WHERE "orders"."total" >= 9.5`,
+		},
+		{
+			"SELECT mydb.users.id FROM mydb.users",
+			`SELECT "users"."id" FROM "users"`,
+		},
+		{
+			"SELECT id FROM users WHERE id IN (1, 2, NULL) AND id BETWEEN -5 AND 5",
+			`SELECT "users"."id" FROM "users" WHERE "users"."id" IN (1, 2, NULL) AND "users"."id" BETWEEN -5 AND 5`,
+		},
+		{
+			"SELECT id % 2, id * 3 - 1 FROM users",
+			`SELECT "users"."id" % 2, "users"."id" * 3 - 1 FROM "users"`,
+		},
+		{
+			"SELECT CASE WHEN id > 1 THEN 'big' ELSE 'small' END FROM users",
+			`SELECT CASE WHEN "users"."id" > 1 THEN 'big' ELSE 'small' END FROM "users"`,
+		},
+
+		// Encoded values are compared with literals encoded the same way
+		{
+			"SELECT balance FROM users WHERE balance > 12.5 ORDER BY balance",
+			`SELECT "users"."balance" FROM "users" WHERE "users"."balance" > 'p00000012.50' ORDER BY "users"."balance" ASC`,
+		},
+		{
+			"SELECT max(balance) FROM users WHERE balance BETWEEN -1 AND 1",
+			`SELECT max("users"."balance") FROM "users" WHERE "users"."balance" BETWEEN 'n99999998.99' AND 'p00000001.00'`,
+		},
+		{
+			"SELECT id FROM users WHERE visits = 18446744073709551615 OR visits IN (0, 1)",
+			`SELECT "users"."id" FROM "users" WHERE "users"."visits" = 9223372036854775807 OR "users"."visits" IN (-9223372036854775808, -9223372036854775807)`,
+		},
+		{"SELECT id FROM users WHERE balance = 1.005", ""},
+		{"SELECT id FROM users WHERE visits = -1", ""},
+		{"SELECT visits + 1 FROM users", ""},
+		{"SELECT sum(balance) FROM users", ""},
+
+		// Comparisons SQLite would make differently
+		{"SELECT id FROM users WHERE born > '2020-01-01'", ""},
+		{"SELECT id FROM users WHERE name = 0", ""},
+		{"SELECT id FROM users WHERE id IN (1, 'a')", ""},
+		{"SELECT id FROM users WHERE id BETWEEN 1 AND 'x'", ""},
+		{"SELECT id FROM users WHERE name LIKE 'a%'", ""},
+		{"SELECT id FROM orders WHERE note LIKE 'a%'", ""},
+		{"SELECT count(*) AS n FROM users HAVING n > 1", ""},
+
+		// Everything else is left to the engine
+		{"SELECT id / 2 FROM users", ""},
+		{"SELECT id FROM users WHERE id IN (SELECT user_id FROM orders)", ""},
+		{"SELECT id FROM users JOIN orders", ""},
+		{"SELECT * FROM users NATURAL JOIN orders", ""},
+		{"SELECT * FROM users JOIN orders USING (id)", ""},
+		{"SELECT * FROM missing", ""},
+		{"SELECT * FROM other.users", ""},
+		{"SELECT * FROM users u JOIN orders u", ""},
+		{"SELECT id FROM users LIMIT ?", ""},
+		{"SELECT id FROM users FOR UPDATE", ""},
+		{"SELECT SQL_CALC_FOUND_ROWS id FROM users", ""},
+		{"SELECT id AS n FROM users WHERE n = 1", ""},
+	}
+	for _, tt := range tests {
+		stmt, err := sqlparser.Parse(tt.query)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.query, err)
+		}
+		translated, ok := db.TranslateSelect(ctx, stmt.(*sqlparser.Select))
+		if ok != (tt.translated != "") || translated != tt.translated {
+			t.Errorf("TranslateSelect(%q) =\n\t%q, %v\nwant\n\t%q", tt.query, translated, ok, tt.translated)
+		}
+	}
+}
+
+func TestPassthroughMatchesEngine(t *testing.T) {
+	db, ctx := testDatabase(t,
+		`CREATE TABLE words (id INT, name VARCHAR(20) COLLATE utf8mb4_general_ci, word VARCHAR(20))`,
+		`INSERT INTO words (id, name, word) VALUES (1, 'abc', 'abc'), (2, 'Abb', 'Abb'), (3, 'ABD', 'ABD'), (4, 'ABC', 'ABC'), (5, 'abc ', 'abc '), (6, NULL, NULL)`,
+	)
+	e := sqle.NewDefault()
+	e.AddDatabase(db)
+
+	for _, query := range []string{
+		"SELECT * FROM words ORDER BY id",
+		"SELECT id FROM words WHERE name = 'ABC' ORDER BY id",
+		"SELECT id FROM words WHERE word = 'ABC' ORDER BY id",
+		"SELECT id FROM words WHERE name <> 'abc' ORDER BY id",
+		"SELECT id FROM words WHERE name IN ('abc', 'ABD') ORDER BY id",
+		"SELECT id FROM words WHERE name BETWEEN 'ABC' AND 'Abz' ORDER BY id",
+		"SELECT id FROM words WHERE name > 'ABD' ORDER BY id",
+		"SELECT name FROM words ORDER BY name",
+		"SELECT word FROM words ORDER BY word DESC",
+		"SELECT name AS n FROM words ORDER BY n",
+		"SELECT name, count(*) FROM words GROUP BY name ORDER BY name",
+		"SELECT DISTINCT name FROM words ORDER BY name",
+		"SELECT min(name), max(name) FROM words",
+		"SELECT w.id FROM words w JOIN words v ON w.name = v.word WHERE w.id <> v.id ORDER BY w.id",
+	} {
+		stmt, err := sqlparser.Parse(query)
+		if err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		translated, ok := db.TranslateSelect(ctx, stmt.(*sqlparser.Select))
+		if !ok {
+			t.Errorf("%s: not translated", query)
+			continue
+		}
+
+		schema, iter, err := e.Query(ctx, query)
+		if err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		want, err := sql.RowIterToRows(iter)
+		if err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		iter, err = db.Passthrough(ctx, translated, schema)
+		if err != nil {
+			t.Fatalf("%s: %v", translated, err)
+		}
+		got, err := sql.RowIterToRows(iter)
+		if err != nil {
+			t.Fatalf("%s: %v", translated, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: passthrough returned\n\t%v\nthe engine\n\t%v", query, got, want)
+		}
+	}
+}
@@ -0,0 +1,458 @@
+package sqlite
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/liquidata-inc/go-mysql-server/sql"
+	"github.com/liquidata-inc/vitess/go/sqltypes"
+	"github.com/liquidata-inc/vitess/go/vt/proto/query"
+	"github.com/pkg/errors"
+)
+
+// geometryType is a spatial column type. Values are in MySQL's internal
+// geometry format, a little-endian SRID followed by the WKB of the geometry,
+// and are stored as such in BLOB columns.
+type geometryType struct {
+	name  string   // lower case MySQL type name
+	kinds []uint32 // the WKB types the column accepts, or any if empty
+}
+
+var spatialTypes = map[string]geometryType{
+	"geometry":           {"geometry", nil},
+	"point":              {"point", []uint32{wkbPoint}},
+	"linestring":         {"linestring", []uint32{wkbLineString}},
+	"polygon":            {"polygon", []uint32{wkbPolygon}},
+	"multipoint":         {"multipoint", []uint32{wkbMultiPoint}},
+	"multilinestring":    {"multilinestring", []uint32{wkbMultiLineString}},
+	"multipolygon":       {"multipolygon", []uint32{wkbMultiPolygon}},
+	"geometrycollection": {"geometrycollection", []uint32{wkbMultiPoint, wkbMultiLineString, wkbMultiPolygon, wkbGeometryCollection}},
+}
+
+var _ sql.Type = geometryType{}
+
+func (t geometryType) Compare(a, b interface{}) (int, error) {
+	if a == nil || b == nil {
+		switch {
+		case a == b:
+			return 0, nil
+		case a == nil:
+			return -1, nil
+		default:
+			return 1, nil
+		}
+	}
+	av, err := t.Convert(a)
+	if err != nil {
+		return 0, err
+	}
+	bv, err := t.Convert(b)
+	if err != nil {
+		return 0, err
+	}
+	return bytes.Compare(av.([]byte), bv.([]byte)), nil
+}
+
+func (t geometryType) Convert(v interface{}) (interface{}, error) {
+	var b []byte
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return nil, errors.Errorf("Cannot get geometry object from data you send to the GEOMETRY field")
+	}
+	g, err := decodeGeometry(b)
+	if err != nil {
+		return nil, errors.Errorf("Cannot get geometry object from data you send to the GEOMETRY field")
+	}
+	if !t.accepts(g.kind) {
+		return nil, errors.Errorf("Cannot store %s in a column of type %s", wkbNames[g.kind], strings.ToUpper(t.name))
+	}
+	return g.value(), nil
+}
+
+func (t geometryType) accepts(kind uint32) bool {
+	if len(t.kinds) == 0 {
+		return true
+	}
+	for _, k := range t.kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func (t geometryType) MustConvert(v interface{}) interface{} {
+	value, err := t.Convert(v)
+	if err != nil {
+		panic(err)
+	}
+	return value
+}
+
+func (t geometryType) Promote() sql.Type { return t }
+
+func (t geometryType) SQL(v interface{}) (sqltypes.Value, error) {
+	if v == nil {
+		return sqltypes.NULL, nil
+	}
+	b, err := t.Convert(v)
+	if err != nil {
+		return sqltypes.Value{}, err
+	}
+	return sqltypes.MakeTrusted(sqltypes.Geometry, b.([]byte)), nil
+}
+
+func (t geometryType) Type() query.Type { return sqltypes.Geometry }
+
+// Zero is nil rather than an empty geometry, which isn't a valid value.
+func (t geometryType) Zero() interface{} { return nil }
+
+func (t geometryType) String() string { return t.name }
+
+var spatialColumnRegex = regexp.MustCompile(`(?is)^(\s*(?:` + "`[^`]*`" + `|\S+)\s+)(geometry|geometrycollection|point|multipoint|linestring|multilinestring|polygon|multipolygon)\b(?:\s+srid\s+\d+)?`)
+
+// SPATIAL {INDEX | KEY} [index_name] (key_part,...) in CREATE TABLE
+var spatialIndexRegex = regexp.MustCompile(`(?is)^\s*spatial\s+(?:index|key)\b`)
+
+// errSpatialIndex is returned for SPATIAL indexes, which would need an R*Tree
+// table kept in sync with the geometries of the column.
+var errSpatialIndex = errors.New("SPATIAL indexes are not supported")
+
+// stripSpatial replaces the type of a spatial column definition with LONGBLOB,
+// which go-mysql-server can create, returning the definition and the spatial
+// type it declares, or nil if it declares another type. The column's SRID
+// attribute, if any, is dropped.
+func stripSpatial(def string) (string, sql.Type) {
+	m := spatialColumnRegex.FindStringSubmatchIndex(def)
+	if m == nil {
+		return def, nil
+	}
+	t := spatialTypes[strings.ToLower(def[m[4]:m[5]])]
+	return def[:m[3]] + "longblob" + def[m[1]:], t
+}
+
+// withSpatial gives the columns of schema declared with spatial types in a
+// CREATE TABLE statement those types.
+func withSpatial(schema sql.Schema, types map[string]sql.Type) sql.Schema {
+	if len(types) == 0 {
+		return schema
+	}
+	spatial := make(sql.Schema, len(schema))
+	for i, col := range schema {
+		if t, ok := types[strings.ToLower(col.Name)]; ok {
+			c := *col
+			c.Type = t
+			col = &c
+		}
+		spatial[i] = col
+	}
+	return spatial
+}
+
+// SpatialFunctions are the spatial functions mysqlite supports: the ST_*
+// constructors from WKT and WKB, POINT, LINESTRING and POLYGON, and the basic
+// accessors. Coordinates are planar whatever the SRID.
+var SpatialFunctions = func() []sql.Function {
+	constructors := []struct {
+		names []string
+		typ   geometryType
+	}{
+		{[]string{"geom", "geometry"}, spatialTypes["geometry"]},
+		{[]string{"point"}, spatialTypes["point"]},
+		{[]string{"line", "linestring"}, spatialTypes["linestring"]},
+		{[]string{"poly", "polygon"}, spatialTypes["polygon"]},
+		{[]string{"mpoint", "multipoint"}, spatialTypes["multipoint"]},
+		{[]string{"mline", "multilinestring"}, spatialTypes["multilinestring"]},
+		{[]string{"mpoly", "multipolygon"}, spatialTypes["multipolygon"]},
+		{[]string{"geomcoll", "geometrycollection"}, geometryType{"geometrycollection", []uint32{wkbGeometryCollection}}},
+	}
+
+	var fns []sql.Function
+	for _, c := range constructors {
+		for _, name := range c.names {
+			fns = append(fns,
+				spatialFunc("st_"+name+"fromtext", c.typ, 1, 2, geometryFromText(c.typ)),
+				spatialFunc("st_"+name+"fromwkb", c.typ, 1, 2, geometryFromWKB(c.typ)),
+			)
+		}
+	}
+	return append(fns,
+		spatialFunc("point", spatialTypes["point"], 2, 2, func(name string, args []interface{}) (interface{}, error) {
+			x, err := sql.Float64.Convert(args[0])
+			if err != nil {
+				return nil, err
+			}
+			y, err := sql.Float64.Convert(args[1])
+			if err != nil {
+				return nil, err
+			}
+			return geometry{kind: wkbPoint, points: []point{{x.(float64), y.(float64)}}}.value(), nil
+		}),
+		spatialFunc("linestring", spatialTypes["linestring"], 2, math.MaxInt32, func(name string, args []interface{}) (interface{}, error) {
+			g := geometry{kind: wkbLineString}
+			for _, arg := range args {
+				p, err := geometryArg(name, arg, wkbPoint)
+				if err != nil {
+					return nil, err
+				}
+				g.points = append(g.points, p.points...)
+			}
+			return g.value(), nil
+		}),
+		spatialFunc("polygon", spatialTypes["polygon"], 1, math.MaxInt32, func(name string, args []interface{}) (interface{}, error) {
+			g := geometry{kind: wkbPolygon}
+			for _, arg := range args {
+				ls, err := geometryArg(name, arg, wkbLineString)
+				if err != nil {
+					return nil, err
+				}
+				g.rings = append(g.rings, ls.points)
+			}
+			if err := g.validate(); err != nil {
+				return nil, invalidGISData(name)
+			}
+			return g.value(), nil
+		}),
+		spatialFunc("st_astext", sql.LongText, 1, 1, geometryAsText),
+		spatialFunc("st_aswkt", sql.LongText, 1, 1, geometryAsText),
+		spatialFunc("st_asbinary", sql.LongBlob, 1, 1, geometryAsBinary),
+		spatialFunc("st_aswkb", sql.LongBlob, 1, 1, geometryAsBinary),
+		spatialFunc("st_x", sql.Float64, 1, 1, func(name string, args []interface{}) (interface{}, error) {
+			p, err := geometryArg(name, args[0], wkbPoint)
+			if err != nil {
+				return nil, err
+			}
+			return p.points[0].x, nil
+		}),
+		spatialFunc("st_y", sql.Float64, 1, 1, func(name string, args []interface{}) (interface{}, error) {
+			p, err := geometryArg(name, args[0], wkbPoint)
+			if err != nil {
+				return nil, err
+			}
+			return p.points[0].y, nil
+		}),
+		spatialFunc("st_srid", sql.Uint32, 1, 1, func(name string, args []interface{}) (interface{}, error) {
+			g, err := geometryArg(name, args[0], 0)
+			if err != nil {
+				return nil, err
+			}
+			return g.srid, nil
+		}),
+		spatialFunc("st_geometrytype", sql.LongText, 1, 1, func(name string, args []interface{}) (interface{}, error) {
+			g, err := geometryArg(name, args[0], 0)
+			if err != nil {
+				return nil, err
+			}
+			return wkbNames[g.kind], nil
+		}),
+		spatialFunc("st_numpoints", sql.Int64, 1, 1, func(name string, args []interface{}) (interface{}, error) {
+			g, err := geometryArg(name, args[0], wkbLineString)
+			if err != nil {
+				return nil, err
+			}
+			return int64(len(g.points)), nil
+		}),
+		spatialFunc("st_numgeometries", sql.Int64, 1, 1, func(name string, args []interface{}) (interface{}, error) {
+			g, err := geometryArg(name, args[0], 0)
+			if err != nil {
+				return nil, err
+			}
+			switch g.kind {
+			case wkbPoint, wkbLineString, wkbPolygon:
+				return int64(1), nil
+			}
+			return int64(len(g.geometries)), nil
+		}),
+		spatialFunc("st_distance", sql.Float64, 2, 2, func(name string, args []interface{}) (interface{}, error) {
+			a, err := geometryArg(name, args[0], 0)
+			if err != nil {
+				return nil, err
+			}
+			b, err := geometryArg(name, args[1], 0)
+			if err != nil {
+				return nil, err
+			}
+			if a.kind != wkbPoint || b.kind != wkbPoint {
+				return nil, errors.Errorf("%s is only supported between points", name)
+			}
+			return math.Hypot(a.points[0].x-b.points[0].x, a.points[0].y-b.points[0].y), nil
+		}),
+	)
+}()
+
+func invalidGISData(name string) error {
+	return errors.Errorf("Invalid GIS data provided to function %s.", name)
+}
+
+// geometryArg decodes a geometry argument to the function name, which must be of
+// the given WKB type unless kind is 0.
+func geometryArg(name string, v interface{}, kind uint32) (geometry, error) {
+	var b []byte
+	switch v := v.(type) {
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	}
+	g, err := decodeGeometry(b)
+	if err != nil {
+		return geometry{}, invalidGISData(name)
+	}
+	if kind != 0 && g.kind != kind {
+		return geometry{}, errors.Errorf("Incorrect arguments to %s", name)
+	}
+	return g, nil
+}
+
+// sridArg returns the optional SRID argument of a constructor.
+func sridArg(args []interface{}) (uint32, error) {
+	if len(args) < 2 {
+		return 0, nil
+	}
+	srid, err := sql.Uint32.Convert(args[1])
+	if err != nil {
+		return 0, err
+	}
+	return srid.(uint32), nil
+}
+
+func geometryFromText(t geometryType) func(string, []interface{}) (interface{}, error) {
+	return func(name string, args []interface{}) (interface{}, error) {
+		wkt, err := sql.LongText.Convert(args[0])
+		if err != nil {
+			return nil, err
+		}
+		g, err := parseWKT(wkt.(string))
+		if err != nil || !t.accepts(g.kind) {
+			return nil, invalidGISData(name)
+		}
+		if g.srid, err = sridArg(args); err != nil {
+			return nil, err
+		}
+		return g.value(), nil
+	}
+}
+
+func geometryFromWKB(t geometryType) func(string, []interface{}) (interface{}, error) {
+	return func(name string, args []interface{}) (interface{}, error) {
+		var b []byte
+		switch v := args[0].(type) {
+		case []byte:
+			b = v
+		case string:
+			b = []byte(v)
+		}
+		g, rest, err := decodeWKB(b)
+		if err != nil || len(rest) != 0 || !t.accepts(g.kind) {
+			return nil, invalidGISData(name)
+		}
+		if g.srid, err = sridArg(args); err != nil {
+			return nil, err
+		}
+		return g.value(), nil
+	}
+}
+
+func geometryAsText(name string, args []interface{}) (interface{}, error) {
+	g, err := geometryArg(name, args[0], 0)
+	if err != nil {
+		return nil, err
+	}
+	return g.wkt(), nil
+}
+
+func geometryAsBinary(name string, args []interface{}) (interface{}, error) {
+	g, err := geometryArg(name, args[0], 0)
+	if err != nil {
+		return nil, err
+	}
+	return g.wkb(), nil
+}
+
+// spatialFunc returns a function taking between min and max arguments whose
+// result of type typ is computed by fn from their values.
+func spatialFunc(name string, typ sql.Type, min, max int, fn func(name string, args []interface{}) (interface{}, error)) sql.Function {
+	return sql.FunctionN{
+		Name: name,
+		Fn: func(args ...sql.Expression) (sql.Expression, error) {
+			if len(args) < min || len(args) > max {
+				expected := fmt.Sprint(min)
+				switch {
+				case max == math.MaxInt32:
+					expected = fmt.Sprintf("at least %d", min)
+				case max != min:
+					expected = fmt.Sprintf("%d or %d", min, max)
+				}
+				return nil, sql.ErrInvalidArgumentNumber.New(name, expected, len(args))
+			}
+			return &spatialFunction{name: name, typ: typ, args: args, fn: fn}, nil
+		},
+	}
+}
+
+// spatialFunction is a call to a spatial function. Like MySQL's, it is NULL if
+// any of its arguments are.
+type spatialFunction struct {
+	name string
+	typ  sql.Type
+	args []sql.Expression
+	fn   func(name string, args []interface{}) (interface{}, error)
+}
+
+var _ sql.Expression = (*spatialFunction)(nil)
+
+func (f *spatialFunction) Resolved() bool {
+	for _, arg := range f.args {
+		if !arg.Resolved() {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *spatialFunction) String() string {
+	args := make([]string, len(f.args))
+	for i, arg := range f.args {
+		args[i] = arg.String()
+	}
+	return fmt.Sprintf("%s(%s)", strings.ToUpper(f.name), strings.Join(args, ", "))
+}
+
+func (f *spatialFunction) Type() sql.Type { return f.typ }
+
+func (f *spatialFunction) IsNullable() bool { return true }
+
+func (f *spatialFunction) Children() []sql.Expression { return f.args }
+
+func (f *spatialFunction) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != len(f.args) {
+		return nil, sql.ErrInvalidChildrenNumber.New(f, len(children), len(f.args))
+	}
+	nf := *f
+	nf.args = children
+	return &nf, nil
+}
+
+func (f *spatialFunction) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
+	args := make([]interface{}, len(f.args))
+	for i, arg := range f.args {
+		v, err := arg.Eval(ctx, row)
+		if err != nil {
+			return nil, err
+		}
+		if v == nil {
+			return nil, nil
+		}
+		args[i] = v
+	}
+	return f.fn(f.name, args)
+}
@@ -0,0 +1,148 @@
+package sqlite
+
+import (
+	"bytes"
+	"encoding/hex"
+	"reflect"
+	"testing"
+
+	sqle "github.com/liquidata-inc/go-mysql-server"
+	"github.com/liquidata-inc/go-mysql-server/sql"
+)
+
+func TestGeometryRoundTrip(t *testing.T) {
+	for _, wkt := range []string{
+		"POINT(1 -2.5)",
+		"LINESTRING(0 0,1 1,2 0)",
+		"POLYGON((0 0,4 0,4 4,0 0),(1 1,2 1,2 2,1 1))",
+		"MULTIPOINT((1 2),(3 4))",
+		"MULTIPOLYGON(((0 0,1 0,1 1,0 0)))",
+		"GEOMETRYCOLLECTION(POINT(1 2),LINESTRING(0 0,1 1))",
+		"GEOMETRYCOLLECTION EMPTY",
+	} {
+		g, err := parseWKT(wkt)
+		if err != nil {
+			t.Errorf("parseWKT(%q): %v", wkt, err)
+			continue
+		}
+		wkb := g.wkb()
+		decoded, rest, err := decodeWKB(wkb)
+		if err != nil || len(rest) != 0 {
+			t.Errorf("%s: decoding %x: %v, %d bytes left", wkt, wkb, err, len(rest))
+			continue
+		}
+		if got := decoded.wkt(); got != wkt {
+			t.Errorf("%s: round trips to %s", wkt, got)
+		}
+		if !bytes.Equal(decoded.wkb(), wkb) {
+			t.Errorf("%s: WKB %x round trips to %x", wkt, wkb, decoded.wkb())
+		}
+	}
+
+	// MySQL's internal format is the SRID followed by the WKB
+	want, _ := hex.DecodeString("e6100000" + "0101000000" + "000000000000f03f" + "0000000000000040")
+	g, _ := parseWKT("POINT(1 2)")
+	g.srid = 4326
+	if got := g.value(); !bytes.Equal(got, want) {
+		t.Errorf("POINT(1 2) with SRID 4326 is %x, want %x", got, want)
+	}
+}
+
+func TestSpatialFunctions(t *testing.T) {
+	db, ctx := testDatabase(t)
+	e := sqle.NewDefault()
+	e.AddDatabase(db)
+	e.Catalog.MustRegister(SpatialFunctions...)
+	var pid uint64
+	query := func(stmt string) ([]sql.Row, error) {
+		// Queries failing to analyze stay in the process list
+		pid++
+		ctx := sql.NewContext(ctx, sql.WithSession(ctx.Session), sql.WithQuery(stmt), sql.WithPid(pid))
+		_, iter, err := e.Query(ctx, RewriteCreateTable(stmt))
+		if err != nil {
+			return nil, err
+		}
+		return sql.RowIterToRows(iter)
+	}
+
+	for _, tt := range []struct {
+		expr string
+		want interface{}
+	}{
+		{"ST_AsText(ST_GeomFromText('POINT(1 2)'))", "POINT(1 2)"},
+		{"ST_AsText(POINT(3, -4))", "POINT(3 -4)"},
+		{"ST_AsText(ST_GeomFromWKB(ST_AsBinary(ST_GeomFromText('LINESTRING(0 0,1 1)'))))", "LINESTRING(0 0,1 1)"},
+		{"ST_AsText(ST_PolyFromText('POLYGON((0 0,1 0,1 1,0 0))'))", "POLYGON((0 0,1 0,1 1,0 0))"},
+		{"ST_X(ST_PointFromText('POINT(1.5 2)'))", 1.5},
+		{"ST_Y(ST_PointFromText('POINT(1.5 2)'))", float64(2)},
+		{"ST_SRID(ST_GeomFromText('POINT(1 2)', 4326))", uint32(4326)},
+		{"ST_GeometryType(ST_GeomFromText('MULTIPOINT((1 2),(3 4))'))", "MULTIPOINT"},
+		{"ST_NumPoints(ST_LineFromText('LINESTRING(0 0,1 1,2 2)'))", int64(3)},
+		{"ST_Distance(POINT(0, 0), POINT(3, 4))", float64(5)},
+		{"ST_AsText(NULL)", nil},
+	} {
+		rows, err := query("SELECT " + tt.expr)
+		if err != nil {
+			t.Errorf("%s: %v", tt.expr, err)
+		} else if rows[0][0] != tt.want {
+			t.Errorf("%s = %#v, want %#v", tt.expr, rows[0][0], tt.want)
+		}
+	}
+	for _, expr := range []string{
+		"ST_GeomFromText('POINT(1)')",
+		"ST_PointFromText('LINESTRING(0 0,1 1)')",
+		"ST_X(ST_LineFromText('LINESTRING(0 0,1 1)'))",
+		"ST_GeomFromWKB('junk')",
+	} {
+		if _, err := query("SELECT " + expr); err == nil {
+			t.Errorf("%s: want an error", expr)
+		}
+	}
+
+	// Columns keep the geometries they are declared with
+	for _, stmt := range []string{
+		"CREATE TABLE places (id INT, pos POINT SRID 4326, area GEOMETRY)",
+		"INSERT INTO places (id, pos, area) VALUES (1, ST_GeomFromText('POINT(1 2)', 4326), ST_GeomFromText('POLYGON((0 0,1 0,1 1,0 0))'))",
+	} {
+		if _, err := query(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	rows, err := query("SELECT ST_AsText(pos), ST_X(pos), ST_SRID(pos), ST_AsText(area) FROM places")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (sql.Row{"POINT(1 2)", float64(1), uint32(4326), "POLYGON((0 0,1 0,1 1,0 0))"}); !reflect.DeepEqual(rows[0], want) {
+		t.Errorf("read back %#v, want %#v", rows[0], want)
+	}
+	if _, err := query("INSERT INTO places (id, pos) VALUES (2, ST_GeomFromText('LINESTRING(0 0,1 1)'))"); err == nil {
+		t.Error("stored a LINESTRING in a POINT column")
+	}
+}
+
+func TestSpatialIndex(t *testing.T) {
+	db, ctx := testDatabase(t, "CREATE TABLE places (id INT, pos longblob)")
+	e := sqle.NewDefault()
+	e.AddDatabase(db)
+
+	for i, stmt := range []string{
+		"CREATE TABLE areas (id INT, area POLYGON NOT NULL, SPATIAL INDEX (area))",
+		"CREATE TABLE areas (id INT, area POLYGON NOT NULL, spatial key a (area))",
+		"CREATE SPATIAL INDEX pos ON places (pos)",
+		"ALTER TABLE places ADD SPATIAL INDEX (pos)",
+	} {
+		ctx := sql.NewContext(ctx, sql.WithSession(ctx.Session), sql.WithQuery(stmt), sql.WithPid(uint64(i+1)))
+		_, iter, err := e.Query(ctx, RewriteCreateTable(stmt))
+		if err == nil {
+			_, err = sql.RowIterToRows(iter)
+		}
+		if err != errSpatialIndex {
+			t.Errorf("%s: got %v, want %v", stmt, err, errSpatialIndex)
+		}
+	}
+	if names, err := db.GetTableNames(ctx); err != nil {
+		t.Fatal(err)
+	} else if len(names) != 1 {
+		t.Errorf("tables are %v, want only places", names)
+	}
+}
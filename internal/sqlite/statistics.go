@@ -0,0 +1,80 @@
+package sqlite
+
+import (
+	stdsql "database/sql"
+	"strconv"
+	"strings"
+
+	"github.com/liquidata-inc/go-mysql-server/sql"
+)
+
+// StatisticsTable is a table that can report its size, for SHOW TABLE STATUS
+// and INFORMATION_SCHEMA.TABLES. go-mysql-server v0.6.0 has no hook for table
+// statistics, so the engine doesn't use them when planning queries.
+type StatisticsTable interface {
+	sql.Table
+	// NumRows returns the number of rows in the table, which may be an estimate.
+	NumRows(ctx *sql.Context) (uint64, error)
+	// DataLength returns the size of the table's data in bytes.
+	DataLength(ctx *sql.Context) (uint64, error)
+}
+
+var _ StatisticsTable = (*Table)(nil)
+
+// NumRows returns SQLite's estimate of the number of rows from sqlite_stat1 if
+// the table has been analyzed, and counts them otherwise.
+func (t *Table) NumRows(ctx *sql.Context) (uint64, error) {
+	var stat string
+	err := t.dbr.QueryRowContext(ctx, `SELECT stat FROM `+t.schemaName()+`.sqlite_stat1 WHERE tbl = ? LIMIT 1`, t.name).Scan(&stat)
+	switch {
+	case err == nil:
+		// The first number of every entry is the number of rows in the table
+		if n, err := strconv.ParseUint(strings.Fields(stat)[0], 10, 64); err == nil {
+			return n, nil
+		}
+	case err != stdsql.ErrNoRows && !strings.Contains(err.Error(), "no such table"):
+		return 0, err
+	}
+
+	var n uint64
+	err = t.dbr.QueryRowContext(ctx, `SELECT count(*) FROM `+t.schemaName()+`."`+t.name+`"`).Scan(&n)
+	return n, err
+}
+
+// DataLength returns the size of the pages holding the table, which SQLite only
+// reports if built with SQLITE_ENABLE_DBSTAT_VTAB. Otherwise it is the total
+// size of the table's values, not counting SQLite's overhead.
+func (t *Table) DataLength(ctx *sql.Context) (uint64, error) {
+	var n uint64
+	err := t.dbr.QueryRowContext(ctx, `SELECT coalesce(sum(pgsize), 0) FROM dbstat WHERE schema = ? AND name = ?`, t.schemaName(), t.name).Scan(&n)
+	if err == nil || !strings.Contains(err.Error(), "no such table") {
+		return n, err
+	}
+
+	lengths := make([]string, len(t.schema))
+	for i, col := range t.schema {
+		lengths[i] = `coalesce(length(CAST("` + col.Name + `" AS BLOB)), 0)`
+	}
+	err = t.dbr.QueryRowContext(ctx, `SELECT CAST(total(`+strings.Join(lengths, " + ")+`) AS INTEGER) FROM `+t.schemaName()+`."`+t.name+`"`).Scan(&n)
+	return n, err
+}
+
+// IndexLength returns the size of the pages holding the table's indexes, or 0 if
+// SQLite isn't built with SQLITE_ENABLE_DBSTAT_VTAB.
+func (t *Table) IndexLength(ctx *sql.Context) (uint64, error) {
+	var n uint64
+	err := t.dbr.QueryRowContext(ctx, `
+		SELECT coalesce(sum(pgsize), 0) FROM dbstat WHERE schema = ? AND name IN (
+			SELECT name FROM `+t.schemaName()+`.sqlite_master WHERE type = 'index' AND tbl_name = ?
+		)`, t.schemaName(), t.name).Scan(&n)
+	if err != nil && strings.Contains(err.Error(), "no such table") {
+		return 0, nil
+	}
+	return n, err
+}
+
+// Analyze gathers the statistics SQLite's query planner and NumRows use.
+func (t *Table) Analyze(ctx *sql.Context) error {
+	_, err := t.dbw.ExecContext(ctx, `ANALYZE `+t.schemaName()+`."`+t.name+`"`)
+	return err
+}
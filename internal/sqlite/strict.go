@@ -0,0 +1,383 @@
+package sqlite
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/liquidata-inc/go-mysql-server/sql"
+	"github.com/liquidata-inc/go-mysql-server/sql/analyzer"
+	"github.com/liquidata-inc/go-mysql-server/sql/expression"
+	"github.com/liquidata-inc/go-mysql-server/sql/plan"
+	"github.com/liquidata-inc/vitess/go/mysql"
+	"github.com/liquidata-inc/vitess/go/sqltypes"
+	"github.com/shopspring/decimal"
+)
+
+// DefaultSQLMode is MySQL's default sql_mode, which sessions should start
+// with.
+const DefaultSQLMode = "ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION"
+
+// MySQL's codes for values a column can't hold
+const (
+	codeDataTruncated = 1265
+	codeOutOfRange    = 1264
+	codeDataTooLong   = mysql.ERDataTooLong
+	codeWrongValue    = mysql.ERTruncatedWrongValue
+	codeIncorrect     = mysql.ERTruncatedWrongValueForField
+)
+
+// strictMode reports whether the session's sql_mode makes writing a value a
+// column can't hold an error, rather than a warning with the value adjusted to
+// fit. Every mysqlite table is transactional, so STRICT_TRANS_TABLES applies to
+// all of them.
+func strictMode(ctx *sql.Context) bool {
+	_, v := ctx.Get("sql_mode")
+	s, _ := v.(string)
+	for _, mode := range strings.Split(s, ",") {
+		switch strings.ToUpper(strings.TrimSpace(mode)) {
+		case "STRICT_TRANS_TABLES", "STRICT_ALL_TABLES", "TRADITIONAL":
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateWrites is an analyzer rule that checks the values an INSERT writes
+// against the types of their columns before the engine converts them, which
+// fails on any value that doesn't fit. Outside strict mode values are adjusted
+// to fit with a warning, as MySQL does. Add it with
+// analyzer.Builder.AddPostAnalyzeRule.
+func ValidateWrites(ctx *sql.Context, a *analyzer.Analyzer, n sql.Node, scope *analyzer.Scope) (sql.Node, error) {
+	return plan.TransformUp(n, func(n sql.Node) (sql.Node, error) {
+		insert, ok := n.(*plan.InsertInto)
+		if !ok {
+			return n, nil
+		}
+		schema := insert.Left.Schema()
+		names := insert.ColumnNames
+		if len(names) == 0 {
+			for _, col := range schema {
+				names = append(names, col.Name)
+			}
+		}
+		cols := make([]*sql.Column, len(names))
+		for i, name := range names {
+			for _, col := range schema {
+				if col.Name == name {
+					cols[i] = col
+				}
+			}
+			if cols[i] == nil {
+				// Left for the engine to report
+				return n, nil
+			}
+		}
+
+		switch src := insert.Right.(type) {
+		case *plan.Values:
+			tuples := make([][]sql.Expression, len(src.ExpressionTuples))
+			for i, tuple := range src.ExpressionTuples {
+				if len(tuple) != len(cols) {
+					return n, nil
+				}
+				row := i + 1
+				tuples[i] = make([]sql.Expression, len(tuple))
+				for j, e := range tuple {
+					if _, ok := e.(*validatedValue); ok {
+						return n, nil
+					}
+					tuples[i][j] = &validatedValue{expression.UnaryExpression{Child: e}, cols[j], &row, false}
+				}
+			}
+			return insert.WithChildren(insert.Left, plan.NewValues(tuples))
+		case *plan.ResolvedTable, *plan.Project, *plan.InnerJoin, *plan.Filter:
+			if p, ok := src.(*plan.Project); ok && len(p.Projections) > 0 {
+				if _, ok := p.Projections[0].(*validatedValue); ok {
+					return n, nil
+				}
+			}
+			srcSchema := src.Schema()
+			if len(srcSchema) != len(cols) {
+				return n, nil
+			}
+			rows := new(int)
+			exprs := make([]sql.Expression, len(srcSchema))
+			for j, col := range srcSchema {
+				e := expression.NewGetField(j, col.Type, col.Name, col.Nullable)
+				exprs[j] = &validatedValue{expression.UnaryExpression{Child: e}, cols[j], rows, j == 0}
+			}
+			return insert.WithChildren(insert.Left, plan.NewProject(exprs, src))
+		}
+		return n, nil
+	})
+}
+
+// validatedValue is a value written to a column, checked against the column's
+// type.
+type validatedValue struct {
+	expression.UnaryExpression
+	col *sql.Column
+	// row is the number of the row written, for errors and warnings, which
+	// the value of the first column counts if counts is set
+	row    *int
+	counts bool
+}
+
+var _ sql.Expression = (*validatedValue)(nil)
+
+func (v *validatedValue) Type() sql.Type { return charTyped(v.col.Type) }
+func (v *validatedValue) String() string { return v.Child.String() }
+
+func (v *validatedValue) WithChildren(children ...sql.Expression) (sql.Expression, error) {
+	if len(children) != 1 {
+		return nil, sql.ErrInvalidChildrenNumber.New(v, len(children), 1)
+	}
+	return &validatedValue{expression.UnaryExpression{Child: children[0]}, v.col, v.row, v.counts}, nil
+}
+
+func (v *validatedValue) Eval(ctx *sql.Context, row sql.Row) (interface{}, error) {
+	if v.counts {
+		*v.row++
+	}
+	val, err := v.Child.Eval(ctx, row)
+	if err != nil {
+		return nil, err
+	}
+	return coerceValue(ctx, v.col, val, *v.row)
+}
+
+// coerceRow checks the values of the nth row written to a table against the
+// types of their columns, returning the row with the values adjusted to fit
+// outside strict mode.
+func coerceRow(ctx *sql.Context, schema sql.Schema, row sql.Row, n int) (sql.Row, error) {
+	coerced := make(sql.Row, len(row))
+	for i, v := range row {
+		var err error
+		if coerced[i], err = coerceValue(ctx, schema[i], v, n); err != nil {
+			return nil, err
+		}
+	}
+	return coerced, nil
+}
+
+// coerceValue converts a value written to a column in the nth row to the
+// column's type. In strict mode a value the type can't hold is an error with
+// MySQL's code for it. Otherwise, unless nothing like it fits, it's replaced by
+// the value MySQL stores instead, and the session gets a warning.
+func coerceValue(ctx *sql.Context, col *sql.Column, v interface{}, n int) (interface{}, error) {
+	t := charTyped(col.Type)
+	converted, err := t.Convert(v)
+	if err == nil {
+		return converted, nil
+	}
+	b := badValue(col, v, n)
+	if b == nil {
+		return nil, err
+	}
+	if b.err == nil {
+		// Written differently than the engine converts, but fine
+		return t.Convert(b.fallback)
+	}
+	if strictMode(ctx) || b.fallback == nil {
+		return nil, b.err
+	}
+	fallback, ferr := t.Convert(b.fallback)
+	if ferr != nil {
+		return nil, b.err
+	}
+	ctx.Warn(b.warning, "%s", b.warningMessage)
+	return fallback, nil
+}
+
+// A bad value is one a column can't hold.
+type bad struct {
+	// err is the error in strict mode, or nil if the value can be written as
+	// fallback after all
+	err *mysql.SQLError
+	// fallback is the value stored instead outside strict mode, or nil if
+	// nothing is
+	fallback       interface{}
+	warning        int
+	warningMessage string
+}
+
+func badValue(col *sql.Column, v interface{}, n int) *bad {
+	truncated := fmt.Sprintf("Data truncated for column '%s' at row %d", col.Name, n)
+	outOfRange := fmt.Sprintf("Out of range value for column '%s' at row %d", col.Name, n)
+	t := col.Type
+	switch {
+	case sql.IsInteger(t) || sql.IsDecimal(t) || sql.IsFloat(t) || t.Type() == sqltypes.Year:
+		kind := "integer"
+		if sql.IsDecimal(t) {
+			kind = "decimal"
+		} else if sql.IsFloat(t) {
+			kind = "double"
+		}
+		s := fmt.Sprint(v)
+		prefix := numberPrefixRegex.FindString(s)
+		d, err := decimal.NewFromString(strings.TrimSpace(prefix))
+		if err != nil {
+			msg := fmt.Sprintf("Incorrect %s value: '%s' for column '%s' at row %d", kind, s, col.Name, n)
+			return &bad{mysql.NewSQLError(codeIncorrect, mysql.SSUnknownSQLState, "%s", msg), "0", codeIncorrect, msg}
+		}
+		if !sql.IsDecimal(t) && !sql.IsFloat(t) {
+			d = d.Round(0)
+		}
+		if min, max, ok := numberRange(t); ok && (d.LessThan(min) || d.GreaterThan(max)) {
+			if d.LessThan(min) {
+				d = min
+			} else {
+				d = max
+			}
+			return &bad{mysql.NewSQLError(codeOutOfRange, mysql.SSDataOutOfRange, "%s", outOfRange), d.String(), codeOutOfRange, outOfRange}
+		}
+		if prefix != s {
+			return &bad{mysql.NewSQLError(codeDataTruncated, "01000", "%s", truncated), d.String(), codeDataTruncated, truncated}
+		}
+		return &bad{fallback: d.String()}
+	case t.Type() == sqltypes.Enum:
+		// go-mysql-server has no empty ENUM value for invalid ones
+		return &bad{err: mysql.NewSQLError(codeDataTruncated, "01000", "%s", truncated)}
+	case t.Type() == sqltypes.Set:
+		s, ok := v.(string)
+		if !ok {
+			break
+		}
+		var members []string
+		for _, m := range strings.Split(s, ",") {
+			if _, err := t.Convert(m); err == nil {
+				members = append(members, m)
+			}
+		}
+		return &bad{mysql.NewSQLError(codeDataTruncated, "01000", "%s", truncated), strings.Join(members, ","), codeDataTruncated, truncated}
+	case sql.IsText(t) || sql.IsBlob(t):
+		st, ok := t.(sql.StringType)
+		if !ok {
+			break
+		}
+		text, err := sql.LongText.Convert(v)
+		if err != nil {
+			break
+		}
+		s := text.(string)
+		if isCharLimited(t) {
+			if int64(utf8.RuneCountInString(s)) <= st.MaxCharacterLength() {
+				break
+			}
+			runes := []rune(s)
+			s = string(runes[:st.MaxCharacterLength()])
+		} else {
+			if int64(len(s)) <= st.MaxByteLength() {
+				break
+			}
+			s = s[:st.MaxByteLength()]
+			if sql.IsText(t) {
+				// Don't split a character
+				for len(s) > 0 && !utf8.ValidString(s) {
+					s = s[:len(s)-1]
+				}
+			}
+		}
+		msg := fmt.Sprintf("Data too long for column '%s' at row %d", col.Name, n)
+		return &bad{mysql.NewSQLError(codeDataTooLong, mysql.SSDataTooLong, "%s", msg), s, codeDataTruncated, truncated}
+	case sql.IsTime(t) || t.Type() == sqltypes.Time:
+		kind := strings.ToLower(t.Type().String())
+		msg := fmt.Sprintf("Incorrect %s value: '%v' for column '%s' at row %d", kind, v, col.Name, n)
+		return &bad{mysql.NewSQLError(codeWrongValue, mysql.SSUnknownSQLState, "%s", msg), t.Zero(), codeDataTruncated, truncated}
+	}
+	return nil
+}
+
+// numberPrefixRegex matches the number a string starts with, which MySQL
+// converts it to.
+var numberPrefixRegex = regexp.MustCompile(`^\s*[-+]?(?:\d+\.?\d*|\.\d+)(?:[eE][-+]?\d+)?`)
+
+// numberRange returns the smallest and largest values of a numeric type, if
+// they're bounded.
+func numberRange(t sql.Type) (min, max decimal.Decimal, ok bool) {
+	var lo, hi int64
+	switch t.Type() {
+	case sqltypes.Int8:
+		lo, hi = math.MinInt8, math.MaxInt8
+	case sqltypes.Uint8:
+		hi = math.MaxUint8
+	case sqltypes.Int16:
+		lo, hi = math.MinInt16, math.MaxInt16
+	case sqltypes.Uint16:
+		hi = math.MaxUint16
+	case sqltypes.Int24:
+		lo, hi = -1<<23, 1<<23-1
+	case sqltypes.Uint24:
+		hi = 1<<24 - 1
+	case sqltypes.Int32:
+		lo, hi = math.MinInt32, math.MaxInt32
+	case sqltypes.Uint32:
+		hi = math.MaxUint32
+	case sqltypes.Int64:
+		lo, hi = math.MinInt64, math.MaxInt64
+	case sqltypes.Uint64:
+		return decimal.Zero, decimal.RequireFromString("18446744073709551615"), true
+	case sqltypes.Year:
+		hi = 2155
+	case sqltypes.Decimal:
+		dt := t.(sql.DecimalType)
+		// The largest value has every digit 9
+		max = decimal.New(1, int32(dt.Precision()-dt.Scale())).Sub(decimal.New(1, -int32(dt.Scale())))
+		return max.Neg(), max, true
+	default:
+		return decimal.Zero, decimal.Zero, false
+	}
+	return decimal.NewFromInt(lo), decimal.NewFromInt(hi), true
+}
+
+// isCharLimited reports whether a string type's length is counted in
+// characters, as CHAR and VARCHAR are, rather than in bytes.
+func isCharLimited(t sql.Type) bool {
+	_, ok := charTyped(t).(charType)
+	return ok
+}
+
+// checkConstraint returns a CHECK constraint that keeps writes from other
+// SQLite clients to the limits of a column's type, or "" if there's none.
+func checkConstraint(col *sql.Column) string {
+	name := quoteIdent(col.Name)
+	t := col.Type
+	switch {
+	case isOffset(t) || t.Type() == sqltypes.Int64:
+		return fmt.Sprintf("typeof(%s) IN ('integer', 'null')", name)
+	case sql.IsInteger(t):
+		min, max, _ := numberRange(t)
+		return fmt.Sprintf("typeof(%s) IN ('integer', 'null') AND %s BETWEEN %s AND %s", name, name, min, max)
+	case sql.IsDecimal(t):
+		dt := t.(sql.DecimalType)
+		digits := int(dt.Precision() - dt.Scale())
+		if digits == 0 {
+			digits = 1
+		}
+		length := 1 + digits
+		if dt.Scale() > 0 {
+			length += 1 + int(dt.Scale())
+		}
+		return fmt.Sprintf("length(%s) = %d AND substr(%s, 1, 1) IN ('p', 'n')", name, length, name)
+	case t.Type() == sqltypes.Enum:
+		values := t.(sql.EnumType).Values()
+		quoted := make([]string, len(values))
+		for i, v := range values {
+			quoted[i] = quoteString(v)
+		}
+		return fmt.Sprintf("%s IN (%s)", name, strings.Join(quoted, ", "))
+	case sql.IsText(t) || sql.IsBlob(t):
+		st, ok := t.(sql.StringType)
+		if !ok {
+			return ""
+		}
+		if isCharLimited(t) {
+			return fmt.Sprintf("length(%s) <= %d", name, st.MaxCharacterLength())
+		}
+		return fmt.Sprintf("length(CAST(%s AS BLOB)) <= %d", name, st.MaxByteLength())
+	}
+	return ""
+}
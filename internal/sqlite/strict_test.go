@@ -0,0 +1,169 @@
+package sqlite
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+
+	sqle "github.com/liquidata-inc/go-mysql-server"
+	"github.com/liquidata-inc/go-mysql-server/sql"
+	"github.com/liquidata-inc/go-mysql-server/sql/analyzer"
+	"github.com/liquidata-inc/vitess/go/mysql"
+)
+
+// strictEngine returns an engine over db checking writes as the server's does.
+func strictEngine(db *Database) *sqle.Engine {
+	catalog := sql.NewCatalog()
+	a := analyzer.NewBuilder(catalog).
+		AddPostAnalyzeRule("validate_writes", ValidateWrites).
+		AddPostAnalyzeRule("count_characters", CountCharacters).
+		Build()
+	e := sqle.New(catalog, a, nil)
+	e.AddDatabase(db)
+	return e
+}
+
+func TestStrictMode(t *testing.T) {
+	db, ctx := testDatabase(t,
+		`CREATE TABLE t (id INT, tiny TINYINT, n INT, name VARCHAR(3), code CHAR(2))`,
+	)
+	e := strictEngine(db)
+	var pid uint64
+	query := func(mode, stmt string) ([]sql.Row, []*sql.Warning, error) {
+		// Queries failing to analyze stay in the process list
+		pid++
+		ctx := sql.NewContext(ctx, sql.WithSession(sql.NewBaseSession()), sql.WithPid(pid))
+		ctx.SetCurrentDatabase("mydb")
+		if err := ctx.Set(ctx, "sql_mode", sql.LongText, mode); err != nil {
+			t.Fatal(err)
+		}
+		_, iter, err := e.Query(ctx, stmt)
+		if err != nil {
+			return nil, nil, err
+		}
+		rows, err := sql.RowIterToRows(iter)
+		return rows, ctx.Warnings(), err
+	}
+
+	tests := []struct {
+		values string
+		code   int    // the error in strict mode, and the warning otherwise
+		row    string // the row stored outside strict mode
+		warn   int    // the warning, if not code
+	}{
+		{"(1, 300, 0, 'a', 'a')", codeOutOfRange, "[1 127 0 a a]", 0},
+		{"(2, -300, 0, 'a', 'a')", codeOutOfRange, "[2 -128 0 a a]", 0},
+		{"(3, 0, '12abc', 'a', 'a')", codeDataTruncated, "[3 0 12 a a]", 0},
+		{"(4, 0, 0, 'abcd', 'a')", codeDataTooLong, "[4 0 0 abc a]", codeDataTruncated},
+		{"(5, 0, 0, 'a', 'abc')", codeDataTooLong, "[5 0 0 a ab]", codeDataTruncated},
+	}
+	for _, tt := range tests {
+		stmt := "INSERT INTO t (id, tiny, n, name, code) VALUES " + tt.values
+		if _, _, err := query(DefaultSQLMode, stmt); sqlErrorCode(err) != tt.code {
+			t.Errorf("strict %s: got %v, want error %d", tt.values, err, tt.code)
+		}
+		_, warnings, err := query("", stmt)
+		if err != nil {
+			t.Errorf("%s: %v", tt.values, err)
+			continue
+		}
+		want := tt.warn
+		if want == 0 {
+			want = tt.code
+		}
+		if len(warnings) != 1 || warnings[0].Code != want {
+			t.Errorf("%s: warned %+v, want %d", tt.values, warnings, want)
+		}
+	}
+	rows, _, err := query("", "SELECT id, tiny, n, name, code FROM t ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, tt := range tests {
+		if got := fmt.Sprint(rows[i]); got != tt.row {
+			t.Errorf("%s stored %s, want %s", tt.values, got, tt.row)
+		}
+	}
+
+	// CHAR and VARCHAR lengths count characters rather than bytes
+	stmt := "INSERT INTO t (id, name, code) VALUES (6, 'héé', 'ñü')"
+	if _, _, err := query(DefaultSQLMode, stmt); err != nil {
+		t.Fatalf("%s: %v", stmt, err)
+	}
+	rows, _, err = query(DefaultSQLMode, "SELECT name, code FROM t WHERE id = 6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (sql.Row{"héé", "ñü"}); !reflect.DeepEqual(rows[0], want) {
+		t.Errorf("read back %v, want %v", rows[0], want)
+	}
+	stmt = "INSERT INTO t (id, name) VALUES (7, 'héé!')"
+	if _, _, err := query(DefaultSQLMode, stmt); sqlErrorCode(err) != codeDataTooLong {
+		t.Errorf("%s: got %v, want error %d", stmt, err, codeDataTooLong)
+	}
+}
+
+func TestBulkLoadStrictMode(t *testing.T) {
+	db, ctx := testDatabase(t, `CREATE TABLE t (id INT, tiny TINYINT, name VARCHAR(3))`)
+	table, _, err := db.GetTableInsensitive(ctx, "t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	load := func(rows ...sql.Row) (int, error) {
+		next := func() (sql.Row, error) {
+			if len(rows) == 0 {
+				return nil, io.EOF
+			}
+			row := rows[0]
+			rows = rows[1:]
+			return row, nil
+		}
+		inserted, _, err := table.(*Table).BulkLoad(ctx, OnDuplicateError, next)
+		return inserted, err
+	}
+
+	// Nothing is loaded when a row fails in strict mode
+	if err := ctx.Set(ctx, "sql_mode", sql.LongText, DefaultSQLMode); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := load(sql.Row{nil, 1, 1, "ñüé"}, sql.Row{nil, 2, 1, "abcd"}); sqlErrorCode(err) != codeDataTooLong {
+		t.Errorf("got %v, want error %d", err, codeDataTooLong)
+	}
+	if n, err := load(sql.Row{nil, 3, 300, "a"}); sqlErrorCode(err) != codeOutOfRange || n != 0 {
+		t.Errorf("loaded %d rows, got %v, want error %d", n, err, codeOutOfRange)
+	}
+
+	if err := ctx.Set(ctx, "sql_mode", sql.LongText, ""); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := load(sql.Row{nil, 4, 300, "abcd"}); err != nil || n != 1 {
+		t.Fatalf("loaded %d rows: %v", n, err)
+	}
+	// The session lists the most recent warning first
+	var codes []int
+	for _, w := range ctx.Warnings() {
+		codes = append(codes, w.Code)
+	}
+	if want := []int{codeDataTruncated, codeOutOfRange}; !reflect.DeepEqual(codes, want) {
+		t.Errorf("warned %v, want %v", codes, want)
+	}
+	var (
+		id, tiny int
+		name     string
+	)
+	if err := db.r.QueryRow(`SELECT id, tiny, name FROM t`).Scan(&id, &tiny, &name); err != nil {
+		t.Fatal(err)
+	}
+	if id != 4 || tiny != 127 || name != "abc" {
+		t.Errorf("loaded %d, %d, %q, want 4, 127, \"abc\"", id, tiny, name)
+	}
+}
+
+// sqlErrorCode returns the MySQL error code of err, or 0 if it has none.
+func sqlErrorCode(err error) int {
+	if serr, ok := err.(*mysql.SQLError); ok {
+		return serr.Number()
+	}
+	return 0
+}
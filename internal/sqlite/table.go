@@ -8,22 +8,33 @@ import (
 	"time"
 
 	"github.com/liquidata-inc/go-mysql-server/sql"
+	"github.com/mattn/go-sqlite3"
 )
 
 type Table struct {
-	name   string
-	schema sql.Schema
-	dbr    *stdsql.DB
-	dbw    *stdsql.DB
+	name      string
+	schema    sql.Schema
+	generated []*generatedColumn // nil, or aligned with schema
+	dbr       conn
+	dbw       conn
+
+	// temporary tables are read and written through the same pinned connection
+	temporary bool
+	// charTyped tables have their CHAR and VARCHAR columns' charTypes, for
+	// the engine to convert the values an INSERT writes to them
+	charTyped bool
+	// filters pushed down by the engine, which offsetFilter translates
+	filters []sql.Expression
 }
 
 var (
 	_ sql.Table           = (*Table)(nil)
 	_ sql.InsertableTable = (*Table)(nil)
+	_ TruncatableTable    = (*Table)(nil)
+	_ sql.FilteredTable   = (*Table)(nil)
 	// _ sql.UpdatableTable = (*Table)(nil)
 	// _ sql.DeletableTable = (*Table)(nil)
 	// _ sql.ReplaceableTable = (*Table)(nil)
-	// _ sql.FilteredTable = (*Table)(nil)
 	// _ sql.ProjectedTable = (*Table)(nil)
 	// _ sql.DriverIndexableTable = (*Table)(nil)
 	// _ sql.AlterableTable = (*Table)(nil)
@@ -56,7 +67,7 @@ func (t *Table) PartitionRows(ctx *sql.Context, partition sql.Partition) (sql.Ro
 		return nil, fmt.Errorf("partition not found: %q", partition.Key())
 	}
 
-	rows, err := t.dbr.QueryContext(ctx, "SELECT * FROM \""+t.name+"\"")
+	rows, err := t.dbr.QueryContext(ctx, t.scanQuery())
 	if err != nil {
 		return nil, err
 	}
@@ -67,6 +78,55 @@ func (t *Table) PartitionRows(ctx *sql.Context, partition sql.Partition) (sql.Ro
 	}, nil
 }
 
+// scanQuery is the SQL run to read the table's rows.
+func (t *Table) scanQuery() string {
+	query := `SELECT * FROM "` + t.name + `"`
+	for i, f := range t.filters {
+		cond, _ := t.offsetFilter(f)
+		if i == 0 {
+			query += " WHERE " + cond
+		} else {
+			query += " AND " + cond
+		}
+	}
+	return query
+}
+
+// TruncatableTable is a table that can delete its rows in bulk while keeping its
+// schema and metadata. go-mysql-server has no notion of TRUNCATE, so the mysqlite
+// server dispatches TRUNCATE TABLE statements to this interface directly.
+type TruncatableTable interface {
+	sql.Table
+	// Truncate deletes every row in the table, returning the number of rows deleted.
+	Truncate(ctx *sql.Context) (int, error)
+	// TruncateBefore deletes every row with a rowtime older than the one given,
+	// returning the number of rows deleted.
+	TruncateBefore(ctx *sql.Context, rowtime int64) (int, error)
+}
+
+// Truncate deletes every row in the table. An unqualified DELETE lets SQLite use
+// its truncate optimization rather than visiting each row, and unlike dropping
+// and recreating the table it leaves mysqlite_table_schema untouched.
+func (t *Table) Truncate(ctx *sql.Context) (int, error) {
+	res, err := t.dbw.ExecContext(ctx, `DELETE FROM "`+t.name+`"`)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// TruncateBefore deletes every row whose rowtime is strictly less than rowtime,
+// which lets stream tables be trimmed to a retention window.
+func (t *Table) TruncateBefore(ctx *sql.Context, rowtime int64) (int, error) {
+	res, err := t.dbw.ExecContext(ctx, `DELETE FROM "`+t.name+`" WHERE rowtime < ?`, rowtime)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
 type partition struct {
 	key []byte
 }
@@ -114,10 +174,36 @@ func (r *rowIter) Next() (sql.Row, error) {
 	}
 	for i, ptr := range row {
 		row[i] = *(ptr.(*interface{}))
+		if t, ok := r.schema[i].Type.(geometryType); ok && row[i] != nil {
+			v, err := t.Convert(row[i])
+			if err != nil {
+				return nil, err
+			}
+			row[i] = v
+		}
+		if isEncoded(r.schema[i].Type) {
+			v, err := decodeValue(r.schema[i].Type, row[i])
+			if err != nil {
+				return nil, err
+			}
+			row[i] = v
+		}
+		if s, ok := row[i].(string); ok && sql.IsTime(r.schema[i].Type) {
+			if t, ok := parseTimestamp(s); ok {
+				row[i] = t
+			}
+		}
 	}
 	return row, nil
 }
 
+// parseTimestamp parses a time in the format go-sqlite3 stores time.Time values
+// in, which the engine's conversions don't accept.
+func parseTimestamp(s string) (time.Time, bool) {
+	t, err := time.Parse(sqlite3.SQLiteTimestampFormats[0], s)
+	return t, err == nil
+}
+
 func (r *rowIter) Close() error {
 	return r.rows.Close()
 }
@@ -132,33 +218,99 @@ func (t *Table) Inserter(ctx *sql.Context) sql.RowInserter {
 }
 
 type rowInserter struct {
-	table *Table
-	tx    *stdsql.Tx
-	err   error
+	table   *Table
+	tx      *stdsql.Tx
+	err     error
+	rows    int
+	pending []pendingInsert
+}
+
+type pendingInsert struct {
+	statement string
+	args      []interface{}
 }
 
 func (i *rowInserter) Insert(ctx *sql.Context, row sql.Row) error {
-	cols := make([]string, len(i.table.schema))
-	phdr := make([]string, len(i.table.schema))
-	for i, col := range i.table.schema {
-		cols[i] = col.Name
-		if col.Name == "rowtime" && row[i] == nil {
-			phdr[i] = fmt.Sprintf("%d", time.Now().UnixNano())
-			row = append(row[:i], row[i+1:]...)
-			fmt.Println("ROW:", row)
-		} else {
-			phdr[i] = "?"
-		}
+	i.rows++
+	cols, args, err := i.table.insertValues(ctx, row, i.rows)
+	if err != nil {
+		return err
+	}
+	statement := i.table.insertStatement("", cols)
+	if i.table.temporary {
+		// Reads of a temporary table share the inserter's connection and would
+		// see rows inserted mid-scan, so an INSERT ... SELECT from the same table
+		// would never terminate. Hold the rows until the scan is done.
+		i.pending = append(i.pending, pendingInsert{statement, args})
+		return nil
 	}
-	statement := fmt.Sprintf(`INSERT INTO "%s" (%s) VALUES (%s)`, i.table.name, strings.Join(cols, ","), strings.Join(phdr, ","))
-	_, err := i.tx.ExecContext(ctx, statement, row...)
+	_, err = i.tx.ExecContext(ctx, statement, args...)
 	return err
 }
 
+// insertValues returns the columns an insert of row, the nth one written,
+// writes and their values, computing the generated columns SQLite doesn't,
+// giving rows that don't supply a rowtime the current time and checking every
+// value against the type of its column.
+func (t *Table) insertValues(ctx *sql.Context, row sql.Row, n int) ([]string, []interface{}, error) {
+	if t.generated != nil {
+		row = row.Copy()
+		if err := computeGenerated(ctx, t.schema, t.generated, row); err != nil {
+			return nil, nil, err
+		}
+	}
+	row, err := coerceRow(ctx, t.schema, row, n)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		cols []string
+		args []interface{}
+	)
+	for idx, col := range t.schema {
+		if t.generated != nil && t.generated[idx] != nil && t.generated[idx].native {
+			// SQLite refuses writes to its generated columns
+			continue
+		}
+		cols = append(cols, col.Name)
+		switch {
+		case col.Name == "rowtime" && row[idx] == nil:
+			args = append(args, time.Now().UnixNano())
+		case isEncoded(col.Type):
+			v, err := encodeValue(col.Type, row[idx])
+			if err != nil {
+				return nil, nil, err
+			}
+			args = append(args, v)
+		default:
+			args = append(args, row[idx])
+		}
+	}
+	return cols, args, nil
+}
+
+// insertStatement returns an INSERT of one row of values for cols, with
+// conflict resolution "OR <onConflict>" if onConflict isn't empty.
+func (t *Table) insertStatement(onConflict string, cols []string) string {
+	verb := "INSERT"
+	if onConflict != "" {
+		verb += " OR " + onConflict
+	}
+	phdr := strings.TrimSuffix(strings.Repeat("?,", len(cols)), ",")
+	return fmt.Sprintf(`%s INTO "%s" (%s) VALUES (%s)`, verb, t.name, strings.Join(cols, ","), phdr)
+}
+
 func (i *rowInserter) Close(ctx *sql.Context) error {
 	if i.err != nil {
 		_ = i.tx.Rollback()
 		return i.err
 	}
+	for _, p := range i.pending {
+		if _, err := i.tx.ExecContext(ctx, p.statement, p.args...); err != nil {
+			_ = i.tx.Rollback()
+			return err
+		}
+	}
 	return i.tx.Commit()
 }
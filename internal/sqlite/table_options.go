@@ -0,0 +1,290 @@
+package sqlite
+
+import (
+	"context"
+	stdsql "database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/liquidata-inc/go-mysql-server/sql"
+	"github.com/liquidata-inc/vitess/go/vt/sqlparser"
+)
+
+// tableOptionsDDL creates the companion to mysqlite_table_schema that holds
+// table-level options, one row per option, in the given SQLite schema.
+const tableOptionsDDL = `CREATE TABLE IF NOT EXISTS %s.mysqlite_table_options (
+	source TEXT NOT NULL, -- table name
+	option TEXT NOT NULL, -- upper case option name, e.g. ENGINE
+	value TEXT NOT NULL,
+	PRIMARY KEY (source, option)
+)`
+
+// TableOptions are the table-level options given to CREATE TABLE. SQLite has no
+// use for them, but they are persisted so that they can be reported back the way
+// MySQL would.
+type TableOptions struct {
+	Engine    string
+	Charset   string
+	Collate   string
+	RowFormat string
+	Comment   string
+}
+
+// Collation returns the table's collation, falling back to the default collation
+// of its character set and then to the server default.
+func (o TableOptions) Collation() string {
+	if o.Collate != "" {
+		return o.Collate
+	}
+	if o.Charset != "" {
+		if cs, err := sql.ParseCharacterSet(o.Charset); err == nil {
+			return cs.DefaultCollation().String()
+		}
+	}
+	return sql.DefaultCollation
+}
+
+// String formats the options as they appear after the column definitions of a
+// CREATE TABLE statement, defaulting ENGINE and DEFAULT CHARSET as MySQL does.
+func (o TableOptions) String() string {
+	engine := o.Engine
+	if engine == "" {
+		engine = "InnoDB"
+	}
+	charset := o.Charset
+	if charset == "" {
+		charset = sql.DefaultCharacterSet
+	}
+	s := fmt.Sprintf("ENGINE=%s DEFAULT CHARSET=%s", engine, charset)
+	if o.Collate != "" {
+		s += " COLLATE=" + o.Collate
+	}
+	if o.RowFormat != "" {
+		s += " ROW_FORMAT=" + strings.ToUpper(o.RowFormat)
+	}
+	if o.Comment != "" {
+		s += " COMMENT='" + strings.ReplaceAll(o.Comment, "'", "''") + "'"
+	}
+	return s
+}
+
+// values returns the options that were set, keyed by their MySQL name.
+func (o TableOptions) values() map[string]string {
+	m := map[string]string{}
+	for k, v := range map[string]string{
+		"ENGINE":     o.Engine,
+		"CHARSET":    o.Charset,
+		"COLLATE":    o.Collate,
+		"ROW_FORMAT": o.RowFormat,
+		"COMMENT":    o.Comment,
+	} {
+		if v != "" {
+			m[k] = v
+		}
+	}
+	return m
+}
+
+func (o *TableOptions) set(option, value string) {
+	switch option {
+	case "ENGINE":
+		o.Engine = value
+	case "CHARSET":
+		o.Charset = value
+	case "COLLATE":
+		o.Collate = value
+	case "ROW_FORMAT":
+		o.RowFormat = value
+	case "COMMENT":
+		o.Comment = value
+	}
+}
+
+var createTemporaryRegex = regexp.MustCompile(`(?is)^\s*create\s+temporary\s+`)
+
+// tableOptionsFromQuery recovers the table options of a CREATE [TEMPORARY] TABLE
+// statement. go-mysql-server drops them before calling Database.CreateTable, but
+// the statement is still available from the context. Any other statement, or one
+// that can't be parsed, has no options.
+func tableOptionsFromQuery(query string) TableOptions {
+	// vitess can't parse TEMPORARY or generated columns, but the options don't
+	// depend on them
+	query = RewriteCreateTable(createTemporaryRegex.ReplaceAllString(query, "create "))
+	stmt, err := sqlparser.ParseStrictDDL(query)
+	if err != nil {
+		return TableOptions{}
+	}
+	ddl, ok := stmt.(*sqlparser.DDL)
+	if !ok || ddl.Action != sqlparser.CreateStr || ddl.TableSpec == nil {
+		return TableOptions{}
+	}
+	// The parsed TableSpec.Options loses escaped quotes, so read the clause
+	// following the column definitions from the statement itself.
+	_, _, tail, _ := splitTableSpec(query)
+	return parseTableOptions(strings.TrimPrefix(tail, ")"))
+}
+
+// parseTableOptions parses a table options clause such as
+// "ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='events'". Options mysqlite
+// doesn't track, like AUTO_INCREMENT, are skipped.
+func parseTableOptions(s string) TableOptions {
+	var (
+		opts TableOptions
+		pos  int
+	)
+	skip := func() {
+		for pos < len(s) && (unicode.IsSpace(rune(s[pos])) || s[pos] == ',' || s[pos] == '=') {
+			pos++
+		}
+	}
+	word := func() string {
+		skip()
+		if pos < len(s) && (s[pos] == '\'' || s[pos] == '"') {
+			quote := s[pos]
+			pos++
+			var b strings.Builder
+			for pos < len(s) {
+				if s[pos] == quote {
+					if pos+1 < len(s) && s[pos+1] == quote {
+						b.WriteByte(quote)
+						pos += 2
+						continue
+					}
+					pos++
+					break
+				}
+				b.WriteByte(s[pos])
+				pos++
+			}
+			return b.String()
+		}
+		start := pos
+		for pos < len(s) && !unicode.IsSpace(rune(s[pos])) && s[pos] != ',' && s[pos] != '=' {
+			pos++
+		}
+		return s[start:pos]
+	}
+
+	for {
+		key := strings.ToUpper(word())
+		if key == "" {
+			return opts
+		}
+		if key == "DEFAULT" {
+			key = strings.ToUpper(word())
+		}
+		switch key {
+		case "CHARACTER":
+			word() // SET
+			key = "CHARSET"
+		case "CHAR":
+			word() // SET
+			key = "CHARSET"
+		}
+		opts.set(key, word())
+	}
+}
+
+// saveTableOptions records the options of a newly created table.
+func saveTableOptions(tx *stdsql.Tx, schemaName, name string, opts TableOptions) error {
+	for option, value := range opts.values() {
+		if _, err := tx.Exec(
+			`INSERT INTO `+schemaName+`.mysqlite_table_options (source, option, value) VALUES (?, ?, ?)`,
+			name, option, value,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadTableOptions reads a table's options back. Unqualified, the lookup finds
+// the temp schema's options on a session's pinned connection and the main
+// schema's everywhere else.
+func loadTableOptions(ctx context.Context, c conn, tblName string) (TableOptions, error) {
+	var opts TableOptions
+	rows, err := c.QueryContext(ctx, `SELECT option, value FROM mysqlite_table_options WHERE source = ? COLLATE NOCASE`, tblName)
+	if err != nil {
+		return opts, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var option, value string
+		if err := rows.Scan(&option, &value); err != nil {
+			return opts, err
+		}
+		opts.set(option, value)
+	}
+	return opts, rows.Err()
+}
+
+// TableOptions returns the table-level options the table was created with.
+func (t *Table) TableOptions(ctx *sql.Context) (TableOptions, error) {
+	return loadTableOptions(ctx, t.dbr, t.name)
+}
+
+// CreateTableStatement returns the MySQL CREATE TABLE statement for the table,
+// formatted like go-mysql-server's SHOW CREATE TABLE but with the table's own
+// options in place of hardcoded defaults.
+func (t *Table) CreateTableStatement(ctx *sql.Context) (string, error) {
+	opts, err := t.TableOptions(ctx)
+	if err != nil {
+		return "", err
+	}
+	indexes, err := loadIndexes(ctx, t.dbr, t.name)
+	if err != nil {
+		return "", err
+	}
+
+	var (
+		colStmts []string
+		pks      []string
+	)
+	for i, col := range t.schema {
+		stmt := fmt.Sprintf("  `%s` %s", col.Name, strings.ToLower(col.Type.String()))
+		if t.generated != nil && t.generated[i] != nil {
+			stmt += " " + t.generated[i].String()
+		}
+		if !col.Nullable {
+			stmt += " NOT NULL"
+		}
+		switch def := col.Default.(type) {
+		case string:
+			if def != "" {
+				stmt = fmt.Sprintf("%s DEFAULT %q", stmt, def)
+			}
+		default:
+			if def != nil {
+				stmt = fmt.Sprintf("%s DEFAULT %v", stmt, col.Default)
+			}
+		}
+		if col.Comment != "" {
+			stmt = fmt.Sprintf("%s COMMENT '%s'", stmt, strings.ReplaceAll(col.Comment, "'", "''"))
+		}
+		if col.PrimaryKey {
+			pks = append(pks, fmt.Sprintf("`%s`", col.Name))
+		}
+		colStmts = append(colStmts, stmt)
+	}
+	if len(pks) > 0 {
+		colStmts = append(colStmts, fmt.Sprintf("  PRIMARY KEY (%s)", strings.Join(pks, ",")))
+	}
+	for _, idx := range indexes {
+		cols := make([]string, len(idx.columns))
+		for i, col := range idx.columns {
+			cols[i] = fmt.Sprintf("`%s`", col)
+		}
+		stmt := fmt.Sprintf("  KEY `%s` (%s)", idx.name, strings.Join(cols, ","))
+		if idx.kind != "" {
+			stmt = "  " + idx.kind + stmt[1:]
+		}
+		if idx.comment != "" {
+			stmt = fmt.Sprintf("%s COMMENT '%s'", stmt, strings.ReplaceAll(idx.comment, "'", "''"))
+		}
+		colStmts = append(colStmts, stmt)
+	}
+
+	return fmt.Sprintf("CREATE TABLE `%s` (\n%s\n) %s", t.name, strings.Join(colStmts, ",\n"), opts), nil
+}
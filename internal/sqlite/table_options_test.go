@@ -0,0 +1,86 @@
+package sqlite
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	sqle "github.com/liquidata-inc/go-mysql-server"
+	"github.com/liquidata-inc/go-mysql-server/sql"
+)
+
+func TestParseTableOptions(t *testing.T) {
+	tests := []struct {
+		clause string
+		opts   TableOptions
+	}{
+		{"", TableOptions{}},
+		{" ENGINE=InnoDB DEFAULT CHARSET=utf8mb4", TableOptions{Engine: "InnoDB", Charset: "utf8mb4"}},
+		{" engine = MyISAM, character set latin1 collate latin1_bin", TableOptions{Engine: "MyISAM", Charset: "latin1", Collate: "latin1_bin"}},
+		{" AUTO_INCREMENT=5 ROW_FORMAT=dynamic COMMENT='it''s \"quoted\"'", TableOptions{RowFormat: "dynamic", Comment: `it's "quoted"`}},
+		{` DEFAULT CHAR SET = ascii COMMENT "a, b"`, TableOptions{Charset: "ascii", Comment: "a, b"}},
+	}
+	for _, tt := range tests {
+		if opts := parseTableOptions(tt.clause); opts != tt.opts {
+			t.Errorf("parseTableOptions(%q) = %+v, want %+v", tt.clause, opts, tt.opts)
+		}
+	}
+}
+
+func TestTableOptionsReopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mysqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "mydb.db")
+
+	db, err := NewDatabase("mydb", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := sqle.NewDefault()
+	e.AddDatabase(db)
+	stmt := "CREATE TABLE events (id INT) ENGINE=MyISAM DEFAULT CHARSET=latin1 ROW_FORMAT=compact COMMENT='user''s events'"
+	ctx := sql.NewContext(context.Background(), sql.WithSession(sql.NewBaseSession()), sql.WithQuery(stmt))
+	ctx.SetCurrentDatabase("mydb")
+	if _, iter, err := e.Query(ctx, stmt); err != nil {
+		t.Fatal(err)
+	} else if _, err := sql.RowIterToRows(iter); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = NewDatabase("mydb", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	table, ok, err := db.GetTableInsensitive(ctx, "events")
+	if err != nil || !ok {
+		t.Fatalf("events not found after reopening: %v", err)
+	}
+	opts, err := table.(*Table).TableOptions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := TableOptions{Engine: "MyISAM", Charset: "latin1", RowFormat: "compact", Comment: "user's events"}
+	if opts != want {
+		t.Errorf("options after reopening are %+v, want %+v", opts, want)
+	}
+	if opts.Collation() != "latin1_swedish_ci" {
+		t.Errorf("collation is %s, want latin1_swedish_ci", opts.Collation())
+	}
+	create, err := table.(*Table).CreateTableStatement(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if suffix := ") ENGINE=MyISAM DEFAULT CHARSET=latin1 ROW_FORMAT=COMPACT COMMENT='user''s events'"; !strings.HasSuffix(create, suffix) {
+		t.Errorf("SHOW CREATE TABLE gives %q, want it to end with %q", create, suffix)
+	}
+}
@@ -0,0 +1,162 @@
+package sqlite
+
+import (
+	"context"
+	stdsql "database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/liquidata-inc/go-mysql-server/sql"
+)
+
+// TemporaryTableCreator is implemented by databases that can create tables which
+// only the creating session can see. go-mysql-server has no notion of temporary
+// tables, so the mysqlite server calls this interface directly and must call
+// EndSession once a session is over.
+type TemporaryTableCreator interface {
+	sql.Database
+	// CreateTemporaryTable creates a table visible only to the session in ctx.
+	CreateTemporaryTable(ctx *sql.Context, name string, schema sql.Schema) error
+	// DropTemporaryTable drops a temporary table created by the session in ctx.
+	DropTemporaryTable(ctx *sql.Context, name string) error
+	// EndSession drops every temporary table held by the given session.
+	EndSession(id uint32) error
+}
+
+var _ TemporaryTableCreator = (*Database)(nil)
+
+// session holds the temporary tables of a single client session. SQLite keeps
+// temporary tables in the "temp" schema of the connection that created them, so
+// each session gets a dedicated connection that is closed when the session ends,
+// taking its tables and their metadata with it.
+type session struct {
+	pool      *stdsql.DB
+	conn      *stdsql.Conn
+	schemas   map[string]sql.Schema
+	generated map[string][]*generatedColumn
+}
+
+// session returns the temporary table session for ctx, or nil if the session has
+// not created any temporary tables.
+func (db *Database) session(ctx *sql.Context) *session {
+	if ctx.Session == nil {
+		return nil
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.sessions[ctx.ID()]
+}
+
+func (db *Database) openSession(ctx *sql.Context) (*session, error) {
+	if s := db.session(ctx); s != nil {
+		return s, nil
+	}
+
+	pool, err := stdsql.Open(db.driver, db.dsn)
+	if err != nil {
+		return nil, err
+	}
+	// The connection is long-lived, so it must not inherit the query's context
+	conn, err := pool.Conn(context.Background())
+	if err != nil {
+		_ = pool.Close()
+		return nil, err
+	}
+	for _, ddl := range []string{tableSchemaDDL, tableOptionsDDL, tableIndexesDDL} {
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf(ddl, "temp")); err != nil {
+			_ = conn.Close()
+			_ = pool.Close()
+			return nil, err
+		}
+	}
+
+	s := &session{
+		pool:      pool,
+		conn:      conn,
+		schemas:   map[string]sql.Schema{},
+		generated: map[string][]*generatedColumn{},
+	}
+	db.mu.Lock()
+	db.sessions[ctx.ID()] = s
+	db.mu.Unlock()
+	return s, nil
+}
+
+func (db *Database) CreateTemporaryTable(ctx *sql.Context, name string, schema sql.Schema) error {
+	schema, err := withRowtime(name, schema)
+	if err != nil {
+		return err
+	}
+	ext := columnExtensionsFromQuery(ctx.Query())
+	schema = withSpatial(schema, ext.spatial)
+	generated, err := withGenerated(ctx, schema, ext.generated)
+	if err != nil {
+		return err
+	}
+
+	s, err := db.openSession(ctx)
+	if err != nil {
+		return err
+	}
+	if _, ok := s.schemas[strings.ToLower(name)]; ok {
+		return sql.ErrTableAlreadyExists.New(name)
+	}
+
+	return inTx(ctx, s.conn, func(tx *stdsql.Tx) error {
+		if err := createTable(tx, "temp", name, schema, generated); err != nil {
+			return err
+		}
+		if err := saveTableOptions(tx, "temp", name, tableOptionsFromQuery(ctx.Query())); err != nil {
+			return err
+		}
+
+		s.schemas[strings.ToLower(name)] = schema
+		s.generated[strings.ToLower(name)] = generated
+
+		return nil
+	})
+}
+
+func (db *Database) DropTemporaryTable(ctx *sql.Context, name string) error {
+	s := db.session(ctx)
+	if s == nil {
+		return sql.ErrTableNotFound.New(name)
+	}
+	if _, ok := s.schemas[strings.ToLower(name)]; !ok {
+		return sql.ErrTableNotFound.New(name)
+	}
+
+	return inTx(ctx, s.conn, func(tx *stdsql.Tx) error {
+		if err := dropTableIndexes(tx, "temp", name); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DROP TABLE temp."` + name + `"`); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM temp.mysqlite_table_schema WHERE source = ?`, name); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM temp.mysqlite_table_options WHERE source = ? COLLATE NOCASE`, name); err != nil {
+			return err
+		}
+		delete(s.schemas, strings.ToLower(name))
+		delete(s.generated, strings.ToLower(name))
+		return nil
+	})
+}
+
+func (db *Database) EndSession(id uint32) error {
+	db.mu.Lock()
+	s, ok := db.sessions[id]
+	delete(db.sessions, id)
+	db.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+	if err := s.conn.Close(); err != nil {
+		_ = s.pool.Close()
+		return err
+	}
+	return s.pool.Close()
+}
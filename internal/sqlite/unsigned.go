@@ -0,0 +1,220 @@
+package sqlite
+
+import (
+	stdsql "database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/liquidata-inc/go-mysql-server/sql"
+	"github.com/liquidata-inc/go-mysql-server/sql/expression"
+	"github.com/liquidata-inc/vitess/go/sqltypes"
+)
+
+// BIGINT UNSIGNED values don't all fit in SQLite's signed 64-bit integers, so
+// they're stored offset by -2^63, which flips their top bit.
+
+// isOffset reports whether values of type t are stored offset.
+func isOffset(t sql.Type) bool {
+	return t.Type() == sqltypes.Uint64
+}
+
+// encodeUint64 returns the value stored for a BIGINT UNSIGNED value.
+func encodeUint64(v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	u, err := sql.Uint64.Convert(v)
+	if err != nil {
+		return nil, err
+	}
+	return int64(u.(uint64) ^ 1<<63), nil
+}
+
+// decodeUint64 returns the BIGINT UNSIGNED value stored as v.
+func decodeUint64(v interface{}) interface{} {
+	if i, ok := v.(int64); ok {
+		return uint64(i) ^ 1<<63
+	}
+	return v
+}
+
+// offsetUint64Columns offsets the values of the BIGINT UNSIGNED columns of a
+// database created before they were stored offset, which only holds values
+// that fit in a signed integer.
+func offsetUint64Columns(tx *stdsql.Tx) error {
+	rows, err := tx.Query(`SELECT source, name FROM main.mysqlite_table_schema WHERE type = ?`, sqltypes.Uint64.String())
+	if err != nil {
+		return err
+	}
+	var cols [][2]string
+	for rows.Next() {
+		var source, name string
+		if err := rows.Scan(&source, &name); err != nil {
+			rows.Close()
+			return err
+		}
+		cols = append(cols, [2]string{source, name})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, col := range cols {
+		// -2^63 can't be written as a literal
+		if _, err := tx.Exec(`UPDATE main."` + col[0] + `" SET "` + col[1] + `" = "` + col[1] + `" + (-9223372036854775807 - 1) WHERE typeof("` + col[1] + `") = 'integer'`); err != nil {
+			return err
+		}
+	}
+	_, err = tx.Exec(`UPDATE main.mysqlite_table_schema SET num_offset = true WHERE type = ?`, sqltypes.Uint64.String())
+	return err
+}
+
+// The engine compares BIGINT UNSIGNED values above 2^63-1 with other integer
+// types as signed, so the table takes the filters comparing its BIGINT
+// UNSIGNED columns with integer literals and has SQLite compare the stored
+// values with the literals offset the same way.
+
+func (t *Table) HandledFilters(filters []sql.Expression) []sql.Expression {
+	var handled []sql.Expression
+	for _, f := range filters {
+		if _, ok := t.offsetFilter(f); ok {
+			handled = append(handled, f)
+		}
+	}
+	return handled
+}
+
+func (t *Table) WithFilters(filters []sql.Expression) sql.Table {
+	nt := *t
+	nt.filters = filters
+	return &nt
+}
+
+func (t *Table) Filters() []sql.Expression {
+	return t.filters
+}
+
+// comparisonOperators are the SQLite operators of comparisons, and those they
+// become with their operands swapped.
+var comparisonOperators = map[string][2]string{
+	"=":  {"=", "="},
+	">":  {">", "<"},
+	"<":  {"<", ">"},
+	">=": {">=", "<="},
+	"<=": {"<=", ">="},
+}
+
+// offsetFilter translates a filter comparing a BIGINT UNSIGNED column of the
+// table with integer literals to a condition on the stored values. It reports
+// false for any other filter.
+func (t *Table) offsetFilter(e sql.Expression) (string, bool) {
+	var op string
+	switch e := e.(type) {
+	case *expression.Equals:
+		op = "="
+	case *expression.GreaterThan:
+		op = ">"
+	case *expression.LessThan:
+		op = "<"
+	case *expression.GreaterThanOrEqual:
+		op = ">="
+	case *expression.LessThanOrEqual:
+		op = "<="
+	case *expression.Not:
+		eq, ok := e.Child.(*expression.Equals)
+		if !ok {
+			return "", false
+		}
+		s, ok := t.offsetFilter(eq)
+		return "NOT (" + s + ")", ok
+	case *expression.InTuple, *expression.NotInTuple:
+		c := e.(expression.Comparer)
+		col, ok := t.offsetColumn(c.Left())
+		if !ok {
+			return "", false
+		}
+		tuple, ok := c.Right().(expression.Tuple)
+		if !ok {
+			return "", false
+		}
+		vals := make([]string, len(tuple))
+		for i, v := range tuple {
+			if vals[i], ok = offsetLiteral(v); !ok {
+				return "", false
+			}
+		}
+		in := " IN "
+		if _, ok := e.(*expression.NotInTuple); ok {
+			in = " NOT IN "
+		}
+		return col + in + "(" + strings.Join(vals, ", ") + ")", true
+	case *expression.Between:
+		col, ok := t.offsetColumn(e.Val)
+		if !ok {
+			return "", false
+		}
+		lower, ok := offsetLiteral(e.Lower)
+		if !ok {
+			return "", false
+		}
+		upper, ok := offsetLiteral(e.Upper)
+		if !ok {
+			return "", false
+		}
+		return col + " BETWEEN " + lower + " AND " + upper, true
+	default:
+		return "", false
+	}
+
+	c := e.(expression.Comparer)
+	if col, ok := t.offsetColumn(c.Left()); ok {
+		lit, ok := offsetLiteral(c.Right())
+		return col + " " + comparisonOperators[op][0] + " " + lit, ok
+	}
+	if col, ok := t.offsetColumn(c.Right()); ok {
+		lit, ok := offsetLiteral(c.Left())
+		return col + " " + comparisonOperators[op][1] + " " + lit, ok
+	}
+	return "", false
+}
+
+// offsetColumn returns the quoted name of the BIGINT UNSIGNED column of the
+// table e is, if it is one.
+func (t *Table) offsetColumn(e sql.Expression) (string, bool) {
+	gf, ok := e.(*expression.GetField)
+	if !ok || !strings.EqualFold(gf.Table(), t.name) {
+		return "", false
+	}
+	for _, col := range t.schema {
+		if strings.EqualFold(col.Name, gf.Name()) && isOffset(col.Type) {
+			return quoteIdent(col.Name), true
+		}
+	}
+	return "", false
+}
+
+// offsetLiteral returns the stored value of e, an integer literal in the range
+// of BIGINT UNSIGNED, as SQL.
+func offsetLiteral(e sql.Expression) (string, bool) {
+	l, ok := e.(*expression.Literal)
+	if !ok {
+		return "", false
+	}
+	var u uint64
+	switch v := l.Value().(type) {
+	case int8, int16, int32, int64:
+		i := reflect.ValueOf(v).Int()
+		if i < 0 {
+			return "", false
+		}
+		u = uint64(i)
+	case uint8, uint16, uint32, uint64:
+		u = reflect.ValueOf(v).Uint()
+	default:
+		return "", false
+	}
+	v, _ := encodeUint64(u)
+	return fmt.Sprintf("%d", v), true
+}
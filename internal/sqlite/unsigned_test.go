@@ -0,0 +1,230 @@
+package sqlite
+
+import (
+	stdsql "database/sql"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	sqle "github.com/liquidata-inc/go-mysql-server"
+	"github.com/liquidata-inc/go-mysql-server/sql"
+)
+
+// legacyTableSchemaDDL is mysqlite_table_schema as first released, before any
+// of tableSchemaMigrations.
+const legacyTableSchemaDDL = `CREATE TABLE main.mysqlite_table_schema (
+	source TEXT,
+	cid INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	type TEXT NOT NULL,
+	pk INTEGER NOT NULL DEFAULT false,
+	nullable INTEGER NOT NULL DEFAULT true,
+	dflt_value BLOB,
+	comment TEXT,
+	num_unsigned INTEGER,
+	num_length INTEGER,
+	num_scale INTEGER,
+	txt_charset TEXT,
+	txt_collate TEXT,
+	enum_vals TEXT
+)`
+
+// legacyDatabase writes a database file as an older mysqlite would have, with
+// the given statements run after creating the original mysqlite_table_schema,
+// and returns its path.
+func legacyDatabase(t *testing.T, stmts ...string) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "mysqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "legacy.db")
+	db, err := stdsql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, stmt := range append([]string{legacyTableSchemaDDL}, stmts...) {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	return path
+}
+
+// storedValues returns the values of a column as SQLite stores them, in rowid
+// order.
+func storedValues(t *testing.T, db *Database, table, column string) []interface{} {
+	t.Helper()
+	rows, err := db.r.Query(`SELECT "` + column + `" FROM main."` + table + `" ORDER BY rowid`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var values []interface{}
+	for rows.Next() {
+		var v interface{}
+		if err := rows.Scan(&v); err != nil {
+			t.Fatal(err)
+		}
+		values = append(values, v)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return values
+}
+
+func TestUint64Encoding(t *testing.T) {
+	tests := []struct {
+		value  interface{}
+		stored interface{}
+	}{
+		{nil, nil},
+		{uint64(0), int64(math.MinInt64)},
+		{uint64(1), int64(math.MinInt64 + 1)},
+		{uint64(math.MaxInt64), int64(-1)},
+		{uint64(math.MaxInt64 + 1), int64(0)},
+		{uint64(math.MaxUint64), int64(math.MaxInt64)},
+		{int64(42), int64(math.MinInt64 + 42)},
+		{"18446744073709551615", int64(math.MaxInt64)},
+	}
+	for _, tt := range tests {
+		stored, err := encodeValue(sql.Uint64, tt.value)
+		if err != nil {
+			t.Errorf("encodeValue(%v): %v", tt.value, err)
+			continue
+		}
+		if stored != tt.stored {
+			t.Errorf("encodeValue(%v) = %v, want %v", tt.value, stored, tt.stored)
+		}
+		want, _ := sql.Uint64.Convert(tt.value)
+		if got, err := decodeValue(sql.Uint64, stored); err != nil || got != want {
+			t.Errorf("decodeValue(%v) = %v, %v, want %v", stored, got, err, want)
+		}
+	}
+}
+
+func TestUint64EncodingOrder(t *testing.T) {
+	values := []uint64{0, 1, 1 << 32, math.MaxInt64 - 1, math.MaxInt64, math.MaxInt64 + 1, math.MaxUint64 - 1, math.MaxUint64}
+	var stored []int64
+	for _, v := range values {
+		s, err := encodeUint64(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stored = append(stored, s.(int64))
+	}
+	if !sort.SliceIsSorted(stored, func(i, j int) bool { return stored[i] < stored[j] }) {
+		t.Errorf("stored values %v don't sort like %v", stored, values)
+	}
+}
+
+func TestOffsetUint64Columns(t *testing.T) {
+	path := legacyDatabase(t,
+		`CREATE TABLE counters (id INTEGER, hits INTEGER, label TEXT)`,
+		`INSERT INTO main.mysqlite_table_schema (source, cid, name, type, num_unsigned) VALUES
+			('counters', 0, 'id', 'INT32', false),
+			('counters', 1, 'hits', 'UINT64', true),
+			('counters', 2, 'label', 'TEXT', NULL)`,
+		`INSERT INTO counters VALUES (1, 0, 'a'), (2, 9223372036854775807, 'b'), (3, NULL, 'c'), (4, 7, 'd')`,
+	)
+
+	db, err := NewDatabase("legacy", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	wantHits := []uint64{0, math.MaxInt64, 0, 7}
+	for i, v := range storedValues(t, db, "counters", "hits") {
+		if i == 2 {
+			if v != nil {
+				t.Errorf("row 3: NULL stored as %v", v)
+			}
+			continue
+		}
+		if got := decodeUint64(v); got != wantHits[i] {
+			t.Errorf("row %d: stored %v decodes to %v, want %d", i+1, v, got, wantHits[i])
+		}
+	}
+	// Other integer columns are left alone
+	for i, v := range storedValues(t, db, "counters", "id") {
+		if v != int64(i+1) {
+			t.Errorf("row %d: id changed to %v", i+1, v)
+		}
+	}
+
+	var offset bool
+	if err := db.r.QueryRow(`SELECT num_offset FROM main.mysqlite_table_schema WHERE name = 'hits'`).Scan(&offset); err != nil {
+		t.Fatal(err)
+	}
+	if !offset {
+		t.Error("num_offset not set for hits")
+	}
+
+	// Opening the database again doesn't offset the values twice
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	db, err = NewDatabase("legacy", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if got := decodeUint64(storedValues(t, db, "counters", "hits")[3]); got != uint64(7) {
+		t.Errorf("after reopening, 7 is stored as %v", got)
+	}
+}
+
+func TestUint64Filters(t *testing.T) {
+	db, ctx := testDatabase(t,
+		`CREATE TABLE big (id INT, u BIGINT UNSIGNED)`,
+		`INSERT INTO big (id, u) VALUES (1, 1), (2, 9223372036854775807), (3, 9223372036854775808), (4, 18446744073709551615), (5, NULL)`,
+	)
+	e := sqle.NewDefault()
+	e.AddDatabase(db)
+
+	tests := []struct {
+		query string
+		ids   []int64
+	}{
+		{"SELECT id FROM big WHERE u > 9223372036854775807", []int64{3, 4}},
+		{"SELECT id FROM big WHERE 9223372036854775807 < u", []int64{3, 4}},
+		{"SELECT id FROM big WHERE u >= 9223372036854775808", []int64{3, 4}},
+		{"SELECT id FROM big WHERE u < 9223372036854775808", []int64{1, 2}},
+		{"SELECT id FROM big WHERE u <= 1", []int64{1}},
+		{"SELECT id FROM big WHERE u = 18446744073709551615", []int64{4}},
+		{"SELECT id FROM big WHERE u <> 18446744073709551615", []int64{1, 2, 3}},
+		{"SELECT id FROM big WHERE u IN (1, 18446744073709551615)", []int64{1, 4}},
+		{"SELECT id FROM big WHERE u NOT IN (1, 18446744073709551615)", []int64{2, 3}},
+		{"SELECT id FROM big WHERE u BETWEEN 9223372036854775807 AND 9223372036854775808", []int64{2, 3}},
+		{"SELECT id FROM big WHERE u > 9223372036854775807 AND id > 3", []int64{4}},
+		{"SELECT b.id FROM big b WHERE b.u > 9223372036854775807", []int64{3, 4}},
+		{"SELECT id FROM big WHERE u IS NULL", []int64{5}},
+	}
+	for _, tt := range tests {
+		_, iter, err := e.Query(ctx, tt.query)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.query, err)
+		}
+		rows, err := sql.RowIterToRows(iter)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.query, err)
+		}
+		var ids []int64
+		for _, row := range rows {
+			id, _ := sql.Int64.Convert(row[0])
+			ids = append(ids, id.(int64))
+		}
+		if !reflect.DeepEqual(ids, tt.ids) {
+			t.Errorf("%s: got ids %v, want %v", tt.query, ids, tt.ids)
+		}
+	}
+}
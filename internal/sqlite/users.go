@@ -0,0 +1,80 @@
+package sqlite
+
+import (
+	"context"
+	stdsql "database/sql"
+	"strings"
+)
+
+// usersDDL creates the table of accounts managed with CREATE USER and GRANT.
+// A user has a row where db and tbl are empty, holding its password hash and
+// the privileges it has on every database, and a row for each database, with
+// tbl empty, or table it has been granted privileges on.
+const usersDDL = `CREATE TABLE IF NOT EXISTS main.mysqlite_users (
+	user TEXT NOT NULL,
+	db TEXT NOT NULL DEFAULT '',
+	tbl TEXT NOT NULL DEFAULT '',
+	password_hash TEXT NOT NULL DEFAULT '', -- mysql_native_password hash, on the global row
+	privileges TEXT NOT NULL DEFAULT '', -- comma separated, e.g. "SELECT,INSERT"
+	PRIMARY KEY (user, db, tbl)
+)`
+
+// UserGrant is a row of mysqlite_users: the privileges User has on Table of
+// Database, where an empty Table means every table and an empty Database every
+// database. The row for every database also holds the user's password hash.
+type UserGrant struct {
+	User         string
+	Database     string
+	Table        string
+	PasswordHash string
+	Privileges   []string
+}
+
+// Users returns the rows of the database's mysqlite_users table, creating it
+// if need be, ordered by user.
+func (db *Database) Users(ctx context.Context) ([]UserGrant, error) {
+	if _, err := db.w.ExecContext(ctx, usersDDL); err != nil {
+		return nil, err
+	}
+	rows, err := db.r.QueryContext(ctx, `SELECT user, db, tbl, password_hash, privileges FROM main.mysqlite_users ORDER BY user, db, tbl`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var grants []UserGrant
+	for rows.Next() {
+		var g UserGrant
+		var privileges string
+		if err := rows.Scan(&g.User, &g.Database, &g.Table, &g.PasswordHash, &privileges); err != nil {
+			return nil, err
+		}
+		if privileges != "" {
+			g.Privileges = strings.Split(privileges, ",")
+		}
+		grants = append(grants, g)
+	}
+	return grants, rows.Err()
+}
+
+// SetUser replaces the rows of user in mysqlite_users with grants, in one
+// transaction. No grants deletes the user.
+func (db *Database) SetUser(ctx context.Context, user string, grants []UserGrant) error {
+	if _, err := db.w.ExecContext(ctx, usersDDL); err != nil {
+		return err
+	}
+	return inTx(ctx, db.w, func(tx *stdsql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM main.mysqlite_users WHERE user = ?`, user); err != nil {
+			return err
+		}
+		for _, g := range grants {
+			if _, err := tx.ExecContext(ctx,
+				`INSERT INTO main.mysqlite_users (user, db, tbl, password_hash, privileges) VALUES (?, ?, ?, ?, ?)`,
+				user, g.Database, g.Table, g.PasswordHash, strings.Join(g.Privileges, ","),
+			); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -237,16 +237,10 @@ func ColumnTypeToType(ct *sqlparser.ColumnType) (sql.Type, error) {
 		return sql.CreateSetType(ct.EnumValues, collation)
 	case "json":
 		return sql.JSON, nil
-	case "geometry":
-	case "geometrycollection":
-	case "linestring":
-	case "multilinestring":
-	case "point":
-	case "multipoint":
-	case "polygon":
-	case "multipolygon":
+	case "geometry", "geometrycollection", "linestring", "multilinestring",
+		"point", "multipoint", "polygon", "multipolygon":
+		return spatialTypes[strings.ToLower(ct.Type)], nil
 	default:
 		return nil, fmt.Errorf("unknown type: %v", ct.Type)
 	}
-	return nil, fmt.Errorf("type not yet implemented: %v", ct.Type)
 }